@@ -33,6 +33,14 @@ func main() {
 	}
 	log.Info("Init postgres", zap.String("status", "success"))
 
+	if cfg.Redis.Addr != "" {
+		if err := repo.InitRedis(cfg); err != nil { // Init Redis
+			log.Error("Init redis", zap.Error(err))
+			os.Exit(1)
+		}
+		log.Info("Init redis", zap.String("status", "success"))
+	}
+
 	srv := server.NewHTTPServer(cfg, log)
 
 	// Start http server
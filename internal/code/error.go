@@ -26,6 +26,35 @@ const (
 	PasswordTooWeak                     = 2008
 	InvalidEmailFormat                  = 2009
 	InvalidUsernameFormat               = 2010
+	RefreshTokenReused                  = 2011
+	SessionNotFound                     = 2012
+	MFACodeInvalid                      = 2013
+	MFAAlreadyEnabled                   = 2014
+	MFANotEnabled                       = 2015
+	PasskeyVerificationFailed           = 2016
+	PasskeyNotFound                     = 2017
+	IdentityProviderNotFound            = 2018
+	IdentityProviderFailed              = 2019
+	IdentityAlreadyLinked               = 2020
+	IdentityNotFound                    = 2021
+	IdentityNotLinked                   = 2022
+	UsernameReserved                    = 2023
+	RegistrationClosed                  = 2024
+	InviteInvalid                       = 2025
+	InviteNotFound                      = 2026
+	ChallengeRequired                   = 2027
+	ChallengeFailed                     = 2028
+	ChallengeNotRequired                = 2029
+	MagicLinkOtherBrowser               = 2030
+	PasswordRequired                    = 2031
+	APITokenNotFound                    = 2032
+	BotNotFound                         = 2033
+	BotLimitReached                     = 2034
+	AccountDisabled                     = 2035
+	GroupNotFound                       = 2036
+	GroupAlreadyExists                  = 2037
+	ReauthenticationUnavailable         = 2038
+	PasswordManagedByDirectory          = 2039
 )
 
 // User module error code (2100-2199)
@@ -64,11 +93,13 @@ const (
 
 func GetMessage(code int) string {
 	messages := map[int]string{
-		Success:          "Success",
-		InvalidParameter: "Invalid parameter",
-		Unauthorized:     "Unauthorized",
-		Forbidden:        "Forbidden",
-		NotFound:         "Resource not found",
+		Success:            "Success",
+		InvalidParameter:   "Invalid parameter",
+		Unauthorized:       "Unauthorized",
+		Forbidden:          "Forbidden",
+		NotFound:           "Resource not found",
+		TooManyRequests:    "Too many requests",
+		ServiceUnavailable: "Service unavailable",
 
 		TokenExpired:                        "Token expired",
 		TokenInvalid:                        "Invalid token",
@@ -80,6 +111,35 @@ func GetMessage(code int) string {
 		PasswordTooWeak:                     "Password is too weak",
 		InvalidEmailFormat:                  "Invalid email format",
 		InvalidUsernameFormat:               "Invalid username format",
+		RefreshTokenReused:                  "Refresh token reused, session revoked",
+		SessionNotFound:                     "Session not found",
+		MFACodeInvalid:                      "Invalid two-factor code",
+		MFAAlreadyEnabled:                   "Two-factor authentication already enabled",
+		MFANotEnabled:                       "Two-factor authentication not enabled",
+		PasskeyVerificationFailed:           "Passkey verification failed",
+		PasskeyNotFound:                     "Passkey not found",
+		IdentityProviderNotFound:            "Identity provider not found",
+		IdentityProviderFailed:              "Identity provider sign in failed",
+		IdentityAlreadyLinked:               "Identity already linked to an account",
+		IdentityNotFound:                    "Linked identity not found",
+		IdentityNotLinked:                   "Identity not linked, sign in and link it from account settings",
+		UsernameReserved:                    "Username is reserved",
+		RegistrationClosed:                  "Registration is closed",
+		InviteInvalid:                       "Invite code is invalid, expired or used up",
+		InviteNotFound:                      "Invite not found",
+		ChallengeRequired:                   "Challenge required, fetch one from /auth/challenge",
+		ChallengeFailed:                     "Challenge answer is wrong or expired",
+		ChallengeNotRequired:                "No challenge is required for this endpoint",
+		MagicLinkOtherBrowser:               "Open the sign in link in the browser that requested it",
+		PasswordRequired:                    "Password is required",
+		APITokenNotFound:                    "API token not found",
+		BotNotFound:                         "Bot not found",
+		BotLimitReached:                     "Bot limit reached",
+		AccountDisabled:                     "Account is disabled",
+		GroupNotFound:                       "Group not found",
+		GroupAlreadyExists:                  "Group name already exists",
+		ReauthenticationUnavailable:         "Set a password or enable two-factor authentication to confirm this change",
+		PasswordManagedByDirectory:          "Password is managed by the account's directory",
 
 		ServerUnknownError: "Internal server error",
 		DatabaseError:      "Database error",
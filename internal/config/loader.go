@@ -3,10 +3,14 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultAuthKey Built-in signing key for development, refused in prod
+const DefaultAuthKey = "abcd1234abcd1234abcd1234abcd1234"
+
 // LoadYAMLConfig Load configuration from YAML file
 func LoadYAMLConfig() *Config {
 	configPath := getEnv("CONFIG_PATH", "./config.yaml")
@@ -22,21 +26,25 @@ func LoadYAMLConfig() *Config {
 		return getDefaultConfig()
 	}
 
-	var config Config
-	if err = yaml.Unmarshal(data, &config); err != nil {
+	// Keys missing from the file keep their defaults, so settings added later apply to older files.
+	// Redis is the exception: a file without a redis section runs without it, keeping state in memory.
+	config := getDefaultConfig()
+	config.Redis = Redis{}
+	if err = yaml.Unmarshal(data, config); err != nil {
 		// YAML parsing failed, reverting to default configuration
 		return getDefaultConfig()
 	}
 
-	return &config
+	return config
 }
 
 // getDefaultConfig Provide built-in default configuration
 func getDefaultConfig() *Config {
 	return &Config{
 		App: App{
-			Name: "aurchat-gateway",
-			Env:  "dev",
+			Name:    "aurchat-gateway",
+			Env:     "dev",
+			BaseURL: "http://localhost:8080",
 		},
 		HTTP: HTTP{
 			Listen: ":8080",
@@ -52,8 +60,40 @@ func getDefaultConfig() *Config {
 			DSN: "host=localhost user=user password=pass dbname=aurchat port=5432 sslmode=disable",
 		},
 		Auth: Auth{
-			Keys: "abcd1234abcd1234abcd1234abcd1234",
-			TTL:  259200,
+			Keys:         DefaultAuthKey,
+			TTL:          900,
+			RefreshTTL:   2592000,
+			Format:       "branca",
+			Registration: "open",
+			Lockout: Lockout{
+				UserAttempts: 5,
+				IPAttempts:   20,
+				BaseDelay:    2,
+				MaxDelay:     900,
+				Window:       3600,
+			},
+			Password: PasswordPolicy{
+				MinLength:  8,
+				MaxLength:  128,
+				MinEntropy: 36,
+			},
+			Challenge: Challenge{
+				PoW: PoWChallenge{
+					Difficulty: 20,
+					TTL:        300,
+				},
+				Captcha: CaptchaChallenge{
+					Timeout: 5,
+				},
+			},
+			MagicLink: MagicLink{
+				TTL: 600,
+			},
+			MaxBots: 10,
+		},
+		Hash: Hash{
+			QueueDepth: 64,
+			MaxWait:    5,
 		},
 		Snowflake: Snowflake{
 			WorkerID:          1,
@@ -61,6 +101,23 @@ func getDefaultConfig() *Config {
 			SeqBitLength:      12,
 			BaseTime:          "2020-01-01",
 		},
+		Mail: Mail{
+			Driver:  "file",
+			From:    "AurChat <no-reply@localhost>",
+			DropDir: "./mail",
+		},
+		WebAuthn: WebAuthn{
+			RPID:          "localhost",
+			RPDisplayName: "AurChat",
+			RPOrigins:     []string{"http://localhost:8080"},
+		},
+		LDAP: LDAP{
+			Timeout:           10,
+			UserFilter:        "(uid={username})",
+			UsernameAttribute: "uid",
+			EmailAttribute:    "mail",
+			GroupFilter:       "(member={dn})",
+		},
 	}
 }
 
@@ -75,6 +132,9 @@ func LoadEnvConfigs(baseConfig *Config) *Config {
 	if v := os.Getenv("APP_ENV"); v != "" {
 		config.App.Env = v
 	}
+	if v := os.Getenv("APP_BASE_URL"); v != "" {
+		config.App.BaseURL = v
+	}
 
 	// HTTP Configuration
 	if v := os.Getenv("HTTP_LISTEN"); v != "" {
@@ -105,11 +165,81 @@ func LoadEnvConfigs(baseConfig *Config) *Config {
 	if v := os.Getenv("AUTH_KEY"); v != "" {
 		config.Auth.Keys = v
 	}
+	if v := os.Getenv("AUTH_ACTIVE_KEY"); v != "" {
+		config.Auth.ActiveKey = v
+	}
+	if v := os.Getenv("AUTH_TOKEN_FORMAT"); v != "" {
+		config.Auth.Format = v
+	}
+	if v := os.Getenv("AUTH_REGISTRATION"); v != "" {
+		config.Auth.Registration = v
+	}
+	if v := os.Getenv("AUTH_MAGIC_LINK"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Auth.MagicLink.Enabled = enabled
+		}
+	}
+	if v := os.Getenv("AUTH_CHALLENGE_PROVIDER"); v != "" {
+		config.Auth.Challenge.Provider = v
+	}
+	if v := os.Getenv("AUTH_CAPTCHA_SECRET"); v != "" {
+		config.Auth.Challenge.Captcha.Secret = v
+	}
 	if v := os.Getenv("AUTH_TTL"); v != "" {
 		if ttl, err := strconv.Atoi(v); err == nil {
 			config.Auth.TTL = uint32(ttl)
 		}
 	}
+	if v := os.Getenv("AUTH_REFRESH_TTL"); v != "" {
+		if ttl, err := strconv.Atoi(v); err == nil {
+			config.Auth.RefreshTTL = uint32(ttl)
+		}
+	}
+	if v := os.Getenv("AUTH_REQUIRE_VERIFIED_EMAIL"); v != "" {
+		if require, err := strconv.ParseBool(v); err == nil {
+			config.Auth.RequireVerifiedEmail = require
+		}
+	}
+
+	// Mail Configuration
+	if v := os.Getenv("MAIL_DRIVER"); v != "" {
+		config.Mail.Driver = v
+	}
+	if v := os.Getenv("MAIL_FROM"); v != "" {
+		config.Mail.From = v
+	}
+	if v := os.Getenv("SMTP_HOST"); v != "" {
+		config.Mail.SMTP.Host = v
+	}
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			config.Mail.SMTP.Port = port
+		}
+	}
+	if v := os.Getenv("SMTP_USERNAME"); v != "" {
+		config.Mail.SMTP.Username = v
+	}
+	if v := os.Getenv("SMTP_PASSWORD"); v != "" {
+		config.Mail.SMTP.Password = v
+	}
+
+	// LDAP Configuration
+	if v := os.Getenv("LDAP_BIND_PASSWORD"); v != "" {
+		config.LDAP.BindPassword = v
+	}
+
+	// SCIM Configuration
+	if v := os.Getenv("SCIM_TOKEN"); v != "" {
+		config.SCIM.Token = v
+	}
+
+	// WebAuthn Configuration
+	if v := os.Getenv("WEBAUTHN_RP_ID"); v != "" {
+		config.WebAuthn.RPID = v
+	}
+	if v := os.Getenv("WEBAUTHN_RP_ORIGINS"); v != "" {
+		config.WebAuthn.RPOrigins = strings.Split(v, ",")
+	}
 
 	// Snowflake Configuration
 	if v := os.Getenv("SNOWFLAKE_WORKER_ID"); v != "" {
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadFile(t *testing.T, content string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_PATH", path)
+	return LoadYAMLConfig()
+}
+
+func TestLoadYAMLConfigKeepsDefaults(t *testing.T) {
+	cfg := loadFile(t, "app:\n  name: chat\nauth:\n  ttl: 60\n")
+
+	if cfg.App.Name != "chat" || cfg.Auth.TTL != 60 {
+		t.Errorf("file values not applied: name %q, ttl %d", cfg.App.Name, cfg.Auth.TTL)
+	}
+	// Keys the file leaves out keep their defaults
+	defaults := getDefaultConfig()
+	if cfg.Auth.Lockout != defaults.Auth.Lockout || cfg.Auth.RefreshTTL != defaults.Auth.RefreshTTL || cfg.Hash.QueueDepth != defaults.Hash.QueueDepth {
+		t.Errorf("missing keys lost their defaults: %+v", cfg.Auth)
+	}
+}
+
+func TestLoadYAMLConfigRedis(t *testing.T) {
+	// Without a redis section the gateway runs without Redis, as it did before defaults were merged
+	if cfg := loadFile(t, "app:\n  name: chat\n"); cfg.Redis.Addr != "" {
+		t.Errorf("redis addr = %q, want none", cfg.Redis.Addr)
+	}
+	if cfg := loadFile(t, "redis:\n  addr: redis:6379\n"); cfg.Redis.Addr != "redis:6379" {
+		t.Errorf("redis addr = %q, want the configured one", cfg.Redis.Addr)
+	}
+}
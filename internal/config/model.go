@@ -9,11 +9,18 @@ type Config struct {
 	Auth      Auth      `yaml:"auth"`
 	Snowflake Snowflake `yaml:"snowflake"`
 	Hash      Hash      `yaml:"hash"`
+	Mail      Mail      `yaml:"mail"`
+	WebAuthn  WebAuthn  `yaml:"webauthn"`
+	OIDC      []OIDC    `yaml:"oidc"`
+	SAML      []SAML    `yaml:"saml"`
+	LDAP      LDAP      `yaml:"ldap"`
+	SCIM      SCIM      `yaml:"scim"`
 }
 
 type App struct {
-	Name string `yaml:"name"`
-	Env  string `yaml:"env"`
+	Name    string `yaml:"name"`
+	Env     string `yaml:"env"`
+	BaseURL string `yaml:"base_url"` // Public URL of the web client, used to build emailed links
 }
 
 type HTTP struct {
@@ -34,8 +41,91 @@ type Database struct {
 }
 
 type Auth struct {
-	Keys string `yaml:"keys"`
-	TTL  uint32 `yaml:"ttl"`
+	Keys       string `yaml:"keys"`        // Single signing key, used when Keyring is empty
+	TTL        uint32 `yaml:"ttl"`         // Access token lifetime in seconds
+	RefreshTTL uint32 `yaml:"refresh_ttl"` // Refresh token lifetime in seconds
+
+	Keyring   []SigningKey `yaml:"keyring"`
+	ActiveKey string       `yaml:"active_key"` // ID of the keyring entry new tokens are signed with
+
+	Format string `yaml:"format"` // Access token format, branca or paseto
+	PASETO PASETO `yaml:"paseto"`
+
+	RequireVerifiedEmail bool   `yaml:"require_verified_email"` // Refuse sign in until the email is verified
+	Registration         string `yaml:"registration"`           // open, invite or closed
+
+	Lockout  Lockout        `yaml:"lockout"`
+	Password PasswordPolicy `yaml:"password"`
+
+	Challenge Challenge `yaml:"challenge"`
+	MagicLink MagicLink `yaml:"magic_link"`
+
+	MaxBots uint32 `yaml:"max_bots"` // Bots each user may own
+}
+
+// SigningKey One token key. The 32 byte material, raw or hex encoded, comes from Key, File or Env.
+// Keys keep opening tokens until retired, so a key is retired once its tokens have expired.
+type SigningKey struct {
+	ID      string `yaml:"id"`
+	Key     string `yaml:"key"`
+	File    string `yaml:"file"` // Path to a file holding the key
+	Env     string `yaml:"env"`  // Name of an environment variable holding the key
+	Retired bool   `yaml:"retired"`
+}
+
+// PASETO v4.public access tokens, signed with Ed25519 keys whose 32 byte seeds come from the keyring
+type PASETO struct {
+	Keyring   []SigningKey `yaml:"keyring"`
+	ActiveKey string       `yaml:"active_key"`
+	Issuer    string       `yaml:"issuer"`
+	Audience  string       `yaml:"audience"` // Set on issued tokens and required when parsing, empty to skip
+}
+
+// Lockout Sign in throttling, failures beyond the free attempts double the delay each time
+type Lockout struct {
+	UserAttempts uint32 `yaml:"user_attempts"` // Free failures per username
+	IPAttempts   uint32 `yaml:"ip_attempts"`   // Free failures per client IP
+	BaseDelay    uint32 `yaml:"base_delay"`    // First lockout in seconds
+	MaxDelay     uint32 `yaml:"max_delay"`     // Longest lockout in seconds
+	Window       uint32 `yaml:"window"`        // Seconds without failures before the count resets
+}
+
+// PasswordPolicy Rules for new passwords, existing passwords keep working
+type PasswordPolicy struct {
+	MinLength    int     `yaml:"min_length"`
+	MaxLength    int     `yaml:"max_length"`
+	MinEntropy   float64 `yaml:"min_entropy"`   // Estimated strength in bits
+	BreachedList string  `yaml:"breached_list"` // Sorted SHA-1 list in the Pwned Passwords format, empty to skip
+}
+
+// Challenge Anti-bot challenges on public endpoints, answered with a proof of work or a CAPTCHA
+type Challenge struct {
+	Provider      string           `yaml:"provider"`       // pow or captcha, empty to disable challenges
+	SignUp        bool             `yaml:"sign_up"`        // Require one on every sign up
+	PasswordReset bool             `yaml:"password_reset"` // Require one on every reset link request
+	MagicLink     bool             `yaml:"magic_link"`     // Require one on every sign in link request
+	SignInAfter   uint32           `yaml:"sign_in_after"`  // Failed sign ins of a user or IP before one is required, 0 for never
+	PoW           PoWChallenge     `yaml:"pow"`
+	Captcha       CaptchaChallenge `yaml:"captcha"`
+}
+
+// MagicLink Passwordless sign in through emailed links, which also allows accounts without a password
+type MagicLink struct {
+	Enabled bool   `yaml:"enabled"`
+	TTL     uint32 `yaml:"ttl"` // Link lifetime in seconds
+}
+
+type PoWChallenge struct {
+	Difficulty uint8  `yaml:"difficulty"` // Leading zero bits of the answer hash, each one doubles the expected work
+	TTL        uint32 `yaml:"ttl"`        // Seconds to solve a puzzle
+}
+
+// CaptchaChallenge Widget verified through a siteverify style API, such as hCaptcha or Turnstile
+type CaptchaChallenge struct {
+	VerifyURL string `yaml:"verify_url"` // Such as https://challenges.cloudflare.com/turnstile/v0/siteverify
+	SiteKey   string `yaml:"site_key"`   // Public key handed to the widget
+	Secret    string `yaml:"secret"`
+	Timeout   uint32 `yaml:"timeout"` // Seconds to wait for the verify API
 }
 
 type Snowflake struct {
@@ -50,4 +140,88 @@ type Hash struct {
 	Inerations uint32 `yaml:"iterations"`
 	SaltLength uint32 `yaml:"salt_length"`
 	KeyLength  uint32 `yaml:"key_length"`
+
+	// Each argon2 operation holds Memory KiB, so these bound the total during sign in storms
+	Concurrency int    `yaml:"concurrency"` // Hashes running at once, 0 for the CPU count
+	QueueDepth  int    `yaml:"queue_depth"` // Hashes waiting for a worker before requests are refused
+	MaxWait     uint32 `yaml:"max_wait"`    // Longest wait for a worker in seconds
+}
+
+type Mail struct {
+	Driver  string `yaml:"driver"`   // smtp or file
+	From    string `yaml:"from"`     // Sender address
+	DropDir string `yaml:"drop_dir"` // Directory for the file driver, mails are only logged when empty
+	SMTP    SMTP   `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type WebAuthn struct {
+	RPID          string   `yaml:"rp_id"`           // Relying party ID, the site's registrable domain
+	RPDisplayName string   `yaml:"rp_display_name"` // Name shown by authenticators
+	RPOrigins     []string `yaml:"rp_origins"`      // Origins allowed to run ceremonies
+}
+
+type OIDC struct {
+	Name         string   `yaml:"name"`         // URL slug of the provider, such as "corp"
+	DisplayName  string   `yaml:"display_name"` // Label of the sign in button
+	Issuer       string   `yaml:"issuer"`       // Issuer URL, discovery is read from its well-known path
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"` // Empty for public clients relying on PKCE only
+	Scopes       []string `yaml:"scopes"`        // Extra scopes besides openid, defaults to profile and email
+	RedirectURL  string   `yaml:"redirect_url"`  // Defaults to the gateway callback under base_url
+	AllowSignUp  bool     `yaml:"allow_sign_up"` // Create accounts for unknown identities on first sign in
+}
+
+// SAML SAML 2.0 identity provider. Assertions must be signed, and AuthnRequests are signed with the service provider key.
+type SAML struct {
+	Name        string `yaml:"name"`         // URL slug of the provider, such as "corp"
+	DisplayName string `yaml:"display_name"` // Label of the sign in button
+	MetadataURL string `yaml:"metadata_url"` // Identity provider metadata, fetched on first use
+	Metadata    string `yaml:"metadata"`     // Path of an identity provider metadata file, used instead of metadata_url
+	EntityID    string `yaml:"entity_id"`    // Service provider entity ID, defaults to its metadata URL under base_url
+	Certificate string `yaml:"certificate"`  // Path of the PEM certificate published in the service provider metadata
+	PrivateKey  string `yaml:"private_key"`  // Path of the PEM key signing AuthnRequests
+
+	UsernameAttribute string            `yaml:"username_attribute"` // Derived from the email when empty
+	EmailAttribute    string            `yaml:"email_attribute"`    // The NameID is used when empty and in the emailAddress format
+	GroupsAttribute   string            `yaml:"groups_attribute"`
+	GroupRoles        map[string]string `yaml:"group_roles"` // Group to role, roles are left alone when empty
+
+	AllowIdPInitiated bool `yaml:"allow_idp_initiated"` // Accept responses the service provider did not request
+	AllowSignUp       bool `yaml:"allow_sign_up"`       // Create accounts for unknown identities on first sign in
+}
+
+// LDAP Directory sign in, tried before local passwords. Accounts it provisions only ever sign in through it.
+type LDAP struct {
+	Enabled  bool   `yaml:"enabled"`
+	URL      string `yaml:"url"`       // ldap:// or ldaps:// URL of the server
+	StartTLS bool   `yaml:"start_tls"` // Upgrade ldap:// connections before binding
+	Timeout  uint32 `yaml:"timeout"`   // Seconds for connecting and for each request
+
+	UserDN       string `yaml:"user_dn"` // DN template such as "uid={username},ou=people,dc=example,dc=com", binds without searching
+	BindDN       string `yaml:"bind_dn"` // Service account that searches for users when UserDN is empty
+	BindPassword string `yaml:"bind_password"`
+	BaseDN       string `yaml:"base_dn"`     // Where users are searched
+	UserFilter   string `yaml:"user_filter"` // Such as "(uid={username})"
+
+	UsernameAttribute string `yaml:"username_attribute"`
+	EmailAttribute    string `yaml:"email_attribute"`
+
+	GroupBaseDN string            `yaml:"group_base_dn"` // Where groups are searched, the memberOf attribute is read when empty
+	GroupFilter string            `yaml:"group_filter"`  // Such as "(member={dn})"
+	GroupRoles  map[string]string `yaml:"group_roles"`   // Group DN to role, roles are left alone when empty
+
+	Provision bool `yaml:"provision"` // Create accounts on first sign in
+}
+
+// SCIM Provisioning API through which an identity provider pushes users and groups
+type SCIM struct {
+	Enabled bool   `yaml:"enabled"`
+	Token   string `yaml:"token"` // Bearer token of the provisioning client, at least 32 characters
 }
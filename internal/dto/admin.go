@@ -0,0 +1,38 @@
+package dto
+
+import "time"
+
+// HashReportResp Password hash migration report structure
+type HashReportResp struct {
+	CurrentParams string          `json:"currentParams" example:"m=65536,t=3,p=4,s=16,k=32"`
+	Outdated      int64           `json:"outdated" example:"42"` // Accounts upgraded on their next sign in
+	Groups        []HashGroupResp `json:"groups"`
+}
+
+// HashGroupResp Accounts sharing a hash parameter set
+type HashGroupResp struct {
+	Algorithm string `json:"algorithm" example:"argon2id"`
+	Params    string `json:"params" example:"m=65536,t=3,p=4,s=16,k=32"`
+	Count     int64  `json:"count" example:"1000"`
+	Current   bool   `json:"current" example:"true"`
+}
+
+// CreateInviteReq Create invite request structure
+type CreateInviteReq struct {
+	MaxUses   int    `json:"maxUses" binding:"required,min=1,max=10000" example:"1"`
+	ExpiresIn uint32 `json:"expiresIn" example:"604800"` // Seconds until the invite expires, 0 for never
+	Note      string `json:"note" binding:"omitempty,max=128" example:"For Alex"`
+}
+
+// InviteResp Invite structure, the code is only returned once when the invite is created
+type InviteResp struct {
+	ID        uint       `json:"id" example:"1"`
+	Code      string     `json:"code,omitempty" example:"k3TQ8xV2..."`
+	Note      string     `json:"note" example:"For Alex"`
+	MaxUses   int        `json:"maxUses" example:"1"`
+	Uses      int        `json:"uses" example:"0"`
+	CreatedBy int64      `json:"createdBy,string" example:"1790000000000000000"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
@@ -4,22 +4,87 @@ import "github.com/go-playground/validator/v10"
 
 // SignInReq Sign in request structure
 type SignInReq struct {
-	Username string `json:"username" binding:"required,min=2,max=20" example:"xxx"`
-	Password string `json:"password" binding:"required,min=2,max=32"       example:"******"`
-	Email    string `json:"email" binding:"required"       example:"xxx@example.com"`
+	Identifier string `json:"identifier" binding:"required,max=64" example:"xxx"` // Username or email, case insensitive
+	Password   string `json:"password" binding:"required,max=1024"       example:"******"`
+	DeviceName string `json:"deviceName" binding:"omitempty,max=64" example:"My laptop"`
+	Challenge  string `json:"challenge" binding:"omitempty,max=4096" example:"Answer"` // Needed after repeated failures
 }
 
 // SignUpReq Sign up request structure
 type SignUpReq struct {
-	Username string `json:"username" binding:"required,min=2,max=20" example:"xxx"`
-	Password string `json:"password" binding:"required,min=2,max=32"       example:"******"`
-	Email    string `json:"email" binding:"required"       example:"xxx@example.com"`
+	Username   string `json:"username" binding:"required,username" example:"xxx"`
+	Password   string `json:"password" binding:"omitempty,max=1024"       example:"******"` // Optional when sign in links are enabled
+	Email      string `json:"email" binding:"required,user_email" example:"xxx@example.com"`
+	InviteCode string `json:"inviteCode" binding:"omitempty,max=64" example:"k3TQ8xV2..."` // Required when registration is invite only
+	DeviceName string `json:"deviceName" binding:"omitempty,max=64" example:"My laptop"`
+	Challenge  string `json:"challenge" binding:"omitempty,max=4096" example:"Answer"` // Required when sign up challenges are enabled
+}
+
+// VerifyEmailReq Verify email request structure
+type VerifyEmailReq struct {
+	Token string `json:"token" binding:"required" example:"Token"`
+}
+
+// ResendVerificationReq Resend verification email request structure
+type ResendVerificationReq struct {
+	Email string `json:"email" binding:"required,email" example:"xxx@example.com"`
+}
+
+// ForgotPasswordReq Forgot password request structure
+type ForgotPasswordReq struct {
+	Email     string `json:"email" binding:"required,email" example:"xxx@example.com"`
+	Challenge string `json:"challenge" binding:"omitempty,max=4096" example:"Answer"` // Required when reset challenges are enabled
+}
+
+// MagicLinkReq Sign in link request structure
+type MagicLinkReq struct {
+	Email     string `json:"email" binding:"required,email" example:"xxx@example.com"`
+	Challenge string `json:"challenge" binding:"omitempty,max=4096" example:"Answer"` // Required when sign in link challenges are enabled
+}
+
+// ConsumeMagicLinkReq Sign in link consumption request structure
+type ConsumeMagicLinkReq struct {
+	Token      string `json:"token" binding:"required" example:"Token"`
+	DeviceName string `json:"deviceName" binding:"omitempty,max=64" example:"My laptop"`
+}
+
+// ResetPasswordReq Reset password request structure
+type ResetPasswordReq struct {
+	Token    string `json:"token" binding:"required" example:"Token"`
+	Password string `json:"password" binding:"required,max=1024" example:"******"`
+}
+
+// RefreshReq Refresh request structure, the refresh token cookie is used when empty
+type RefreshReq struct {
+	RefreshToken string `json:"refreshToken" example:"RefreshToken"`
 }
 
 // TokenResp Sign in or Sign up response structure
 type TokenResp struct {
-	Token string `json:"token" example:"Token"`
+	Token            string `json:"token" example:"Token"`
+	RefreshToken     string `json:"refreshToken" example:"RefreshToken"`
+	ExpiresIn        uint32 `json:"expiresIn" example:"900"`
+	RefreshExpiresIn uint32 `json:"refreshExpiresIn" example:"2592000"`
 }
 
 // Validate Single case verifier
 var Validate = validator.New()
+
+// ChangePasswordReq Change password request structure
+type ChangePasswordReq struct {
+	CurrentPassword string `json:"currentPassword" binding:"max=1024" example:"******"` // Empty for accounts without a password
+	NewPassword     string `json:"newPassword" binding:"required,max=1024" example:"******"`
+}
+
+// PublicKeyResp Token verification key response structure
+type PublicKeyResp struct {
+	KeyID     string `json:"kid" example:"2026-01"`
+	Version   string `json:"version" example:"v4.public"`
+	PublicKey string `json:"publicKey" example:"k4.public.Hrnbu7wEfAP9cGBOAHHwmH4Wsot1ciXBHwBBXQ4gsaI"` // PASERK encoding
+	Active    bool   `json:"active" example:"true"`
+}
+
+// PublicKeysResp Token verification keys response structure
+type PublicKeysResp struct {
+	Keys []PublicKeyResp `json:"keys"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+// CreateBotReq Create bot request structure
+type CreateBotReq struct {
+	Username string `json:"username" binding:"required,username" example:"builds"`
+}
+
+// BotResp Bot account structure
+type BotResp struct {
+	ID        int64     `json:"id,string" example:"1790000000000000000"`
+	Username  string    `json:"username" example:"builds"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateAPITokenReq Create API token request structure
+type CreateAPITokenReq struct {
+	Name      string   `json:"name" binding:"required,max=64" example:"CI notifications"`
+	Scopes    []string `json:"scopes" binding:"required,min=1,dive,oneof=profile:read rooms:read rooms:write messages:read messages:write files:read files:write" example:"messages:write"`
+	BotID     int64    `json:"botId,string,omitempty" example:"1790000000000000000"` // Bot the token acts as, the caller when omitted
+	ExpiresIn uint32   `json:"expiresIn" example:"7776000"`                          // Seconds until the token expires, 0 for never
+}
+
+// APITokenResp API token structure, the token itself is only returned when created or rotated
+type APITokenResp struct {
+	ID         uint       `json:"id" example:"1"`
+	Name       string     `json:"name" example:"CI notifications"`
+	Token      string     `json:"token,omitempty" example:"aur_k3TQ8xV2..."`
+	Hint       string     `json:"hint" example:"aur_k3TQ8x"`
+	Scopes     []string   `json:"scopes" example:"messages:write"`
+	UserID     int64      `json:"userId,string" example:"1790000000000000000"` // User or bot the token acts as
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+// ChallengeResp Anti-bot challenge structure. Proof of work puzzles are answered with "<token>:<nonce>",
+// where the SHA-256 hash of the answer starts with difficulty zero bits. CAPTCHA challenges are answered
+// with the response of the widget created from the site key.
+type ChallengeResp struct {
+	Type       string `json:"type" example:"pow"` // pow or captcha
+	Token      string `json:"token,omitempty" example:"Token"`
+	Difficulty uint8  `json:"difficulty,omitempty" example:"20"`
+	ExpiresIn  uint32 `json:"expiresIn,omitempty" example:"300"` // Seconds to solve the puzzle
+	SiteKey    string `json:"siteKey,omitempty" example:"SiteKey"`
+}
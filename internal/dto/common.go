@@ -1,6 +1,7 @@
 package dto
 
 type ErrorResp struct {
-	Code int `json:"code" example:"1001"`
+	Code    int      `json:"code" example:"1001"`
+	Reasons []string `json:"reasons,omitempty" example:"too_short"` // Details of the error, such as password policy violations
 	//Msg  int `json:"msg" example:"1001"`
 }
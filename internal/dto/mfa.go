@@ -0,0 +1,44 @@
+package dto
+
+// MFARequiredResp Sign in response when a second factor is required
+type MFARequiredResp struct {
+	MFARequired bool   `json:"mfaRequired" example:"true"`
+	Ticket      string `json:"ticket" example:"Ticket"`
+	ExpiresIn   uint32 `json:"expiresIn" example:"300"`
+}
+
+// MFAVerifyReq Second sign in step request structure, code is a TOTP code or a recovery code
+type MFAVerifyReq struct {
+	Ticket     string `json:"ticket" binding:"required" example:"Ticket"`
+	Code       string `json:"code" binding:"required,max=32" example:"123456"`
+	DeviceName string `json:"deviceName" binding:"omitempty,max=64" example:"My laptop"`
+}
+
+// TOTPSetupResp TOTP enrollment response structure
+type TOTPSetupResp struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri" example:"otpauth://totp/AurChat:xxx?secret=JBSWY3DPEHPK3PXP&issuer=AurChat"`
+}
+
+// TOTPConfirmReq TOTP enrollment confirmation request structure
+type TOTPConfirmReq struct {
+	Code string `json:"code" binding:"required,len=6,numeric" example:"123456"`
+}
+
+// MFAReauthReq Re-authentication for sensitive two-factor changes
+type MFAReauthReq struct {
+	Password string `json:"password" binding:"required,max=128" example:"******"`
+	Code     string `json:"code" binding:"required,max=32" example:"123456"`
+}
+
+// ReauthReq Re-authentication for adding a sign in method. The password is needed when the
+// account has one, the code when two-factor authentication is enabled.
+type ReauthReq struct {
+	Password string `json:"password" binding:"max=128" example:"******"`
+	Code     string `json:"code" binding:"max=32" example:"123456"`
+}
+
+// RecoveryCodesResp Recovery codes, only shown once
+type RecoveryCodesResp struct {
+	RecoveryCodes []string `json:"recoveryCodes" example:"k3m9q-x2w7a"`
+}
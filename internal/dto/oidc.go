@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// OIDCProviderResp Identity provider structure
+type OIDCProviderResp struct {
+	Name        string `json:"name" example:"corp"`
+	DisplayName string `json:"displayName" example:"Corp SSO"`
+}
+
+// OIDCLinkResp Provider authorization URL to open for linking
+type OIDCLinkResp struct {
+	URL string `json:"url" example:"https://idp.example.com/authorize?client_id=aurchat"`
+}
+
+// LinkedIdentityResp Linked identity structure
+type LinkedIdentityResp struct {
+	ID        uint      `json:"id" example:"1"`
+	Provider  string    `json:"provider" example:"corp"`
+	Email     string    `json:"email" example:"xxx@example.com"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+// SAMLProviderResp SAML identity provider structure
+type SAMLProviderResp struct {
+	Name        string `json:"name" example:"corp"`
+	DisplayName string `json:"displayName" example:"Corp SSO"`
+}
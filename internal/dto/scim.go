@@ -0,0 +1,124 @@
+package dto
+
+import "time"
+
+// SCIM schema URNs (RFC 7643 and RFC 7644)
+const (
+	SCIMUserSchema          = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMGroupSchema         = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMListResponseSchema  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMPatchOpSchema       = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMErrorSchema         = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMServiceConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// SCIMMeta SCIM resource metadata structure
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType" example:"User"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location" example:"https://chat.example.com/api/v1/scim/v2/Users/1234567890"`
+}
+
+// SCIMEmail SCIM email structure
+type SCIMEmail struct {
+	Value   string `json:"value" example:"alice@example.com"`
+	Type    string `json:"type,omitempty" example:"work"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMUser SCIM user structure, used for requests and responses
+type SCIMUser struct {
+	Schemas    []string    `json:"schemas"`
+	ID         string      `json:"id,omitempty" example:"1234567890"`
+	ExternalID string      `json:"externalId,omitempty" example:"00u1a2b3c4"`
+	UserName   string      `json:"userName" example:"alice"`
+	Emails     []SCIMEmail `json:"emails,omitempty"`
+	Active     *bool       `json:"active,omitempty"` // Missing on a request means active
+	Password   string      `json:"password,omitempty"`
+	Meta       *SCIMMeta   `json:"meta,omitempty"`
+}
+
+// SCIMMember SCIM group member structure
+type SCIMMember struct {
+	Value   string `json:"value" example:"1234567890"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty" example:"alice"`
+}
+
+// SCIMGroup SCIM group structure, used for requests and responses
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty" example:"1"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName" example:"Engineering"`
+	Members     []SCIMMember `json:"members,omitempty"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+// SCIMListResp SCIM list response structure
+type SCIMListResp struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults" example:"1"`
+	StartIndex   int      `json:"startIndex" example:"1"`
+	ItemsPerPage int      `json:"itemsPerPage" example:"1"`
+	Resources    []any    `json:"Resources"`
+}
+
+// SCIMPatchOp SCIM PATCH operation structure
+type SCIMPatchOp struct {
+	Op    string `json:"op" example:"replace"`
+	Path  string `json:"path,omitempty" example:"active"`
+	Value any    `json:"value,omitempty" swaggertype:"object"`
+}
+
+// SCIMPatchReq SCIM PATCH request structure
+type SCIMPatchReq struct {
+	Schemas    []string      `json:"schemas"`
+	Operations []SCIMPatchOp `json:"Operations"`
+}
+
+// SCIMErrorResp SCIM error structure, status is a string as RFC 7644 requires
+type SCIMErrorResp struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status" example:"404"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty" example:"User not found"`
+}
+
+// SCIMSupported SCIM feature flag structure
+type SCIMSupported struct {
+	Supported bool `json:"supported"`
+}
+
+// SCIMFilterConfig SCIM filter support structure
+type SCIMFilterConfig struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults" example:"200"`
+}
+
+// SCIMBulkConfig SCIM bulk support structure
+type SCIMBulkConfig struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+// SCIMAuthScheme SCIM authentication scheme structure
+type SCIMAuthScheme struct {
+	Type        string `json:"type" example:"oauthbearertoken"`
+	Name        string `json:"name" example:"Bearer token"`
+	Description string `json:"description"`
+}
+
+// SCIMServiceConfigResp SCIM service provider configuration structure
+type SCIMServiceConfigResp struct {
+	Schemas               []string         `json:"schemas"`
+	Patch                 SCIMSupported    `json:"patch"`
+	Bulk                  SCIMBulkConfig   `json:"bulk"`
+	Filter                SCIMFilterConfig `json:"filter"`
+	ChangePassword        SCIMSupported    `json:"changePassword"`
+	Sort                  SCIMSupported    `json:"sort"`
+	ETag                  SCIMSupported    `json:"etag"`
+	AuthenticationSchemes []SCIMAuthScheme `json:"authenticationSchemes"`
+}
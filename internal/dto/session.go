@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+// SessionResp Signed-in device structure
+type SessionResp struct {
+	ID         int64     `json:"id,string" example:"1790000000000000000"`
+	DeviceName string    `json:"deviceName" example:"My laptop"`
+	UserAgent  string    `json:"userAgent" example:"Mozilla/5.0"`
+	IP         string    `json:"ip" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current" example:"true"`
+}
+
+// RenameSessionReq Rename device request structure
+type RenameSessionReq struct {
+	DeviceName string `json:"deviceName" binding:"required,max=64" example:"My laptop"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// PasskeyResp Registered passkey structure
+type PasskeyResp struct {
+	ID         uint       `json:"id" example:"1"`
+	Name       string     `json:"name" example:"My phone"`
+	Synced     bool       `json:"synced" example:"true"` // Backed up to a passkey provider
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// RenamePasskeyReq Rename passkey request structure
+type RenamePasskeyReq struct {
+	Name string `json:"name" binding:"required,max=64" example:"My phone"`
+}
+
+// PasskeyLoginBeginReq Passkey sign in request structure, an empty username lets the authenticator pick the account
+type PasskeyLoginBeginReq struct {
+	Username string `json:"username" binding:"omitempty,max=20" example:"xxx"`
+}
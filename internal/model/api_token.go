@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// API token scopes, checked by routes with middleware.RequireScopes
+const (
+	ScopeProfileRead   = "profile:read"
+	ScopeRoomsRead     = "rooms:read"
+	ScopeRoomsWrite    = "rooms:write"
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+	ScopeFilesRead     = "files:read"
+	ScopeFilesWrite    = "files:write"
+)
+
+// APIToken Long-lived bearer token of a user or bot, only its hash is stored
+type APIToken struct {
+	gorm.Model
+	UserID     int64      `gorm:"index;not null"`               // User ID the token acts as, a bot or its creator
+	CreatedBy  int64      `gorm:"index;not null"`               // Creator User ID
+	Name       string     `gorm:"size:64;not null"`             // What the token is used for
+	Hint       string     `gorm:"size:16;not null"`             // Start of the token, to recognize it in listings
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null"` // SHA-256 of the token
+	Scopes     string     `gorm:"size:512;not null"`            // Comma separated scopes
+	ExpiresAt  *time.Time // Expire Time, nil for no expiry
+	LastUsedAt *time.Time // Last authenticated request, updated at most once a minute
+	RevokedAt  *time.Time // Set once revoked
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GroupIndexes Group names are unique regardless of case
+var GroupIndexes = []string{
+	"CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_display_name_lower ON groups (lower(display_name)) WHERE deleted_at IS NULL",
+}
+
+// Group Set of users pushed from an identity provider through SCIM
+type Group struct {
+	gorm.Model
+	DisplayName string `gorm:"size:255;not null"`
+	ExternalID  string `gorm:"size:255;index"` // Identifier assigned by the provisioning client
+}
+
+// GroupMember Membership of a user in a group
+type GroupMember struct {
+	GroupID   uint      `gorm:"primaryKey"`
+	UserID    int64     `gorm:"primaryKey;index"`
+	CreatedAt time.Time // Time the user joined
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invite Code admitting new accounts while registration is invite only, only its hash is stored
+type Invite struct {
+	gorm.Model
+	CodeHash  string     `gorm:"size:64;uniqueIndex;not null"` // SHA-256 of the code
+	CreatedBy int64      `gorm:"index;not null"`               // Creator User ID
+	Note      string     `gorm:"size:128"`                     // Who or what the invite is for
+	MaxUses   int        `gorm:"not null"`                     // Accounts the code can register
+	Uses      int        `gorm:"not null;default:0"`           // Accounts registered so far
+	ExpiresAt *time.Time // Expire Time, nil for no expiry
+	RevokedAt *time.Time // Set once revoked by an administrator
+}
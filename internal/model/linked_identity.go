@@ -0,0 +1,13 @@
+package model
+
+import "gorm.io/gorm"
+
+// LinkedIdentity An external identity provider account that signs in as a user
+type LinkedIdentity struct {
+	gorm.Model
+	UserID   int64  `gorm:"index;not null"`                                   // Linked User ID
+	Provider string `gorm:"size:64;not null"`                                 // Configured provider name
+	Issuer   string `gorm:"size:255;not null;uniqueIndex:idx_issuer_subject"` // ID token iss claim
+	Subject  string `gorm:"size:255;not null;uniqueIndex:idx_issuer_subject"` // ID token sub claim
+	Email    string `gorm:"size:64"`                                          // Email reported by the provider at linking
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// One-time token purposes
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+	TokenPurposeMagicLink     = "magic_link"
+)
+
+// OneTimeToken Single-use emailed token, only its hash is stored
+type OneTimeToken struct {
+	gorm.Model
+	UserID    int64      `gorm:"index;not null"`               // Owner User ID
+	Purpose   string     `gorm:"size:32;not null"`             // What the token may be used for
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null"` // SHA-256 of the token
+	ExpiresAt time.Time  `gorm:"not null"`                     // Expire Time
+	UsedAt    *time.Time // Set once the token has been consumed
+
+	NonceHash string `gorm:"size:64"` // SHA-256 of the browser nonce the token is bound to, empty when unbound
+}
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode Single-use two-factor fallback code, only its hash is stored
+type RecoveryCode struct {
+	gorm.Model
+	UserID   int64      `gorm:"index;not null"`         // Owner User ID
+	CodeHash string     `gorm:"size:64;index;not null"` // SHA-256 of the normalized code
+	UsedAt   *time.Time // Set once the code has been used
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken One link of a rotating refresh token family
+type RefreshToken struct {
+	gorm.Model
+	FamilyID  int64      `gorm:"index;not null"`               // Rotation family, shared by every rotated token
+	UserID    int64      `gorm:"index;not null"`               // Owner User ID
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null"` // SHA-256 of the refresh token
+	ExpiresAt time.Time  `gorm:"not null"`                     // Expire Time
+	UsedAt    *time.Time // Set once the token has been rotated
+	RevokedAt *time.Time // Set when the family is revoked
+}
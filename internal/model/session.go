@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session A signed-in device, shared by every token issued from one sign in
+type Session struct {
+	gorm.Model
+	SessionID  int64      `gorm:"uniqueIndex;not null"` // Session ID, also the refresh token family
+	UserID     int64      `gorm:"index;not null"`       // Owner User ID
+	DeviceName string     `gorm:"size:64"`              // User editable device name
+	UserAgent  string     `gorm:"size:255"`             // User-Agent at sign in
+	IP         string     `gorm:"size:64"`              // Client IP at sign in
+	LastSeenAt time.Time  `gorm:"not null"`             // Last authenticated request
+	RevokedAt  *time.Time // Set when the session is signed out or revoked
+}
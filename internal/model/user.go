@@ -6,13 +6,60 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin" // Granted directly in the database
+)
+
+// AuthSourceLDAP Accounts provisioned from the LDAP directory
+const AuthSourceLDAP = "ldap"
+
+// BotEmailDomain Reserved domain of the placeholder addresses bots are given, mail to it is never delivered
+const BotEmailDomain = "bots.invalid"
+
+// CaseIndex Unique index on lower(Column), which cannot be built while two rows differ only by case
+type CaseIndex struct {
+	Column string
+	SQL    string
+}
+
+// UserCaseIndexes Usernames and emails are unique regardless of case, lookups use the same lower() expressions.
+// Both are stored NFKC normalized, so compatibility variants of a name collide as well.
+var UserCaseIndexes = []CaseIndex{
+	{"username", "CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username)) WHERE deleted_at IS NULL"},
+	{"email", "CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email)) WHERE deleted_at IS NULL"},
+}
+
+// UserIndexes Lookalike usernames collide on their skeleton
+var UserIndexes = []string{
+	"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_skeleton ON users (username_skeleton) WHERE deleted_at IS NULL AND username_skeleton <> ''",
+}
+
 type User struct {
 	gorm.Model
 	UserID    int64     `gorm:"primaryKey;unique"` // User ID
 	Username  string    `gorm:"size:32;not null"`  // User Name
 	Email     string    `gorm:"size:64;not null"`  // User Email
-	Password  string    `gorm:"size:255;not null"` // User Password hash(Argon2)
+	Password  string    `gorm:"size:255;not null"` // User Password hash(Argon2), empty for passwordless accounts
 	CreatedAt time.Time `gorm:"->"`                // Create Time
 
-	_ struct{} `gorm:"uniqueIndex:idx_name_email"`
+	Role string `gorm:"size:16;not null;default:user"` // RoleUser or RoleAdmin
+
+	UsernameSkeleton string `gorm:"size:128;not null;default:''"` // Confusable skeleton of the username, unique so lookalike names collide
+
+	InviteID *uint `gorm:"index"` // Invite the account registered with, nil for open registration
+
+	Bot     bool   `gorm:"not null;default:false"` // Bot account, authenticates with API tokens only
+	OwnerID *int64 `gorm:"index"`                  // User ID of the human managing the bot
+
+	AuthSource string `gorm:"size:16;not null;default:''"` // Directory managing the account, such as AuthSourceLDAP, empty for local accounts
+
+	ExternalID string     `gorm:"size:255;index"` // Identifier assigned by a SCIM provisioning client
+	DisabledAt *time.Time // Set while the account is disabled, it can neither sign in nor use its tokens
+
+	EmailVerifiedAt *time.Time // Email verification time, nil until verified
+
+	TOTPSecret    string     `gorm:"size:64"` // Base32 TOTP secret, set from enrollment on
+	TOTPEnabledAt *time.Time // Two-factor activation time, nil while disabled or pending
+	TOTPLastStep  int64      // Last accepted TOTP time step, blocks code replay
 }
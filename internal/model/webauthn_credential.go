@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebAuthnCredential A passkey or security key registered by a user
+type WebAuthnCredential struct {
+	gorm.Model
+	UserID          int64      `gorm:"index;not null"`       // Owner User ID
+	CredentialID    []byte     `gorm:"uniqueIndex;not null"` // Authenticator credential ID
+	Name            string     `gorm:"size:64"`              // User editable name
+	PublicKey       []byte     `gorm:"not null"`             // COSE encoded public key
+	AttestationType string     `gorm:"size:32"`              // Attestation format at registration
+	Transports      string     `gorm:"size:128"`             // Comma separated transports
+	AAGUID          []byte     // Authenticator model
+	SignCount       uint32     `gorm:"not null;default:0"`     // Last seen signature counter
+	BackupEligible  bool       `gorm:"not null;default:false"` // Credential may sync between devices
+	BackupState     bool       `gorm:"not null;default:false"` // Credential is currently synced
+	LastUsedAt      *time.Time // Last successful sign in
+}
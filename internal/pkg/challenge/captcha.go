@@ -0,0 +1,87 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// maxCaptchaResponse Longest widget response sent on to the verify API
+const maxCaptchaResponse = 4096
+
+// Captcha Widget responses checked with a siteverify style API, the form POST of secret, response
+// and remoteip answered with {"success": bool} used by hCaptcha, Turnstile and reCAPTCHA
+type Captcha struct {
+	verifyURL string
+	siteKey   string
+	secret    string
+	client    *http.Client
+}
+
+type captchaResult struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// NewCaptcha New CAPTCHA verifier
+func NewCaptcha(verifyURL, siteKey, secret string, timeout time.Duration) *Captcha {
+	return &Captcha{
+		verifyURL: verifyURL,
+		siteKey:   siteKey,
+		secret:    secret,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Issue Hand out the site key, the widget creates the challenge on the client
+func (v *Captcha) Issue(_ string) (*Challenge, error) {
+	return &Challenge{Type: TypeCaptcha, SiteKey: v.siteKey}, nil
+}
+
+// Verify Ask the verify API whether the widget response is valid
+func (v *Captcha) Verify(ctx context.Context, _ string, answer, remoteIP string) error {
+	if answer == "" || len(answer) > maxCaptchaResponse {
+		return ErrFailed
+	}
+
+	form := url.Values{
+		"secret":   {v.secret},
+		"response": {answer},
+	}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha verify API returned status %d", resp.StatusCode)
+	}
+
+	var result captchaResult
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.Success {
+		logger.Logger.Debug("CAPTCHA response rejected", zap.Strings("errorCodes", result.ErrorCodes))
+		return ErrFailed
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger.Logger = zap.NewNop()
+}
+
+// siteverify Local stand-in for a siteverify API accepting one widget response
+type siteverify struct {
+	*httptest.Server
+	secret   string
+	accepted string
+	status   int           // Answer with this status instead, when set
+	delay    time.Duration // Wait before answering
+	body     string        // Answer with this body instead, when set
+	sent     []string      // Widget responses received
+	remoteIP string
+}
+
+func newSiteverify(t *testing.T) *siteverify {
+	t.Helper()
+	s := &siteverify{secret: "server-secret", accepted: "good-token"}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *siteverify) handle(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(s.delay)
+	if err := r.ParseForm(); err != nil || r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.sent = append(s.sent, r.PostForm.Get("response"))
+	s.remoteIP = r.PostForm.Get("remoteip")
+
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if s.body != "" {
+		_, _ = w.Write([]byte(s.body))
+		return
+	}
+
+	switch {
+	case r.PostForm.Get("secret") != s.secret:
+		_, _ = w.Write([]byte(`{"success":false,"error-codes":["invalid-input-secret"]}`))
+	case r.PostForm.Get("response") != s.accepted:
+		_, _ = w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+	default:
+		_, _ = w.Write([]byte(`{"success":true}`))
+	}
+}
+
+func TestCaptchaIssue(t *testing.T) {
+	captcha := NewCaptcha("https://verify.example.com", "site-key", "secret", time.Second)
+
+	issued, err := captcha.Issue(EndpointSignUp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued.Type != TypeCaptcha || issued.SiteKey != "site-key" || issued.Token != "" {
+		t.Errorf("challenge = %+v", issued)
+	}
+}
+
+func TestCaptchaVerify(t *testing.T) {
+	server := newSiteverify(t)
+	captcha := NewCaptcha(server.URL, "site-key", server.secret, time.Second)
+
+	if err := captcha.Verify(context.Background(), EndpointSignUp, "good-token", "203.0.113.7"); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if server.remoteIP != "203.0.113.7" {
+		t.Errorf("remoteip = %q, want the client IP", server.remoteIP)
+	}
+}
+
+func TestCaptchaVerifyRejected(t *testing.T) {
+	server := newSiteverify(t)
+
+	tests := []struct {
+		name   string
+		secret string
+		answer string
+	}{
+		{"wrong response", server.secret, "bad-token"},
+		{"wrong secret", "other-secret", "good-token"},
+		{"empty response", server.secret, ""},
+		{"oversized response", server.secret, strings.Repeat("x", maxCaptchaResponse+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captcha := NewCaptcha(server.URL, "site-key", tt.secret, time.Second)
+			if err := captcha.Verify(context.Background(), EndpointSignUp, tt.answer, ""); !errors.Is(err, ErrFailed) {
+				t.Errorf("err = %v, want ErrFailed", err)
+			}
+		})
+	}
+
+	// Answers rejected locally never reach the API
+	for _, response := range server.sent {
+		if response == "" || len(response) > maxCaptchaResponse {
+			t.Errorf("sent a response of %d bytes to the API", len(response))
+		}
+	}
+}
+
+func TestCaptchaVerifyUnavailable(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*siteverify)
+	}{
+		{"server error", func(s *siteverify) { s.status = http.StatusInternalServerError }},
+		{"malformed body", func(s *siteverify) { s.body = "not json" }},
+		{"timeout", func(s *siteverify) { s.delay = 200 * time.Millisecond }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSiteverify(t)
+			tt.setup(server)
+			captcha := NewCaptcha(server.URL, "site-key", server.secret, 50*time.Millisecond)
+
+			// The answer could not be checked, which is not the same as a wrong answer
+			err := captcha.Verify(context.Background(), EndpointSignUp, "good-token", "")
+			if err == nil || errors.Is(err, ErrFailed) {
+				t.Errorf("err = %v, want an error other than ErrFailed", err)
+			}
+		})
+	}
+}
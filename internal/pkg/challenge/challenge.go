@@ -0,0 +1,48 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+)
+
+// Endpoints a challenge can guard
+const (
+	EndpointSignUp        = "signUp"
+	EndpointSignIn        = "signIn"
+	EndpointPasswordReset = "passwordReset"
+	EndpointMagicLink     = "magicLink"
+)
+
+// Challenge types
+const (
+	TypeProofOfWork = "pow"
+	TypeCaptcha     = "captcha"
+)
+
+// ErrFailed The answer is wrong, expired or was already used
+var ErrFailed = errors.New("challenge answer rejected")
+
+// Challenge What a client needs to answer, a puzzle or the site key of a CAPTCHA widget
+type Challenge struct {
+	Type       string
+	Token      string // Proof of work puzzle
+	Difficulty uint8  // Leading zero bits the proof of work hash needs
+	ExpiresIn  uint32 // Seconds to solve the puzzle
+	SiteKey    string // CAPTCHA widget key
+}
+
+// Verifier Issues challenges for an endpoint and checks their answers
+type Verifier interface {
+	Issue(endpoint string) (*Challenge, error)
+	// Verify Check an answer, ErrFailed when it is rejected and other errors when it could not be checked
+	Verify(ctx context.Context, endpoint, answer, remoteIP string) error
+}
+
+// IsEndpoint Report whether the name is an endpoint challenges can guard
+func IsEndpoint(endpoint string) bool {
+	switch endpoint {
+	case EndpointSignUp, EndpointSignIn, EndpointPasswordReset, EndpointMagicLink:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package challenge
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+	"strings"
+
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+)
+
+// maxNonceLength Longest nonce accepted in an answer
+const maxNonceLength = 64
+
+// ProofOfWork Hashcash style puzzles verified without server state. The puzzle is a sealed token
+// naming its endpoint, and the answer "<puzzle>:<nonce>" is solved when its SHA-256 hash starts
+// with Difficulty zero bits. Solved puzzles are burned so each one admits a single request.
+type ProofOfWork struct {
+	sealer     *token.Sealer
+	difficulty uint8
+}
+
+type puzzle struct {
+	Endpoint string `json:"ep"`
+}
+
+// NewProofOfWork New proof of work verifier, the sealer TTL bounds the time to solve a puzzle
+func NewProofOfWork(sealer *token.Sealer, difficulty uint8) *ProofOfWork {
+	return &ProofOfWork{sealer: sealer, difficulty: difficulty}
+}
+
+// Issue Seal a new puzzle for the endpoint
+func (p *ProofOfWork) Issue(endpoint string) (*Challenge, error) {
+	sealed, err := p.sealer.Seal(puzzle{Endpoint: endpoint})
+	if err != nil {
+		return nil, err
+	}
+	return &Challenge{
+		Type:       TypeProofOfWork,
+		Token:      sealed,
+		Difficulty: p.difficulty,
+		ExpiresIn:  p.sealer.TTL(),
+	}, nil
+}
+
+// Verify Check the work before opening the puzzle, so unsolved answers cost a single hash
+func (p *ProofOfWork) Verify(_ context.Context, endpoint, answer, _ string) error {
+	sealed, nonce, ok := strings.Cut(answer, ":")
+	if !ok || sealed == "" || nonce == "" || len(nonce) > maxNonceLength {
+		return ErrFailed
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(answer))) < int(p.difficulty) {
+		return ErrFailed
+	}
+
+	var solved puzzle
+	if err := p.sealer.Open(sealed, &solved); err != nil {
+		if errors.Is(err, token.ErrRevocation) {
+			return err
+		}
+		return ErrFailed
+	}
+	if solved.Endpoint != endpoint {
+		return ErrFailed
+	}
+	return nil
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
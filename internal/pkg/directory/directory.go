@@ -0,0 +1,196 @@
+package directory
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ErrInvalidCredentials The directory has no such user, or the password is wrong
+var ErrInvalidCredentials = errors.New("invalid directory credentials")
+
+// Entry Directory account whose password was accepted
+type Entry struct {
+	DN       string
+	Username string
+	Email    string
+	Groups   []string // Group DNs
+}
+
+// Conn The LDAP operations a sign in needs, so tests can stand in for a server
+type Conn interface {
+	Bind(username, password string) error
+	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// Client Checks passwords by binding to an LDAP directory. Users are bound directly
+// through a DN template, or found by a service account search first.
+type Client struct {
+	cfg     config.LDAP
+	timeout time.Duration
+	dial    func() (Conn, error)
+}
+
+// NewClient New client dialing the configured server for every sign in
+func NewClient(cfg config.LDAP) *Client {
+	client := &Client{cfg: cfg, timeout: time.Duration(cfg.Timeout) * time.Second}
+	client.dial = client.dialServer
+	return client
+}
+
+// NewClientWithDialer New client using dial for connections instead of the configured server
+func NewClientWithDialer(cfg config.LDAP, dial func() (Conn, error)) *Client {
+	return &Client{cfg: cfg, timeout: time.Duration(cfg.Timeout) * time.Second, dial: dial}
+}
+
+// Authenticate Bind as the user and read their entry and groups
+func (c *Client) Authenticate(username, password string) (*Entry, error) {
+	// An empty password makes an unauthenticated bind, which servers accept for any DN
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var dn string
+	if c.cfg.UserDN != "" {
+		dn = strings.ReplaceAll(c.cfg.UserDN, "{username}", ldap.EscapeDN(username))
+	} else {
+		if err = c.bindService(conn); err != nil {
+			return nil, err
+		}
+		if dn, err = c.findUser(conn, username); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = conn.Bind(dn, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	// Read as the service account when there is one, users may not see their own groups
+	if c.cfg.BindDN != "" {
+		if err = c.bindService(conn); err != nil {
+			return nil, err
+		}
+	}
+	return c.readEntry(conn, dn)
+}
+
+func (c *Client) bindService(conn Conn) error {
+	if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); err != nil {
+		return fmt.Errorf("ldap service account bind: %w", err)
+	}
+	return nil
+}
+
+// findUser Search for the DN of the single user matching the filter
+func (c *Client) findUser(conn Conn, username string) (string, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		c.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, c.timeLimit(), false,
+		strings.ReplaceAll(c.cfg.UserFilter, "{username}", ldap.EscapeFilter(username)),
+		[]string{"dn"}, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return "", ErrInvalidCredentials
+		}
+		return "", err
+	}
+	// Ambiguous filters must not pick one of several accounts
+	if len(result.Entries) != 1 {
+		return "", ErrInvalidCredentials
+	}
+	return result.Entries[0].DN, nil
+}
+
+func (c *Client) readEntry(conn Conn, dn string) (*Entry, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, c.timeLimit(), false,
+		"(objectClass=*)",
+		[]string{c.cfg.UsernameAttribute, c.cfg.EmailAttribute, "memberOf"}, nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("ldap entry %q not readable", dn)
+	}
+
+	found := result.Entries[0]
+	entry := &Entry{
+		DN:       found.DN,
+		Username: found.GetAttributeValue(c.cfg.UsernameAttribute),
+		Email:    found.GetAttributeValue(c.cfg.EmailAttribute),
+		Groups:   found.GetAttributeValues("memberOf"),
+	}
+
+	if c.cfg.GroupBaseDN != "" {
+		if entry.Groups, err = c.findGroups(conn, entry); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// findGroups Search for the groups listing the entry as a member
+func (c *Client) findGroups(conn Conn, entry *Entry) ([]string, error) {
+	filter := strings.NewReplacer(
+		"{dn}", ldap.EscapeFilter(entry.DN),
+		"{username}", ldap.EscapeFilter(entry.Username),
+	).Replace(c.cfg.GroupFilter)
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		c.cfg.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, c.timeLimit(), false,
+		filter, []string{"dn"}, nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, group := range result.Entries {
+		groups = append(groups, group.DN)
+	}
+	return groups, nil
+}
+
+func (c *Client) dialServer() (Conn, error) {
+	conn, err := ldap.DialURL(c.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: c.timeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(c.timeout)
+
+	if c.cfg.StartTLS {
+		serverURL, err := url.Parse(c.cfg.URL)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if err = conn.StartTLS(&tls.Config{ServerName: serverURL.Hostname(), MinVersion: tls.VersionTLS12}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *Client) timeLimit() int {
+	return int(c.timeout.Seconds())
+}
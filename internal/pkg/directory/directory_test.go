@@ -0,0 +1,264 @@
+package directory
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeEntry Directory entry held by the in-process server
+type fakeEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// fakeServer In-process stand-in for an LDAP directory
+type fakeServer struct {
+	entries []fakeEntry
+	binds   []string // DNs bound, in order
+	dials   int
+}
+
+func (s *fakeServer) dial() (Conn, error) {
+	s.dials++
+	return &fakeConn{server: s}, nil
+}
+
+type fakeConn struct {
+	server *fakeServer
+}
+
+func (c *fakeConn) Bind(username, password string) error {
+	c.server.binds = append(c.server.binds, username)
+	for _, entry := range c.server.entries {
+		if strings.EqualFold(entry.dn, username) && entry.password != "" && entry.password == password {
+			return nil
+		}
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func (c *fakeConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	filter, err := ldap.CompileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ldap.SearchResult{}
+	for _, entry := range c.server.entries {
+		if !inScope(entry.dn, req.BaseDN, req.Scope) || !matches(filter, entry) {
+			continue
+		}
+		result.Entries = append(result.Entries, ldap.NewEntry(entry.dn, entry.attributes))
+	}
+	if req.SizeLimit > 0 && len(result.Entries) > req.SizeLimit {
+		return nil, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded"))
+	}
+	return result, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func inScope(dn, base string, scope int) bool {
+	if scope == ldap.ScopeBaseObject {
+		return strings.EqualFold(dn, base)
+	}
+	return strings.HasSuffix(strings.ToLower(dn), strings.ToLower(base))
+}
+
+// matches Evaluate the filter kinds the client sends: and, or, not, equality and presence
+func matches(filter *ber.Packet, entry fakeEntry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matches(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matches(filter.Children[0], entry)
+	case ldap.FilterPresent:
+		return filter.Data.String() == "objectClass" || len(entry.attribute(filter.Data.String())) > 0
+	case ldap.FilterEqualityMatch:
+		value := filter.Children[1].Data.String()
+		return slices.ContainsFunc(entry.attribute(filter.Children[0].Data.String()), func(v string) bool {
+			return strings.EqualFold(v, value)
+		})
+	}
+	return false
+}
+
+func (e fakeEntry) attribute(name string) []string {
+	for key, values := range e.attributes {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+const (
+	serviceDN = "cn=svc,dc=example,dc=com"
+	aliceDN   = "uid=alice,ou=people,dc=example,dc=com"
+	opsDN     = "cn=ops,ou=groups,dc=example,dc=com"
+	devsDN    = "cn=devs,ou=groups,dc=example,dc=com"
+)
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{entries: []fakeEntry{
+		{dn: serviceDN, password: "svc-secret"},
+		{dn: aliceDN, password: "alice-secret", attributes: map[string][]string{
+			"uid":      {"alice"},
+			"mail":     {"alice@example.com"},
+			"memberOf": {devsDN},
+		}},
+		{dn: "uid=bob,ou=people,dc=example,dc=com", password: "bob-secret", attributes: map[string][]string{
+			"uid":  {"bob"},
+			"mail": {"shared@example.com"},
+		}},
+		{dn: "uid=carol,ou=people,dc=example,dc=com", password: "carol-secret", attributes: map[string][]string{
+			"uid":  {"carol"},
+			"mail": {"shared@example.com"},
+		}},
+		{dn: opsDN, attributes: map[string][]string{"member": {aliceDN}}},
+		{dn: devsDN, attributes: map[string][]string{"member": {"uid=bob,ou=people,dc=example,dc=com"}}},
+	}}
+}
+
+func baseConfig() config.LDAP {
+	return config.LDAP{
+		Timeout:           5,
+		UsernameAttribute: "uid",
+		EmailAttribute:    "mail",
+		GroupFilter:       "(member={dn})",
+	}
+}
+
+func TestAuthenticateUserDNTemplate(t *testing.T) {
+	server := newFakeServer()
+	cfg := baseConfig()
+	cfg.UserDN = "uid={username},ou=people,dc=example,dc=com"
+
+	entry, err := NewClientWithDialer(cfg, server.dial).Authenticate("alice", "alice-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if entry.DN != aliceDN || entry.Username != "alice" || entry.Email != "alice@example.com" {
+		t.Errorf("entry = %+v", entry)
+	}
+	// Without a group base the memberOf attribute is used
+	if !slices.Equal(entry.Groups, []string{devsDN}) {
+		t.Errorf("groups = %v, want memberOf %v", entry.Groups, []string{devsDN})
+	}
+	if !slices.Equal(server.binds, []string{aliceDN}) {
+		t.Errorf("binds = %v, want only the user", server.binds)
+	}
+}
+
+func TestAuthenticateSearch(t *testing.T) {
+	server := newFakeServer()
+	cfg := baseConfig()
+	cfg.BindDN, cfg.BindPassword = serviceDN, "svc-secret"
+	cfg.BaseDN = "ou=people,dc=example,dc=com"
+	cfg.UserFilter = "(&(objectClass=*)(uid={username}))"
+
+	entry, err := NewClientWithDialer(cfg, server.dial).Authenticate("alice", "alice-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if entry.DN != aliceDN {
+		t.Errorf("DN = %q, want %q", entry.DN, aliceDN)
+	}
+	// Search as the service account, check the password as the user, read back as the service account
+	if want := []string{serviceDN, aliceDN, serviceDN}; !slices.Equal(server.binds, want) {
+		t.Errorf("binds = %v, want %v", server.binds, want)
+	}
+}
+
+func TestAuthenticateGroupFilter(t *testing.T) {
+	server := newFakeServer()
+	cfg := baseConfig()
+	cfg.UserDN = "uid={username},ou=people,dc=example,dc=com"
+	cfg.GroupBaseDN = "ou=groups,dc=example,dc=com"
+
+	entry, err := NewClientWithDialer(cfg, server.dial).Authenticate("alice", "alice-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	// Groups listing the user as a member replace memberOf
+	if !slices.Equal(entry.Groups, []string{opsDN}) {
+		t.Errorf("groups = %v, want %v", entry.Groups, []string{opsDN})
+	}
+}
+
+func TestAuthenticateRejected(t *testing.T) {
+	searchCfg := baseConfig()
+	searchCfg.BindDN, searchCfg.BindPassword = serviceDN, "svc-secret"
+	searchCfg.BaseDN = "ou=people,dc=example,dc=com"
+
+	tests := []struct {
+		name       string
+		userFilter string
+		username   string
+		password   string
+	}{
+		{"wrong password", "(uid={username})", "alice", "wrong"},
+		{"unknown user", "(uid={username})", "nobody", "alice-secret"},
+		{"empty password", "(uid={username})", "alice", ""},
+		{"ambiguous filter", "(mail={username})", "shared@example.com", "bob-secret"},
+		{"filter injection", "(uid={username})", "*", "alice-secret"},
+		{"filter injection in and", "(uid={username})", "alice)(uid=*", "alice-secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := searchCfg
+			cfg.UserFilter = tt.userFilter
+
+			_, err := NewClientWithDialer(cfg, newFakeServer().dial).Authenticate(tt.username, tt.password)
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("err = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateEmptyPasswordNeverDials(t *testing.T) {
+	server := newFakeServer()
+	cfg := baseConfig()
+	cfg.UserDN = "uid={username},ou=people,dc=example,dc=com"
+
+	if _, err := NewClientWithDialer(cfg, server.dial).Authenticate("alice", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("err = %v, want ErrInvalidCredentials", err)
+	}
+	if server.dials != 0 {
+		t.Errorf("dialed %d times, an unauthenticated bind must never be attempted", server.dials)
+	}
+}
+
+func TestAuthenticateServiceAccountFailure(t *testing.T) {
+	cfg := baseConfig()
+	cfg.BindDN, cfg.BindPassword = serviceDN, "wrong"
+	cfg.BaseDN = "ou=people,dc=example,dc=com"
+	cfg.UserFilter = "(uid={username})"
+
+	_, err := NewClientWithDialer(cfg, newFakeServer().dial).Authenticate("alice", "alice-secret")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("err = %v, want a configuration error rather than a rejected password", err)
+	}
+}
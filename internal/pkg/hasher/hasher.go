@@ -1,8 +1,11 @@
 package hasher
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/AurChatOrg/aurchat-server/internal/code"
 	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
@@ -10,12 +13,15 @@ import (
 	"go.uber.org/zap"
 )
 
+var ErrUnsupportedHash = errors.New(strconv.Itoa(code.ErrorAccountNameOrPassword))
+
 type Hasher struct {
 	argon2 *argon2id.Params
+	pool   *Pool // Bounds concurrent hashing, nil for no limit
 }
 
 // NewHasher New argon2 hash instance
-func NewHasher(memory, iterations uint32, parallelism uint8, saltLength, keyLength uint32) *Hasher {
+func NewHasher(memory, iterations uint32, parallelism uint8, saltLength, keyLength uint32, pool *Pool) *Hasher {
 	return &Hasher{
 		argon2: &argon2id.Params{
 			Memory:      memory,
@@ -24,26 +30,83 @@ func NewHasher(memory, iterations uint32, parallelism uint8, saltLength, keyLeng
 			SaltLength:  saltLength,
 			KeyLength:   keyLength,
 		},
+		pool: pool,
 	}
 }
 
 // Hash Convert strings to hashes using Argon2
-func (a *Hasher) Hash(plaintext string) string {
-	hash, err := argon2id.CreateHash(plaintext, a.argon2) // Generate Argon2 hash
+func (a *Hasher) Hash(ctx context.Context, plaintext string) (string, error) {
+	var (
+		hash string
+		err  error
+	)
+	if poolErr := a.pool.Do(ctx, func() {
+		hash, err = argon2id.CreateHash(plaintext, a.argon2) // Generate Argon2 hash
+	}); poolErr != nil {
+		return "", poolErr
+	}
 
 	if err != nil {
-		logger.Logger.Fatal("Create hash error", zap.Error(err))
+		logger.Logger.Error("Create hash error", zap.Error(err))
+		return "", err
 	}
-	return hash
+	return hash, nil
 }
 
-// VerifyHash Verify if the string matches the provided hash
-func (a *Hasher) VerifyHash(plaintext string, hash string) (bool, error) {
-	match, err := argon2id.ComparePasswordAndHash(plaintext, hash) // Verify hash
+// VerifyHash Verify if the string matches the provided hash.
+// Argon2id hashes are checked natively, other formats go through the legacy verifiers.
+// Unknown or malformed hashes return ErrUnsupportedHash.
+func (a *Hasher) VerifyHash(ctx context.Context, plaintext string, hash string) (bool, error) {
+	var (
+		match bool
+		err   error
+	)
+	if poolErr := a.pool.Do(ctx, func() {
+		if strings.HasPrefix(hash, "$argon2id$") {
+			match, err = argon2id.ComparePasswordAndHash(plaintext, hash) // Verify hash
+		} else {
+			match, err = verifyLegacy(plaintext, hash)
+		}
+	}); poolErr != nil {
+		return false, poolErr
+	}
+
 	if err != nil {
-		logger.Logger.Fatal("Compare hash error", zap.Error(err))
-		return false, errors.New(strconv.Itoa(code.ErrorAccountNameOrPassword))
+		logger.Logger.Warn("Compare hash error", zap.Error(err))
+		return false, ErrUnsupportedHash
 	}
 
 	return match, nil
 }
+
+// NeedsRehash Report whether the hash was created with weaker parameters than the current ones
+func (a *Hasher) NeedsRehash(hash string) bool {
+	params, _, _, err := argon2id.DecodeHash(hash)
+	if err != nil {
+		return true
+	}
+	return a.weaker(params)
+}
+
+// Params Current parameters with the salt and key lengths in bytes, such as "m=65536,t=3,p=4,s=16,k=32"
+func (a *Hasher) Params() string {
+	return fmt.Sprintf("m=%d,t=%d,p=%d,s=%d,k=%d",
+		a.argon2.Memory, a.argon2.Iterations, a.argon2.Parallelism, a.argon2.SaltLength, a.argon2.KeyLength)
+}
+
+// IsCurrent Report whether a parameter string in the form of Params meets the current parameters,
+// so hashes reported current are exactly those NeedsRehash leaves alone
+func (a *Hasher) IsCurrent(params string) bool {
+	var parsed argon2id.Params
+	if _, err := fmt.Sscanf(params, "m=%d,t=%d,p=%d,s=%d,k=%d",
+		&parsed.Memory, &parsed.Iterations, &parsed.Parallelism, &parsed.SaltLength, &parsed.KeyLength); err != nil {
+		return false
+	}
+	return !a.weaker(&parsed)
+}
+
+// weaker Parallelism is ignored, it follows the CPU count of the host that created the hash
+func (a *Hasher) weaker(params *argon2id.Params) bool {
+	return params.Memory < a.argon2.Memory || params.Iterations < a.argon2.Iterations ||
+		params.SaltLength < a.argon2.SaltLength || params.KeyLength < a.argon2.KeyLength
+}
@@ -0,0 +1,168 @@
+package hasher
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Upper bounds on imported work factors, so a crafted hash cannot exhaust the gateway
+const (
+	maxPBKDF2Iterations = 10_000_000
+	maxScryptLogN       = 20
+	maxScryptRP         = 64
+)
+
+// verifyLegacy Check a password against a hash imported from another system.
+// Supported formats:
+//
+//	bcrypt          $2a$10$..., $2b$..., $2y$...
+//	scrypt          $scrypt$ln=15,r=8,p=1$<salt>$<hash>             (passlib)
+//	PBKDF2          $pbkdf2-sha256$29000$<salt>$<hash>              (passlib, also -sha512 and $pbkdf2$ for SHA-1)
+//	PBKDF2          pbkdf2_sha256$260000$<salt>$<hash>              (Django, also pbkdf2_sha1)
+func verifyLegacy(plaintext, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return verifyBcrypt(plaintext, encoded)
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		return verifyScrypt(plaintext, encoded)
+	case strings.HasPrefix(encoded, "$pbkdf2"):
+		return verifyPassLibPBKDF2(plaintext, encoded)
+	case strings.HasPrefix(encoded, "pbkdf2_"):
+		return verifyDjangoPBKDF2(plaintext, encoded)
+	}
+	return false, ErrUnsupportedHash
+}
+
+func verifyBcrypt(plaintext, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(plaintext))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, ErrUnsupportedHash
+}
+
+func verifyScrypt(plaintext, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return false, ErrUnsupportedHash
+	}
+
+	var logN, r, p int
+	for _, field := range strings.Split(parts[2], ",") {
+		name, value, _ := strings.Cut(field, "=")
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return false, ErrUnsupportedHash
+		}
+		switch name {
+		case "ln":
+			logN = n
+		case "r":
+			r = n
+		case "p":
+			p = n
+		}
+	}
+	if logN < 1 || logN > maxScryptLogN || r < 1 || r > maxScryptRP || p < 1 || p > maxScryptRP {
+		return false, ErrUnsupportedHash
+	}
+
+	salt, err := decodePassLib(parts[3])
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	expected, err := decodePassLib(parts[4])
+	if err != nil || len(expected) == 0 {
+		return false, ErrUnsupportedHash
+	}
+
+	key, err := scrypt.Key([]byte(plaintext), salt, 1<<logN, r, p, len(expected))
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+func verifyPassLibPBKDF2(plaintext, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return false, ErrUnsupportedHash
+	}
+
+	var digest func() hash.Hash
+	switch parts[1] {
+	case "pbkdf2":
+		digest = sha1.New
+	case "pbkdf2-sha256":
+		digest = sha256.New
+	case "pbkdf2-sha512":
+		digest = sha512.New
+	default:
+		return false, ErrUnsupportedHash
+	}
+
+	salt, err := decodePassLib(parts[3])
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	expected, err := decodePassLib(parts[4])
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	return verifyPBKDF2(plaintext, digest, parts[2], salt, expected)
+}
+
+func verifyDjangoPBKDF2(plaintext, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 {
+		return false, ErrUnsupportedHash
+	}
+
+	var digest func() hash.Hash
+	switch parts[0] {
+	case "pbkdf2_sha1":
+		digest = sha1.New
+	case "pbkdf2_sha256":
+		digest = sha256.New
+	default:
+		return false, ErrUnsupportedHash
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	return verifyPBKDF2(plaintext, digest, parts[1], []byte(parts[2]), expected)
+}
+
+func verifyPBKDF2(plaintext string, digest func() hash.Hash, rounds string, salt, expected []byte) (bool, error) {
+	iterations, err := strconv.Atoi(rounds)
+	if err != nil || iterations < 1 || iterations > maxPBKDF2Iterations || len(expected) == 0 {
+		return false, ErrUnsupportedHash
+	}
+
+	key, err := pbkdf2.Key(digest, plaintext, salt, iterations, len(expected))
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+// decodePassLib Decode passlib's base64 variant, which uses "." instead of "+" and drops padding
+func decodePassLib(value string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.ReplaceAll(strings.TrimRight(value, "="), ".", "+"))
+}
@@ -0,0 +1,81 @@
+package hasher
+
+import (
+	"errors"
+	"testing"
+)
+
+// Known answers come from published test vectors: the OpenWall crypt_blowfish suite for bcrypt,
+// RFC 7914 for scrypt and PBKDF2-HMAC-SHA256, RFC 6070 for PBKDF2-HMAC-SHA1. They are re-encoded
+// in the passlib and Django formats. The SHA-512 entry was computed with Python's hashlib.
+func TestVerifyLegacyKnownAnswers(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+		encoded   string
+	}{
+		{"bcrypt 2a", "U*U", "$2a$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW"},
+		{"bcrypt 2a longer", "U*U*", "$2a$05$CCCCCCCCCCCCCCCCCCCCC.VGOzA784oUp/Z0DY336zx7pLYAy0lwK"},
+		{"bcrypt 2b", "U*U", "$2b$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW"},
+		{"bcrypt 2y", "U*U*U", "$2y$05$XXXXXXXXXXXXXXXXXXXXXOAcXxm9kjPGEMsLznoKqmqw7tc8WCx4a"},
+		{"passlib scrypt", "pleaseletmein",
+			"$scrypt$ln=14,r=8,p=1$U29kaXVtQ2hsb3JpZGU$cCO9yzr9c0hGHAbNgf046/2o.7qQT44.qbVD9lRdofLVQylVYT8Pz2LUlwUkKpr55h6F3A1lHkDfzwF7RVdYhw"},
+		{"passlib pbkdf2 sha1", "password", "$pbkdf2$4096$c2FsdA$SwB5AbdlSJq.rUnZJvch0GWkKcE"},
+		{"passlib pbkdf2 sha256", "passwd",
+			"$pbkdf2-sha256$1$c2FsdA$VawEblbjCJ/sFpHCJUS2BflBhSFt3gRl5oudV8INrLxJypzM8Xm2RZkWZLOdd.8xfHG4RbHjC9UJESBB06GXgw"},
+		{"passlib pbkdf2 sha512", "hunter2",
+			"$pbkdf2-sha512$25000$c2FsdHlzYWx0MTIzNDU2Nw$gkTs3tKOTJqmFcMPNkB2uqMrsqURaIkUw608DfvFmdClHZOwsx33QldS.bSmlxTsw.mxNg1yqHkwTwiH.QYXpw"},
+		{"django pbkdf2 sha1", "password", "pbkdf2_sha1$4096$salt$SwB5AbdlSJq+rUnZJvch0GWkKcE="},
+		{"django pbkdf2 sha256", "passwd",
+			"pbkdf2_sha256$1$salt$VawEblbjCJ/sFpHCJUS2BflBhSFt3gRl5oudV8INrLxJypzM8Xm2RZkWZLOdd+8xfHG4RbHjC9UJESBB06GXgw=="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := verifyLegacy(tt.plaintext, tt.encoded)
+			if err != nil || !match {
+				t.Fatalf("verifyLegacy = %v, %v, want a match", match, err)
+			}
+			match, err = verifyLegacy(tt.plaintext+"x", tt.encoded)
+			if err != nil || match {
+				t.Errorf("wrong password: verifyLegacy = %v, %v, want no match", match, err)
+			}
+		})
+	}
+}
+
+func TestVerifyLegacyMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"unknown scheme", "$1$saltsalt$hash"},
+		{"plain text", "password"},
+		{"bcrypt truncated", "$2a$05$CCCCCCCCCCCCCCCCCCCCC."},
+		{"bcrypt bad cost", "$2a$99$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW"},
+		{"scrypt missing field", "$scrypt$ln=14,r=8,p=1$U29kaXVtQ2hsb3JpZGU"},
+		{"scrypt bad parameter", "$scrypt$ln=x,r=8,p=1$U29kaXVtQ2hsb3JpZGU$cCO9yzr9c0hGHAbN"},
+		{"scrypt missing parameter", "$scrypt$ln=14,r=8$U29kaXVtQ2hsb3JpZGU$cCO9yzr9c0hGHAbN"},
+		{"scrypt work too high", "$scrypt$ln=30,r=8,p=1$U29kaXVtQ2hsb3JpZGU$cCO9yzr9c0hGHAbN"},
+		{"scrypt bad salt", "$scrypt$ln=14,r=8,p=1$!!!$cCO9yzr9c0hGHAbN"},
+		{"scrypt empty hash", "$scrypt$ln=14,r=8,p=1$U29kaXVtQ2hsb3JpZGU$"},
+		{"passlib unknown digest", "$pbkdf2-md5$4096$c2FsdA$SwB5AbdlSJq.rUnZJvch0GWkKcE"},
+		{"passlib missing field", "$pbkdf2$4096$c2FsdA"},
+		{"passlib bad rounds", "$pbkdf2$many$c2FsdA$SwB5AbdlSJq.rUnZJvch0GWkKcE"},
+		{"passlib zero rounds", "$pbkdf2$0$c2FsdA$SwB5AbdlSJq.rUnZJvch0GWkKcE"},
+		{"passlib rounds too high", "$pbkdf2$100000000$c2FsdA$SwB5AbdlSJq.rUnZJvch0GWkKcE"},
+		{"passlib bad hash", "$pbkdf2$4096$c2FsdA$!!!"},
+		{"passlib empty hash", "$pbkdf2$4096$c2FsdA$"},
+		{"django unknown digest", "pbkdf2_md5$4096$salt$SwB5AbdlSJq+rUnZJvch0GWkKcE="},
+		{"django missing field", "pbkdf2_sha1$4096$salt"},
+		{"django bad rounds", "pbkdf2_sha1$-1$salt$SwB5AbdlSJq+rUnZJvch0GWkKcE="},
+		{"django bad hash", "pbkdf2_sha1$4096$salt$not base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if match, err := verifyLegacy("password", tt.encoded); match || !errors.Is(err, ErrUnsupportedHash) {
+				t.Errorf("verifyLegacy(%q) = %v, %v, want ErrUnsupportedHash", tt.encoded, match, err)
+			}
+		})
+	}
+}
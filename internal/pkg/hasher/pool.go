@@ -0,0 +1,98 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrBusy Hashing queue is full or the wait for a worker timed out
+var ErrBusy = errors.New(strconv.Itoa(code.ServiceUnavailable))
+
+var (
+	queueWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "aurchat",
+		Subsystem: "hasher",
+		Name:      "queue_wait_seconds",
+		Help:      "Time spent waiting for a password hashing worker.",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	})
+	inFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "aurchat",
+		Subsystem: "hasher",
+		Name:      "in_flight",
+		Help:      "Password hashing operations running or waiting for a worker.",
+	})
+	rejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aurchat",
+		Subsystem: "hasher",
+		Name:      "rejected_total",
+		Help:      "Password hashing operations refused before running, by reason.",
+	}, []string{"reason"})
+)
+
+// Pool Bounds how many argon2 operations run at once, and so their memory, with a
+// limited queue in front. Callers whose context ends while queued never hash.
+type Pool struct {
+	workers chan struct{} // Running operations
+	admit   chan struct{} // Running plus queued operations
+	maxWait time.Duration
+}
+
+// NewPool New pool running concurrency operations at once with up to queueDepth waiting
+func NewPool(concurrency, queueDepth int, maxWait time.Duration) *Pool {
+	return &Pool{
+		workers: make(chan struct{}, concurrency),
+		admit:   make(chan struct{}, concurrency+queueDepth),
+		maxWait: maxWait,
+	}
+}
+
+// Do Run fn on a worker once one is free
+func (p *Pool) Do(ctx context.Context, fn func()) error {
+	if p == nil {
+		fn()
+		return nil
+	}
+
+	select {
+	case p.admit <- struct{}{}:
+	default:
+		rejected.WithLabelValues("queue_full").Inc()
+		return ErrBusy
+	}
+	inFlight.Inc()
+	defer func() {
+		<-p.admit
+		inFlight.Dec()
+	}()
+
+	if p.maxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.maxWait)
+		defer cancel()
+	}
+
+	start := time.Now()
+	select {
+	case p.workers <- struct{}{}:
+	case <-ctx.Done():
+		queueWait.Observe(time.Since(start).Seconds())
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			rejected.WithLabelValues("wait_timeout").Inc()
+			return ErrBusy
+		}
+		rejected.WithLabelValues("canceled").Inc()
+		return ctx.Err()
+	}
+	queueWait.Observe(time.Since(start).Seconds())
+	defer func() { <-p.workers }()
+
+	fn()
+	return nil
+}
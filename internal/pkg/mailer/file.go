@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// FileMailer Drops emails into a directory for development, or only logs them when no directory is set
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+// NewFileMailer New file drop mailer
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{from: from, dir: dir}
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	if m.dir == "" {
+		logger.Logger.Info("Mail dropped",
+			zap.String("to", msg.To),
+			zap.String("subject", msg.Subject),
+			zap.String("body", msg.Body),
+		)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return err
+	}
+
+	name := time.Now().Format("20060102-150405") + "-" + messageID() + ".eml"
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, msg.encode(m.from), 0o640); err != nil {
+		return err
+	}
+
+	logger.Logger.Info("Mail dropped", zap.String("to", msg.To), zap.String("path", path))
+	return nil
+}
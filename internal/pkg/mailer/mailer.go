@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+)
+
+// Message Plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer Delivers emails
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer New mailer selected by the configured driver
+func NewMailer(cfg *config.Config) Mailer {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg.Mail.From, cfg.Mail.SMTP)
+	default:
+		return NewFileMailer(cfg.Mail.From, cfg.Mail.DropDir)
+	}
+}
+
+// encode Render the message as an RFC 5322 email
+func (m Message) encode(from string) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", sanitizeHeader(from))
+	fmt.Fprintf(&buf, "To: %s\r\n", sanitizeHeader(m.To))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", sanitizeHeader(m.Subject)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@aurchat>\r\n", messageID())
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+
+	return buf.Bytes()
+}
+
+// sanitizeHeader Strip line breaks so values cannot inject headers
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+func messageID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+)
+
+// SMTPMailer Delivers emails through an SMTP relay
+type SMTPMailer struct {
+	from string
+	cfg  config.SMTP
+}
+
+// NewSMTPMailer New SMTP mailer
+func NewSMTPMailer(from string, cfg config.SMTP) *SMTPMailer {
+	return &SMTPMailer{from: from, cfg: cfg}
+}
+
+// Send Send the message, upgrading to TLS when the server offers STARTTLS
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+	recipient, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if m.cfg.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(sender.Address); err != nil {
+		return err
+	}
+	if err = client.Rcpt(recipient.Address); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg.encode(m.from)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
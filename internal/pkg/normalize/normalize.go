@@ -0,0 +1,26 @@
+// Package normalize puts user supplied names and addresses in a canonical form,
+// so visually identical input matches the same account.
+package normalize
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Identifier NFKC form with surrounding spaces removed, the form usernames and emails are stored in.
+// Case is kept for display, lookups compare lower(column) = lower(value) in the database.
+func Identifier(s string) string {
+	return norm.NFKC.String(strings.TrimSpace(s))
+}
+
+// Fold Case folded identifier, for keys that must match regardless of case such as throttling
+func Fold(s string) string {
+	return norm.NFKC.String(cases.Fold().String(Identifier(s)))
+}
+
+// IsEmail Report whether a sign in identifier names an email rather than a username
+func IsEmail(identifier string) bool {
+	return strings.Contains(identifier, "@")
+}
@@ -0,0 +1,185 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// httpTimeout Bound on discovery and key requests to a provider
+const httpTimeout = 10 * time.Second
+
+var (
+	ErrUnknownProvider = errors.New("unknown oidc provider")
+	ErrNonceMismatch   = errors.New("oidc nonce mismatch")
+	ErrMissingIDToken  = errors.New("oidc token response has no id_token")
+)
+
+// Identity Verified claims of an external account
+type Identity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+// Provider OpenID Connect relying party for one identity provider.
+// Discovery runs on first use so an unreachable provider does not block startup.
+type Provider struct {
+	cfg         config.OIDC
+	redirectURL string
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+// Registry Configured providers by name
+type Registry struct {
+	providers map[string]*Provider
+	order     []string
+}
+
+// NewRegistry New registry of the configured providers, callbacks default to the gateway under baseURL
+func NewRegistry(providers []config.OIDC, baseURL string) *Registry {
+	registry := &Registry{providers: make(map[string]*Provider, len(providers))}
+	for _, cfg := range providers {
+		redirectURL := cfg.RedirectURL
+		if redirectURL == "" {
+			redirectURL = strings.TrimRight(baseURL, "/") + "/api/v1/auth/oidc/" + cfg.Name + "/callback"
+		}
+		registry.providers[cfg.Name] = &Provider{cfg: cfg, redirectURL: redirectURL}
+		registry.order = append(registry.order, cfg.Name)
+	}
+	return registry
+}
+
+// Get Look up a provider by name
+func (r *Registry) Get(name string) (*Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// List Providers in configuration order
+func (r *Registry) List() []*Provider {
+	providers := make([]*Provider, 0, len(r.order))
+	for _, name := range r.order {
+		providers = append(providers, r.providers[name])
+	}
+	return providers
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) DisplayName() string {
+	if p.cfg.DisplayName == "" {
+		return p.cfg.Name
+	}
+	return p.cfg.DisplayName
+}
+
+// AllowSignUp Report whether unknown identities get an account on first sign in
+func (p *Provider) AllowSignUp() bool {
+	return p.cfg.AllowSignUp
+}
+
+// AuthCodeURL Build the authorization request, binding it to state, nonce and the PKCE verifier
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oauth, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauth.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange Redeem an authorization code and verify the ID token against the provider's JWKS
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	oauth, idVerifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = gooidc.ClientContext(ctx, &http.Client{Timeout: httpTimeout})
+	tok, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc code exchange: %w", err)
+	}
+	rawIDToken, ok := tok.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := idVerifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc id token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     any    `json:"email_verified"` // Some providers send a string
+		PreferredUsername string `json:"preferred_username"`
+		Name              string `json:"name"`
+	}
+	if err = idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("oidc claims: %w", err)
+	}
+
+	return &Identity{
+		Issuer:            idToken.Issuer,
+		Subject:           idToken.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified == true || claims.EmailVerified == "true",
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+	}, nil
+}
+
+// discover Fetch the provider metadata once, retrying on the next call after a failure
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *gooidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	// The provider keeps this context for later JWKS fetches, so it must outlive the request
+	client := gooidc.ClientContext(context.WithoutCancel(ctx), &http.Client{Timeout: httpTimeout})
+	provider, err := gooidc.NewProvider(client, p.cfg.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc discovery for %s: %w", p.cfg.Name, err)
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.redirectURL,
+		Scopes:       append([]string{gooidc.ScopeOpenID}, scopes...),
+	}
+	p.verifier = provider.Verifier(&gooidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth, p.verifier, nil
+}
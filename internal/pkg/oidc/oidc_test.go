@@ -0,0 +1,366 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+)
+
+const (
+	testClientID     = "aurchat"
+	testClientSecret = "client-secret"
+	testKeyID        = "test-key"
+)
+
+// authorization Pending code issued by the mock provider
+type authorization struct {
+	challenge string         // PKCE S256 code challenge of the authorization request
+	claims    map[string]any // ID token claims, iss, aud, iat and exp are filled in when missing
+}
+
+// mockProvider Local OpenID provider serving discovery, JWKS and the token endpoint
+type mockProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	signKey   *rsa.PrivateKey // Key the ID tokens are signed with, the published key unless a test swaps it
+	omitToken bool            // Answer without an id_token
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &mockProvider{key: key, signKey: key, codes: map[string]authorization{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize Issue a code as the provider would after the user consents to the request in authURL
+func (p *mockProvider) authorize(t *testing.T, authURL string, claims map[string]any) string {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = query.Get("nonce")
+	}
+
+	code := base64.RawURLEncoding.EncodeToString([]byte(time.Now().String()))
+	p.mu.Lock()
+	p.codes[code] = authorization{challenge: query.Get("code_challenge"), claims: claims}
+	p.mu.Unlock()
+	return code
+}
+
+func (p *mockProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *mockProvider) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"alg": "RS256",
+		"use": "sig",
+		"kid": testKeyID,
+		"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+	}}})
+}
+
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+	if clientID != testClientID || clientSecret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	auth, ok := p.codes[r.FormValue("code")]
+	delete(p.codes, r.FormValue("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	resp := map[string]any{"access_token": "access", "token_type": "Bearer", "expires_in": 300}
+	if !p.omitToken {
+		resp["id_token"] = p.sign(auth.claims)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// sign Build an RS256 ID token, filling in the standard claims a test left out
+func (p *mockProvider) sign(claims map[string]any) string {
+	defaults := map[string]any{
+		"iss": p.URL,
+		"aud": testClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range defaults {
+		if _, ok := claims[name]; !ok {
+			claims[name] = value
+		}
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": testKeyID})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, p.signKey, crypto.SHA256, digest[:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func newTestProvider(t *testing.T, idp *mockProvider) *Provider {
+	t.Helper()
+	registry := NewRegistry([]config.OIDC{{
+		Name:         "mock",
+		Issuer:       idp.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+	}}, "https://chat.example.com/")
+	provider, err := registry.Get("mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := newMockProvider(t)
+	provider := newTestProvider(t, idp)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "the-state", "the-nonce", "the-verifier")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+
+	sum := sha256.Sum256([]byte("the-verifier"))
+	want := map[string]string{
+		"client_id":             testClientID,
+		"redirect_uri":          "https://chat.example.com/api/v1/auth/oidc/mock/callback",
+		"response_type":         "code",
+		"state":                 "the-state",
+		"nonce":                 "the-nonce",
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(sum[:]),
+		"code_challenge_method": "S256",
+		"scope":                 "openid profile email",
+	}
+	for name, value := range want {
+		if got := query.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if !strings.HasPrefix(authURL, idp.URL+"/authorize?") {
+		t.Errorf("authorization endpoint = %q, want the discovered one", authURL)
+	}
+	// The verifier itself never leaves the relying party
+	if strings.Contains(authURL, "the-verifier") {
+		t.Error("authorization URL leaks the PKCE verifier")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := newMockProvider(t)
+	provider := newTestProvider(t, idp)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := idp.authorize(t, authURL, map[string]any{
+		"sub":                "user-42",
+		"email":              "alice@example.com",
+		"email_verified":     "true", // Some providers send a string
+		"preferred_username": "alice",
+		"name":               "Alice",
+	})
+
+	identity, err := provider.Exchange(ctx, code, "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Identity{
+		Issuer:            idp.URL,
+		Subject:           "user-42",
+		Email:             "alice@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice",
+		Name:              "Alice",
+	}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestExchangeRejected(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		claims   map[string]any
+		nonce    string // Nonce the relying party expects
+		verifier string // PKCE verifier sent with the code
+		setup    func(*mockProvider)
+		wantErr  error
+	}{
+		{name: "nonce mismatch", claims: map[string]any{"nonce": "replayed"}, wantErr: ErrNonceMismatch},
+		{name: "wrong PKCE verifier", verifier: "another-verifier"},
+		{name: "other audience", claims: map[string]any{"aud": "another-client"}},
+		{name: "other issuer", claims: map[string]any{"iss": "https://evil.example.com"}},
+		{name: "expired", claims: map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{name: "unknown signing key", setup: func(p *mockProvider) { p.signKey = otherKey }},
+		{name: "no id_token", setup: func(p *mockProvider) { p.omitToken = true }, wantErr: ErrMissingIDToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockProvider(t)
+			if tt.setup != nil {
+				tt.setup(idp)
+			}
+			provider := newTestProvider(t, idp)
+			ctx := context.Background()
+
+			authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier")
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims := map[string]any{"sub": "user-42"}
+			for name, value := range tt.claims {
+				claims[name] = value
+			}
+			code := idp.authorize(t, authURL, claims)
+
+			verifier := tt.verifier
+			if verifier == "" {
+				verifier = "verifier"
+			}
+			identity, err := provider.Exchange(ctx, code, "nonce", verifier)
+			if err == nil {
+				t.Fatalf("Exchange accepted %+v", identity)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExchangeCodeSingleUse(t *testing.T) {
+	idp := newMockProvider(t)
+	provider := newTestProvider(t, idp)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := idp.authorize(t, authURL, map[string]any{"sub": "user-42"})
+
+	if _, err = provider.Exchange(ctx, code, "nonce", "verifier"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err = provider.Exchange(ctx, code, "nonce", "verifier"); err == nil {
+		t.Error("a redeemed code was accepted again")
+	}
+}
+
+func TestDiscoveryRetriedAfterFailure(t *testing.T) {
+	idp := newMockProvider(t)
+	issuer := idp.URL
+	idp.Close()
+
+	registry := NewRegistry([]config.OIDC{{Name: "mock", Issuer: issuer, ClientID: testClientID}}, "https://chat.example.com")
+	provider, err := registry.Get("mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier"); err == nil {
+		t.Fatal("AuthCodeURL succeeded with the provider down")
+	}
+	if provider.oauth != nil {
+		t.Error("a failed discovery was cached")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry([]config.OIDC{
+		{Name: "b", DisplayName: "Bee"},
+		{Name: "a", RedirectURL: "https://custom.example.com/callback"},
+	}, "https://chat.example.com")
+
+	if _, err := registry.Get("missing"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("err = %v, want ErrUnknownProvider", err)
+	}
+
+	providers := registry.List()
+	if len(providers) != 2 || providers[0].Name() != "b" || providers[1].Name() != "a" {
+		t.Fatalf("List not in configuration order: %v", providers)
+	}
+	if providers[0].DisplayName() != "Bee" || providers[1].DisplayName() != "a" {
+		t.Errorf("display names = %q, %q", providers[0].DisplayName(), providers[1].DisplayName())
+	}
+	if providers[1].redirectURL != "https://custom.example.com/callback" {
+		t.Errorf("redirect URL = %q, want the configured one", providers[1].redirectURL)
+	}
+}
@@ -0,0 +1,56 @@
+package otp
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	period = 30 // Seconds per time step
+	skew   = 1  // Accepted steps before and after the current one
+)
+
+// Generate Generate a new TOTP secret and its otpauth:// enrollment URI
+func Generate(issuer, account string) (secret, uri string, err error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: account,
+		Period:      period,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return key.Secret(), key.URL(), nil
+}
+
+// Validate Check a passcode against the secret, refusing time steps at or before lastStep
+// so an observed code cannot be replayed. Returns the matched step.
+func Validate(passcode, secret string, now time.Time, lastStep int64) (int64, bool) {
+	current := now.Unix() / period
+
+	for offset := int64(-skew); offset <= skew; offset++ {
+		step := current + offset
+		if step <= lastStep {
+			continue
+		}
+
+		expected, err := hotp.GenerateCodeCustom(secret, uint64(step), hotp.ValidateOpts{
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(passcode)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+)
+
+// prefixLength Hash prefix length of a range lookup, as in the Pwned Passwords range API
+const prefixLength = 5
+
+// BreachedList Local breached password list in the Pwned Passwords download format:
+// one upper-case SHA-1 hash per line, optionally followed by ":count", sorted by hash.
+// Lookups read only the range of lines sharing the 5 character hash prefix, found by
+// binary search, so lists far larger than memory can be used.
+type BreachedList struct {
+	file *os.File
+	size int64
+}
+
+// OpenBreachedList Open a sorted breached password list
+func OpenBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &BreachedList{file: file, size: info.Size()}, nil
+}
+
+// Contains Report whether the password appears in the list
+func (l *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := l.Range(hash[:prefixLength])
+	if err != nil {
+		return false, err
+	}
+	for _, suffix := range suffixes {
+		if suffix == hash[prefixLength:] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Range Return the hash suffixes of every entry with the given prefix
+func (l *BreachedList) Range(prefix string) ([]string, error) {
+	prefix = strings.ToUpper(prefix)
+
+	// Find the smallest offset whose next line sorts at or after the prefix
+	lo, hi := int64(0), l.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, _, err := l.lineFrom(mid)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" || keyOf(line) >= prefix {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	var suffixes []string
+	for offset := lo; offset < l.size; {
+		line, next, err := l.lineFrom(offset)
+		if err != nil {
+			return nil, err
+		}
+		key := keyOf(line)
+		if line == "" || !strings.HasPrefix(key, prefix) {
+			break
+		}
+		suffixes = append(suffixes, key[len(prefix):])
+		offset = next
+	}
+	return suffixes, nil
+}
+
+// lineFrom Return the first line starting at or after offset and the offset following it
+func (l *BreachedList) lineFrom(offset int64) (string, int64, error) {
+	start := offset
+	if offset > 0 {
+		// Step back one byte so a line starting exactly at offset is kept
+		start = offset - 1
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(l.file, start, l.size-start))
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return "", l.size, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	return strings.TrimRight(line, "\r\n"), start + int64(len(line)), nil
+}
+
+// keyOf Upper-case hash of a line, without the count
+func keyOf(line string) string {
+	key, _, _ := strings.Cut(line, ":")
+	return strings.ToUpper(strings.TrimSpace(key))
+}
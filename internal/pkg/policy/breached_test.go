@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// passwordHash SHA-1 of "password"
+const passwordHash = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+// entry Full hash made of the prefix and a suffix repeating one character
+func entry(prefix string, fill byte) string {
+	return prefix + strings.Repeat(string(fill), 40-len(prefix))
+}
+
+func writeBreachedList(t *testing.T, content string) *BreachedList {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := OpenBreachedList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = list.file.Close() })
+	return list
+}
+
+func TestBreachedListRange(t *testing.T) {
+	lines := []string{
+		entry("00000", '1') + ":3",
+		entry("00000", '2') + ":10",
+		entry("0A1B2", '3') + ":1",
+		passwordHash + ":9545824",
+		entry("ABCDE", '4') + ":2",
+		entry("FFFFF", '5') + ":7",
+		entry("FFFFF", '6') + ":1",
+	}
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"counts", strings.Join(lines, "\n") + "\n"},
+		{"no trailing newline", strings.Join(lines, "\n")},
+		{"crlf", strings.Join(lines, "\r\n") + "\r\n"},
+		{"without counts", func() string {
+			var bare []string
+			for _, line := range lines {
+				key, _, _ := strings.Cut(line, ":")
+				bare = append(bare, key)
+			}
+			return strings.Join(bare, "\n") + "\n"
+		}()},
+	}
+	ranges := []struct {
+		prefix string
+		want   []string
+	}{
+		{"00000", []string{entry("", '1')[5:], entry("", '2')[5:]}}, // First line
+		{"FFFFF", []string{entry("", '5')[5:], entry("", '6')[5:]}}, // Last line
+		{"abcde", []string{entry("", '4')[5:]}},                     // Lower-case prefix
+		{"5BAA6", []string{passwordHash[5:]}},
+		{"00001", nil}, // Between entries
+		{"5BAA5", nil},
+		{"FFFFE", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := writeBreachedList(t, tt.content)
+			for _, r := range ranges {
+				got, err := list.Range(r.prefix)
+				if err != nil {
+					t.Fatalf("Range(%s): %v", r.prefix, err)
+				}
+				if !slices.Equal(got, r.want) {
+					t.Errorf("Range(%s) = %v, want %v", r.prefix, got, r.want)
+				}
+			}
+
+			found, err := list.Contains("password")
+			if err != nil || !found {
+				t.Errorf("Contains(password) = %v, %v, want true", found, err)
+			}
+			if found, _ = list.Contains("correct horse battery staple"); found {
+				t.Error("Contains of an unlisted password = true")
+			}
+		})
+	}
+}
+
+func TestBreachedListSmall(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		prefix  string
+		want    []string
+	}{
+		{"empty file", "", "5BAA6", nil},
+		{"single line", passwordHash + ":1\n", "5BAA6", []string{passwordHash[5:]}},
+		{"single line without newline", passwordHash, "5BAA6", []string{passwordHash[5:]}},
+		{"single line missing", passwordHash + "\r\n", "00000", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := writeBreachedList(t, tt.content).Range(tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Range(%s) = %v, want %v", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreachedListLineFrom(t *testing.T) {
+	content := "AAAAA:1\r\nBBBBB:2\r\nCCCCC"
+	list := writeBreachedList(t, content)
+	tests := []struct {
+		offset int64
+		line   string
+		next   int64
+	}{
+		{0, "AAAAA:1", 9},
+		{1, "BBBBB:2", 18},
+		{9, "BBBBB:2", 18}, // A line starting exactly at the offset is kept
+		{10, "CCCCC", 23},
+		{18, "CCCCC", 23},
+		{19, "", 23},
+	}
+	for _, tt := range tests {
+		line, next, err := list.lineFrom(tt.offset)
+		if err != nil {
+			t.Fatalf("lineFrom(%d): %v", tt.offset, err)
+		}
+		if line != tt.line || next != tt.next {
+			t.Errorf("lineFrom(%d) = %q, %d, want %q, %d", tt.offset, line, next, tt.line, tt.next)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+)
+
+// Reasons a password is rejected, returned to clients alongside code.PasswordTooWeak
+const (
+	ReasonTooShort         = "too_short"
+	ReasonTooLong          = "too_long"
+	ReasonTooPredictable   = "too_predictable"
+	ReasonContainsUsername = "contains_username"
+	ReasonContainsEmail    = "contains_email"
+	ReasonBreached         = "breached"
+)
+
+// minIdentifierLength Identifiers shorter than this are too common to reject passwords over
+const minIdentifierLength = 3
+
+// PasswordPolicy Rules a new password has to satisfy
+type PasswordPolicy struct {
+	minLength  int
+	maxLength  int
+	minEntropy float64
+	breached   *BreachedList // nil when no list is configured
+}
+
+// NewPasswordPolicy New password policy, opening the breached password list when configured
+func NewPasswordPolicy(cfg config.PasswordPolicy) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{
+		minLength:  cfg.MinLength,
+		maxLength:  cfg.MaxLength,
+		minEntropy: cfg.MinEntropy,
+	}
+
+	if cfg.BreachedList != "" {
+		breached, err := OpenBreachedList(cfg.BreachedList)
+		if err != nil {
+			return nil, err
+		}
+		p.breached = breached
+	}
+	return p, nil
+}
+
+// Check Return the reasons the password is rejected, none when it is acceptable.
+// Username and email of the account must not appear in the password.
+func (p *PasswordPolicy) Check(password, username, email string) ([]string, error) {
+	var reasons []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.minLength {
+		reasons = append(reasons, ReasonTooShort)
+	}
+	if p.maxLength > 0 && length > p.maxLength {
+		reasons = append(reasons, ReasonTooLong)
+	}
+	if Entropy(password) < p.minEntropy {
+		reasons = append(reasons, ReasonTooPredictable)
+	}
+
+	lower := strings.ToLower(password)
+	if containsIdentifier(lower, username) {
+		reasons = append(reasons, ReasonContainsUsername)
+	}
+	localPart, _, _ := strings.Cut(email, "@")
+	if containsIdentifier(lower, email) || containsIdentifier(lower, localPart) {
+		reasons = append(reasons, ReasonContainsEmail)
+	}
+
+	if p.breached != nil && len(reasons) == 0 {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			reasons = append(reasons, ReasonBreached)
+		}
+	}
+
+	return reasons, nil
+}
+
+// Entropy Estimate the strength of a password in bits. Each character adds the
+// entropy of its character pool, while repeats and runs such as "aaa" or "123"
+// only add a fraction of it.
+func Entropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	effective := 0.0
+	var prev rune = -1
+	var prevDelta rune
+	for _, r := range password {
+		weight := 1.0
+		if prev >= 0 {
+			delta := unicode.ToLower(r) - unicode.ToLower(prev)
+			switch {
+			case delta == 0:
+				weight = 0.25 // Repeat
+			case (delta == 1 || delta == -1) && delta == prevDelta:
+				weight = 0.25 // Continuing run
+			case delta == 1 || delta == -1:
+				weight = 0.5 // Possible start of a run
+			}
+			prevDelta = delta
+		}
+		effective += weight
+		prev = r
+	}
+
+	return effective * math.Log2(float64(pool))
+}
+
+func containsIdentifier(lowerPassword, identifier string) bool {
+	identifier = strings.ToLower(strings.TrimSpace(identifier))
+	if utf8.RuneCountInString(identifier) < minIdentifierLength {
+		return false
+	}
+	return strings.Contains(lowerPassword, identifier)
+}
@@ -0,0 +1,366 @@
+package saml
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	gosaml "github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	// httpTimeout Bound on fetching identity provider metadata
+	httpTimeout = 10 * time.Second
+	// maxMetadataSize Largest metadata document read from an identity provider
+	maxMetadataSize = 1 << 20
+)
+
+var (
+	ErrUnknownProvider      = errors.New("unknown saml provider")
+	ErrUnsolicitedResponse  = errors.New("saml response was not requested and idp-initiated sign in is disabled")
+	ErrRequestMismatch      = errors.New("saml response answers another request")
+	ErrMissingSubject       = errors.New("saml assertion has no subject")
+	ErrNoIdentityProvider   = errors.New("saml metadata has no identity provider")
+	ErrMissingSigningMethod = errors.New("saml service provider key is neither rsa nor ecdsa")
+)
+
+// Identity Verified subject and mapped attributes of an assertion
+type Identity struct {
+	Issuer      string // Identity provider entity ID
+	Subject     string // NameID
+	AssertionID string
+	ExpiresAt   time.Time // Assertion is rejected after this time, so replays only need tracking until then
+	Username    string
+	Email       string
+	Groups      []string
+}
+
+// Provider SAML 2.0 service provider for one identity provider.
+// Metadata is read on first use so an unreachable provider does not block startup.
+type Provider struct {
+	cfg         config.SAML
+	entityID    string
+	metadataURL url.URL
+	acsURL      url.URL
+	key         crypto.Signer
+	certificate *x509.Certificate
+
+	mu  sync.Mutex
+	idp *gosaml.EntityDescriptor
+}
+
+// Registry Configured providers by name
+type Registry struct {
+	providers map[string]*Provider
+	order     []string
+}
+
+// NewRegistry New registry of the configured providers, endpoints default to the gateway under baseURL
+func NewRegistry(providers []config.SAML, baseURL string) (*Registry, error) {
+	registry := &Registry{providers: make(map[string]*Provider, len(providers))}
+	for _, cfg := range providers {
+		keyPair, err := tls.LoadX509KeyPair(cfg.Certificate, cfg.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("saml key pair for %s: %w", cfg.Name, err)
+		}
+		certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("saml certificate for %s: %w", cfg.Name, err)
+		}
+		key, ok := keyPair.PrivateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("saml private key for %s cannot sign", cfg.Name)
+		}
+
+		base := strings.TrimRight(baseURL, "/") + "/api/v1/auth/saml/" + cfg.Name
+		metadataURL, err := url.Parse(base + "/metadata")
+		if err != nil {
+			return nil, err
+		}
+		acsURL, err := url.Parse(base + "/acs")
+		if err != nil {
+			return nil, err
+		}
+
+		entityID := cfg.EntityID
+		if entityID == "" {
+			entityID = metadataURL.String()
+		}
+		registry.providers[cfg.Name] = &Provider{
+			cfg:         cfg,
+			entityID:    entityID,
+			metadataURL: *metadataURL,
+			acsURL:      *acsURL,
+			key:         key,
+			certificate: certificate,
+		}
+		registry.order = append(registry.order, cfg.Name)
+	}
+	return registry, nil
+}
+
+// Get Look up a provider by name
+func (r *Registry) Get(name string) (*Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// List Providers in configuration order
+func (r *Registry) List() []*Provider {
+	providers := make([]*Provider, 0, len(r.order))
+	for _, name := range r.order {
+		providers = append(providers, r.providers[name])
+	}
+	return providers
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) DisplayName() string {
+	if p.cfg.DisplayName == "" {
+		return p.cfg.Name
+	}
+	return p.cfg.DisplayName
+}
+
+// AllowSignUp Report whether unknown identities get an account on first sign in
+func (p *Provider) AllowSignUp() bool {
+	return p.cfg.AllowSignUp
+}
+
+// GroupRoles Group name to role, empty when the provider does not manage roles
+func (p *Provider) GroupRoles() map[string]string {
+	return p.cfg.GroupRoles
+}
+
+// Metadata Service provider metadata to register with the identity provider.
+// Only the HTTP-POST binding is advertised for assertions.
+func (p *Provider) Metadata() ([]byte, error) {
+	descriptor := p.serviceProvider(nil).Metadata()
+	for i := range descriptor.SPSSODescriptors {
+		descriptor.SPSSODescriptors[i].AssertionConsumerServices = []gosaml.IndexedEndpoint{{
+			Binding:  gosaml.HTTPPostBinding,
+			Location: p.acsURL.String(),
+			Index:    1,
+		}}
+	}
+	return xml.MarshalIndent(descriptor, "", "  ")
+}
+
+// AuthnRequest Build a signed HTTP-Redirect AuthnRequest, returning the URL to send the browser to
+// and the request ID the response must answer
+func (p *Provider) AuthnRequest(ctx context.Context, relayState string) (string, string, error) {
+	idp, err := p.identityProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	sp := p.serviceProvider(idp)
+	location := sp.GetSSOBindingLocation(gosaml.HTTPRedirectBinding)
+	if location == "" {
+		return "", "", fmt.Errorf("saml metadata for %s has no HTTP-Redirect sign on service", p.cfg.Name)
+	}
+	request, err := sp.MakeAuthenticationRequest(location, gosaml.HTTPRedirectBinding, gosaml.HTTPPostBinding)
+	if err != nil {
+		return "", "", err
+	}
+	redirectURL, err := request.Redirect(url.QueryEscape(relayState), sp)
+	if err != nil {
+		return "", "", err
+	}
+	return redirectURL.String(), request.ID, nil
+}
+
+// ParseResponse Verify a base64 SAMLResponse posted to the assertion consumer service: the signature
+// against the identity provider's certificates, the issuer, audience, recipient and validity window.
+// requestID is empty for a response the service provider did not request.
+func (p *Provider) ParseResponse(ctx context.Context, samlResponse, requestID string) (*Identity, error) {
+	if requestID == "" && !p.cfg.AllowIdPInitiated {
+		return nil, ErrUnsolicitedResponse
+	}
+
+	idp, err := p.identityProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return nil, fmt.Errorf("saml response encoding: %w", err)
+	}
+
+	sp := p.serviceProvider(idp)
+	assertion, err := sp.ParseXMLResponse(raw, []string{requestID}, p.acsURL)
+	if err != nil {
+		var invalid *gosaml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			return nil, fmt.Errorf("saml response: %w", invalid.PrivateErr)
+		}
+		return nil, err
+	}
+
+	// Allowing unsolicited responses turns off the library's request check, so answers to our own requests are checked here
+	if requestID != "" && sp.AllowIDPInitiated {
+		for _, confirmation := range assertion.Subject.SubjectConfirmations {
+			if data := confirmation.SubjectConfirmationData; data != nil && data.InResponseTo != requestID {
+				return nil, ErrRequestMismatch
+			}
+		}
+	}
+
+	return p.identity(assertion)
+}
+
+// identity Map the assertion subject and attributes
+func (p *Provider) identity(assertion *gosaml.Assertion) (*Identity, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, ErrMissingSubject
+	}
+
+	expiresAt := assertion.IssueInstant.Add(gosaml.MaxIssueDelay)
+	if assertion.Conditions != nil {
+		if notOnOrAfter := assertion.Conditions.NotOnOrAfter.Add(gosaml.MaxClockSkew); notOnOrAfter.After(expiresAt) {
+			expiresAt = notOnOrAfter
+		}
+	}
+
+	attributes := make(map[string][]string)
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			for _, value := range attribute.Values {
+				attributes[attribute.Name] = append(attributes[attribute.Name], value.Value)
+				if attribute.FriendlyName != "" {
+					attributes[attribute.FriendlyName] = append(attributes[attribute.FriendlyName], value.Value)
+				}
+			}
+		}
+	}
+	first := func(name string) string {
+		if values := attributes[name]; name != "" && len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	identity := &Identity{
+		Issuer:      assertion.Issuer.Value,
+		Subject:     assertion.Subject.NameID.Value,
+		AssertionID: assertion.ID,
+		ExpiresAt:   expiresAt,
+		Username:    first(p.cfg.UsernameAttribute),
+		Email:       first(p.cfg.EmailAttribute),
+	}
+	if p.cfg.EmailAttribute == "" && assertion.Subject.NameID.Format == string(gosaml.EmailAddressNameIDFormat) {
+		identity.Email = assertion.Subject.NameID.Value
+	}
+	if p.cfg.GroupsAttribute != "" {
+		identity.Groups = attributes[p.cfg.GroupsAttribute]
+	}
+	return identity, nil
+}
+
+// serviceProvider Library service provider for the identity provider, nil when only metadata is needed
+func (p *Provider) serviceProvider(idp *gosaml.EntityDescriptor) *gosaml.ServiceProvider {
+	signatureMethod := dsig.RSASHA256SignatureMethod
+	if _, ok := p.key.(*ecdsa.PrivateKey); ok {
+		signatureMethod = dsig.ECDSASHA256SignatureMethod
+	}
+
+	return &gosaml.ServiceProvider{
+		EntityID:          p.entityID,
+		Key:               p.key,
+		Certificate:       p.certificate,
+		MetadataURL:       p.metadataURL,
+		AcsURL:            p.acsURL,
+		IDPMetadata:       idp,
+		AuthnNameIDFormat: gosaml.UnspecifiedNameIDFormat,
+		AllowIDPInitiated: p.cfg.AllowIdPInitiated,
+		SignatureMethod:   signatureMethod,
+	}
+}
+
+// identityProvider Read the identity provider metadata once, retrying on the next call after a failure
+func (p *Provider) identityProvider(ctx context.Context) (*gosaml.EntityDescriptor, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.idp != nil {
+		return p.idp, nil
+	}
+
+	var data []byte
+	var err error
+	if p.cfg.Metadata != "" {
+		data, err = os.ReadFile(p.cfg.Metadata)
+	} else {
+		data, err = fetchMetadata(ctx, p.cfg.MetadataURL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("saml metadata for %s: %w", p.cfg.Name, err)
+	}
+
+	idp, err := parseMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("saml metadata for %s: %w", p.cfg.Name, err)
+	}
+	p.idp = idp
+	return p.idp, nil
+}
+
+func fetchMetadata(ctx context.Context, metadataURL string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+}
+
+// parseMetadata Read an EntityDescriptor, or the first identity provider of an EntitiesDescriptor
+func parseMetadata(data []byte) (*gosaml.EntityDescriptor, error) {
+	var entity gosaml.EntityDescriptor
+	if err := xml.Unmarshal(data, &entity); err == nil && len(entity.IDPSSODescriptors) > 0 {
+		return &entity, nil
+	}
+
+	var entities gosaml.EntitiesDescriptor
+	if err := xml.Unmarshal(data, &entities); err != nil {
+		return nil, err
+	}
+	for i := range entities.EntityDescriptors {
+		if len(entities.EntityDescriptors[i].IDPSSODescriptors) > 0 {
+			return &entities.EntityDescriptors[i], nil
+		}
+	}
+	return nil, ErrNoIdentityProvider
+}
@@ -0,0 +1,298 @@
+// Package scim compiles SCIM 2.0 filter expressions (RFC 7644 section 3.4.2.2) into SQL conditions.
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidFilter The filter is malformed, or uses an attribute or operator that is not supported
+var ErrInvalidFilter = errors.New("invalid scim filter")
+
+// Column How a filter attribute maps to SQL
+type Column struct {
+	Expr    string // Column or expression the attribute is compared as
+	Boolean bool   // Expr is a boolean compared with true and false, instead of text
+}
+
+// Compile Translate a filter into a SQL condition and its arguments. Attribute names are matched
+// case insensitively against columns, and text comparisons ignore case as SCIM attributes default to caseExact false.
+// Comparisons, pr, not, and, or and parentheses are supported, value paths such as emails[type eq "work"] are not.
+func Compile(filter string, columns map[string]Column) (string, []any, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	lowered := make(map[string]Column, len(columns))
+	for name, column := range columns {
+		lowered[strings.ToLower(name)] = column
+	}
+
+	p := &parser{tokens: tokens, columns: lowered}
+	sql, err := p.or()
+	if err != nil {
+		return "", nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return "", nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilter, p.tokens[p.pos].text)
+	}
+	return sql, p.args, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(filter string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(filter)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenOpen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenClose, text: ")"})
+			i++
+		case r == '[' || r == ']':
+			return nil, fmt.Errorf("%w: value paths are not supported", ErrInvalidFilter)
+		case r == '"':
+			// Strings are JSON strings, find the closing quote skipping escaped ones
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidFilter)
+			}
+			var value string
+			if err := json.Unmarshal([]byte(string(runes[i:end+1])), &value); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: value})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()[]"`, runes[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, text: string(runes[i:end])})
+			i = end
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty filter", ErrInvalidFilter)
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens  []filterToken
+	pos     int
+	columns map[string]Column
+	args    []any
+}
+
+func (p *parser) peekWord(word string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenWord && strings.EqualFold(p.tokens[p.pos].text, word)
+}
+
+func (p *parser) next() (filterToken, error) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, fmt.Errorf("%w: unexpected end", ErrInvalidFilter)
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+// or Binds looser than and
+func (p *parser) or() (string, error) {
+	left, err := p.and()
+	if err != nil {
+		return "", err
+	}
+	for p.peekWord("or") {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return "", err
+		}
+		left = "(" + left + " OR " + right + ")"
+	}
+	return left, nil
+}
+
+func (p *parser) and() (string, error) {
+	left, err := p.factor()
+	if err != nil {
+		return "", err
+	}
+	for p.peekWord("and") {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return "", err
+		}
+		left = "(" + left + " AND " + right + ")"
+	}
+	return left, nil
+}
+
+func (p *parser) factor() (string, error) {
+	negate := p.peekWord("not")
+	if negate {
+		p.pos++
+	}
+
+	tok, err := p.next()
+	if err != nil {
+		return "", err
+	}
+
+	var sql string
+	switch {
+	case tok.kind == tokenOpen:
+		if sql, err = p.or(); err != nil {
+			return "", err
+		}
+		if closing, err := p.next(); err != nil || closing.kind != tokenClose {
+			return "", fmt.Errorf("%w: missing closing parenthesis", ErrInvalidFilter)
+		}
+	case negate:
+		// not only applies to a parenthesized filter
+		return "", fmt.Errorf("%w: not must be followed by a parenthesis", ErrInvalidFilter)
+	case tok.kind == tokenWord:
+		if sql, err = p.comparison(tok.text); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%w: unexpected %q", ErrInvalidFilter, tok.text)
+	}
+
+	if negate {
+		return "NOT (" + sql + ")", nil
+	}
+	return sql, nil
+}
+
+// comparison attrPath op value, or attrPath pr
+func (p *parser) comparison(attribute string) (string, error) {
+	column, ok := p.columns[strings.ToLower(attribute)]
+	if !ok {
+		return "", fmt.Errorf("%w: unsupported attribute %q", ErrInvalidFilter, attribute)
+	}
+
+	opToken, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if opToken.kind != tokenWord {
+		return "", fmt.Errorf("%w: expected an operator after %q", ErrInvalidFilter, attribute)
+	}
+	op := strings.ToLower(opToken.text)
+	if op == "pr" {
+		if column.Boolean {
+			return "(" + column.Expr + ") IS NOT NULL", nil
+		}
+		return "(" + column.Expr + " IS NOT NULL AND " + column.Expr + " <> '')", nil
+	}
+
+	valueToken, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	value, isNull, err := literal(valueToken)
+	if err != nil {
+		return "", err
+	}
+
+	if isNull {
+		switch op {
+		case "eq":
+			return column.Expr + " IS NULL", nil
+		case "ne":
+			return column.Expr + " IS NOT NULL", nil
+		}
+		return "", fmt.Errorf("%w: null only compares with eq and ne", ErrInvalidFilter)
+	}
+
+	if column.Boolean {
+		flag, ok := value.(bool)
+		if !ok || (op != "eq" && op != "ne") {
+			return "", fmt.Errorf("%w: %q compares with true or false using eq and ne", ErrInvalidFilter, attribute)
+		}
+		p.args = append(p.args, flag)
+		if op == "ne" {
+			return "(" + column.Expr + ") <> ?", nil
+		}
+		return "(" + column.Expr + ") = ?", nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %q compares with a string", ErrInvalidFilter, attribute)
+	}
+	lowered := "lower(" + column.Expr + ")"
+	switch op {
+	case "eq", "ne", "gt", "ge", "lt", "le":
+		p.args = append(p.args, text)
+		return lowered + " " + map[string]string{"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<="}[op] + " lower(?)", nil
+	case "co":
+		p.args = append(p.args, "%"+escapeLike(text)+"%")
+	case "sw":
+		p.args = append(p.args, escapeLike(text)+"%")
+	case "ew":
+		p.args = append(p.args, "%"+escapeLike(text))
+	default:
+		return "", fmt.Errorf("%w: unsupported operator %q", ErrInvalidFilter, opToken.text)
+	}
+	return lowered + " LIKE lower(?)", nil
+}
+
+// literal Read a comparison value: a string, true, false, null or a number
+func literal(tok filterToken) (any, bool, error) {
+	if tok.kind == tokenString {
+		return tok.text, false, nil
+	}
+	if tok.kind != tokenWord {
+		return nil, false, fmt.Errorf("%w: expected a value", ErrInvalidFilter)
+	}
+
+	switch strings.ToLower(tok.text) {
+	case "true":
+		return true, false, nil
+	case "false":
+		return false, false, nil
+	case "null":
+		return nil, true, nil
+	}
+	var number json.Number
+	if err := json.Unmarshal([]byte(tok.text), &number); err != nil {
+		return nil, false, fmt.Errorf("%w: unexpected value %q", ErrInvalidFilter, tok.text)
+	}
+	return number.String(), false, nil
+}
+
+// escapeLike Match LIKE wildcards in the value literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,91 @@
+package scim
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testColumns = map[string]Column{
+	"userName":    {Expr: "username"},
+	"displayName": {Expr: "nickname"},
+	"active":      {Expr: "disabled_at IS NULL", Boolean: true},
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		sql    string
+		args   []any
+	}{
+		{"eq", `userName eq "Alice"`, "lower(username) = lower(?)", []any{"Alice"}},
+		{"attribute case", `USERNAME Eq "alice"`, "lower(username) = lower(?)", []any{"alice"}},
+		{"ordering", `userName ge "m"`, "lower(username) >= lower(?)", []any{"m"}},
+		{"number", `userName eq 42`, "lower(username) = lower(?)", []any{"42"}},
+		{"and binds tighter than or", `userName eq "a" or userName eq "b" and displayName eq "c"`,
+			"(lower(username) = lower(?) OR (lower(username) = lower(?) AND lower(nickname) = lower(?)))", []any{"a", "b", "c"}},
+		{"or chains left to right", `userName eq "a" or userName eq "b" or userName eq "c"`,
+			"((lower(username) = lower(?) OR lower(username) = lower(?)) OR lower(username) = lower(?))", []any{"a", "b", "c"}},
+		{"parentheses", `(userName eq "a" or userName eq "b") and displayName eq "c"`,
+			"((lower(username) = lower(?) OR lower(username) = lower(?)) AND lower(nickname) = lower(?))", []any{"a", "b", "c"}},
+		{"not", `not (userName eq "a") and active eq true`,
+			"(NOT (lower(username) = lower(?)) AND (disabled_at IS NULL) = ?)", []any{"a", true}},
+		{"boolean ne", `active ne false`, "(disabled_at IS NULL) <> ?", []any{false}},
+		{"eq null", `displayName eq null`, "nickname IS NULL", nil},
+		{"ne null", `displayName ne null`, "nickname IS NOT NULL", nil},
+		{"pr", `displayName pr`, "(nickname IS NOT NULL AND nickname <> '')", nil},
+		{"boolean pr", `active pr`, "(disabled_at IS NULL) IS NOT NULL", nil},
+		{"co escapes wildcards", `userName co "50%_off\\"`, "lower(username) LIKE lower(?)", []any{`%50\%\_off\\%`}},
+		{"sw", `userName sw "a_"`, "lower(username) LIKE lower(?)", []any{`a\_%`}},
+		{"ew", `userName ew "%"`, "lower(username) LIKE lower(?)", []any{`%\%`}},
+		{"escaped quote", `userName eq "say \"hi\""`, "lower(username) = lower(?)", []any{`say "hi"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := Compile(tt.filter, testColumns)
+			if err != nil {
+				t.Fatalf("Compile(%s): %v", tt.filter, err)
+			}
+			if sql != tt.sql {
+				t.Errorf("sql = %s\nwant  %s", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCompileRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{"empty", "  "},
+		{"unknown attribute", `password eq "x"`},
+		{"sub attribute", `name.familyName eq "x"`},
+		{"value path", `emails[type eq "work"]`},
+		{"value path after attribute", `userName eq "a" and emails[value co "x"]`},
+		{"unknown operator", `userName like "a"`},
+		{"missing value", `userName eq`},
+		{"missing operator", `userName`},
+		{"not without parenthesis", `not userName eq "a"`},
+		{"unclosed parenthesis", `(userName eq "a"`},
+		{"stray parenthesis", `userName eq "a")`},
+		{"unterminated string", `userName eq "a`},
+		{"dangling and", `userName eq "a" and`},
+		{"null ordering", `userName gt null`},
+		{"boolean ordering", `active gt true`},
+		{"boolean with string", `active eq "true"`},
+		{"text with boolean", `userName eq true`},
+		{"bare word value", `userName eq alice`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sql, _, err := Compile(tt.filter, testColumns); !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("Compile(%s) = %q, %v, want ErrInvalidFilter", tt.filter, sql, err)
+			}
+		})
+	}
+}
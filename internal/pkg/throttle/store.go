@@ -0,0 +1,129 @@
+package throttle
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store Counts consecutive failures per key
+type Store interface {
+	// Fail Record a failure at the given time and return the failure count
+	Fail(ctx context.Context, key string, at time.Time, window time.Duration) (int64, error)
+	// Get Return the failure count and the time of the last failure
+	Get(ctx context.Context, key string) (int64, time.Time, error)
+	// Reset Forget the failures of a key
+	Reset(ctx context.Context, key string) error
+}
+
+// redisStore Failure counts shared by every gateway instance
+type redisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore New Redis backed failure store
+func NewRedisStore(client *redis.Client) Store {
+	return &redisStore{client: client}
+}
+
+func (r *redisStore) Fail(ctx context.Context, key string, at time.Time, window time.Duration) (int64, error) {
+	var count *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.HIncrBy(ctx, attemptsKey(key), "count", 1)
+		pipe.HSet(ctx, attemptsKey(key), "last", at.UnixMilli())
+		pipe.Expire(ctx, attemptsKey(key), window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	values, err := r.client.HMGet(ctx, attemptsKey(key), "count", "last").Result()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	count, _ := parseInt(values[0])
+	last, _ := parseInt(values[1])
+	if count == 0 {
+		return 0, time.Time{}, nil
+	}
+	return count, time.UnixMilli(last), nil
+}
+
+func (r *redisStore) Reset(ctx context.Context, key string) error {
+	return r.client.Del(ctx, attemptsKey(key)).Err()
+}
+
+func attemptsKey(key string) string {
+	return "auth:attempts:" + key
+}
+
+func parseInt(value any) (int64, error) {
+	s, _ := value.(string)
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// memoryStore Process local failure store for tests and development
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	count     int64
+	last      time.Time
+	expiresAt time.Time
+}
+
+// NewMemoryStore New in-memory failure store
+func NewMemoryStore() Store {
+	return &memoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (m *memoryStore) Fail(_ context.Context, key string, at time.Time, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purge(at)
+	entry := m.entries[key]
+	entry.count++
+	entry.last = at
+	entry.expiresAt = at.Add(window)
+	m.entries[key] = entry
+	return entry.count, nil
+}
+
+func (m *memoryStore) Get(_ context.Context, key string) (int64, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return 0, time.Time{}, nil
+	}
+	return entry.count, entry.last, nil
+}
+
+func (m *memoryStore) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// purge Drop entries whose window has passed
+func (m *memoryStore) purge(now time.Time) {
+	for key, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
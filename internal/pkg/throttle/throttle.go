@@ -0,0 +1,72 @@
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter Exponential backoff after a number of free failures
+type Limiter struct {
+	store     Store
+	prefix    string
+	free      int64
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	window    time.Duration
+}
+
+// NewLimiter New limiter for keys of one kind, such as usernames or client IPs
+func NewLimiter(store Store, prefix string, free uint32, baseDelay, maxDelay, window time.Duration) *Limiter {
+	return &Limiter{
+		store:     store,
+		prefix:    prefix,
+		free:      int64(free),
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+		window:    window,
+	}
+}
+
+// Check Return how long the key stays locked, zero when an attempt is allowed
+func (l *Limiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	count, last, err := l.store.Get(ctx, l.prefix+key)
+	if err != nil {
+		return 0, err
+	}
+
+	wait := time.Until(last.Add(l.delay(count)))
+	if wait <= 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
+
+// Failures Return the failure count of a key within the window
+func (l *Limiter) Failures(ctx context.Context, key string) (int64, error) {
+	count, _, err := l.store.Get(ctx, l.prefix+key)
+	return count, err
+}
+
+// Fail Record a failed attempt
+func (l *Limiter) Fail(ctx context.Context, key string) error {
+	window := max(l.window, l.maxDelay) // Keep the count at least as long as the longest lockout
+	_, err := l.store.Fail(ctx, l.prefix+key, time.Now(), window)
+	return err
+}
+
+// Reset Forget the failures of a key after a successful attempt
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, l.prefix+key)
+}
+
+// delay Lockout after count failures: none within the free attempts, then doubling up to the maximum
+func (l *Limiter) delay(count int64) time.Duration {
+	over := count - l.free
+	if over <= 0 {
+		return 0
+	}
+	if over > 20 {
+		return l.maxDelay
+	}
+	return min(l.baseDelay<<(over-1), l.maxDelay)
+}
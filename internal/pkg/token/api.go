@@ -0,0 +1,28 @@
+package token
+
+import "strings"
+
+// APITokenPrefix Marks API tokens, so they are routed to the API token lookup and easy to spot in leaked logs
+const APITokenPrefix = "aur_"
+
+// apiTokenHintLength Characters of a token kept in clear for listings
+const apiTokenHintLength = len(APITokenPrefix) + 6
+
+// NewAPIToken Generate a prefixed opaque API token
+func NewAPIToken() (string, error) {
+	raw, err := NewOpaque()
+	if err != nil {
+		return "", err
+	}
+	return APITokenPrefix + raw, nil
+}
+
+// IsAPIToken Report whether a bearer token is an API token
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// APITokenHint Start of an API token, safe to show since the rest carries its entropy
+func APITokenHint(token string) string {
+	return token[:min(len(token), apiTokenHintLength)]
+}
@@ -0,0 +1,109 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// brancaToken Branca's encapsulation structure, encrypted and opaque to clients
+type brancaToken struct {
+	revocable
+	keyring *Keyring
+	purpose string // Tokens are only accepted by a generator of the same purpose
+}
+
+// NewBrancaToken New branca instance for access tokens
+func NewBrancaToken(keyring *Keyring, ttl uint32, revocations RevocationStore) Token {
+	return NewPurposeToken(keyring, ttl, revocations, "")
+}
+
+// NewPurposeToken New branca instance for short-lived tickets that must never pass as access tokens
+func NewPurposeToken(keyring *Keyring, ttl uint32, revocations RevocationStore, purpose string) Token {
+	return &brancaToken{
+		revocable: revocable{ttl: ttl, revocations: revocations},
+		keyring:   keyring,
+		purpose:   purpose,
+	}
+}
+
+// Generate Generate a new branca token
+func (b *brancaToken) Generate(username string, userId int64, sessionID int64) (string, error) {
+	if username == "" {
+		return "", errors.New("username cannot be blank")
+	}
+
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	// Encode User information
+	userInfo := &UserClaims{
+		Username:  username,
+		UserID:    userId,
+		TokenID:   tokenID,
+		SessionID: sessionID,
+		Purpose:   b.purpose,
+	}
+
+	userData, err := json.Marshal(userInfo)
+	if err != nil {
+		logger.Logger.Error("Error marshalling user info", zap.Error(err))
+		return "", err
+	}
+
+	// Generate branca token
+	token, err := b.keyring.Encode([]byte(userData))
+	if err != nil {
+		logger.Logger.Error("Error encoding token", zap.Error(err))
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Parse Parse branca token
+func (b *brancaToken) Parse(token string) (UserClaims, error) {
+	// Decode token
+	raw, err := b.keyring.Decode(token)
+	if err != nil {
+		logger.Logger.Error("Error decoding token", zap.Error(err))
+		return UserClaims{}, err
+	}
+
+	// Decode JSON
+	var userInfo UserClaims
+	if err = json.Unmarshal([]byte(raw.Payload()), &userInfo); err != nil {
+		logger.Logger.Error("Error unmarshalling token", zap.Error(err))
+		return UserClaims{}, err
+	}
+	if userInfo.Purpose != b.purpose {
+		return UserClaims{}, ErrTokenPurpose
+	}
+	userInfo.IssuedAt = raw.Timestamp()
+	userInfo.ExpiresAt = userInfo.IssuedAt.Add(time.Duration(b.ttl) * time.Second)
+
+	// Expired inspection
+	expired := raw.IsExpired(b.ttl)
+	if expired {
+		logger.Logger.Error("Token is expired", zap.String("token", token))
+		return UserClaims{}, ErrTokenExpired
+	}
+
+	// Revocation inspection
+	if err = b.checkRevoked(&userInfo); err != nil {
+		return UserClaims{}, err
+	}
+
+	// Return User information
+	return userInfo, nil
+}
+
+// PublicKeys Branca is symmetric, only the server can open its tokens
+func (b *brancaToken) PublicKeys() []PublicKey {
+	return nil
+}
@@ -0,0 +1,144 @@
+package token
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/essentialkaos/branca/v2"
+)
+
+// ErrDefaultKey The built-in development key is configured in prod
+var ErrDefaultKey = errors.New("refusing to use the built-in default signing key in prod")
+
+// Keyring Branca keys by ID. New tokens are encrypted with the active key and every key
+// that is not retired opens them, so keys rotate without signing anyone out.
+type Keyring struct {
+	keys []keyringEntry // Active key first
+}
+
+type keyringEntry struct {
+	id  string
+	key []byte
+}
+
+// NewKeyring Load the configured keys, falling back to the single Auth.Keys value
+func NewKeyring(cfg *config.Config) (*Keyring, error) {
+	entries, active := cfg.Auth.Keyring, cfg.Auth.ActiveKey
+	if len(entries) == 0 {
+		entries = []config.SigningKey{{ID: "default", Key: cfg.Auth.Keys}}
+		active = "default"
+	}
+
+	keys, err := loadKeys(entries, active, cfg.App.Env == "prod")
+	if err != nil {
+		return nil, err
+	}
+	return &Keyring{keys: keys}, nil
+}
+
+// ActiveID ID of the key new tokens are encrypted with
+func (k *Keyring) ActiveID() string {
+	return k.keys[0].id
+}
+
+// Encode Encrypt a payload with the active key
+func (k *Keyring) Encode(payload []byte) (string, error) {
+	return branca.Branca(k.keys[0].key).EncodeToString(payload)
+}
+
+// Decode Open a token with whichever accepted key encrypted it
+func (k *Keyring) Decode(token string) (branca.Token, error) {
+	data, err := branca.DecodeBase62(token)
+	if err != nil {
+		return branca.Token{}, err
+	}
+
+	// Branca tokens carry no key ID, only the right key passes authentication
+	for _, entry := range k.keys {
+		raw, decodeErr := branca.Branca(entry.key).Decode(data)
+		if decodeErr == nil {
+			return raw, nil
+		}
+		err = decodeErr
+	}
+	return branca.Token{}, err
+}
+
+// loadKeys Read every key that is not retired, active key first
+func loadKeys(entries []config.SigningKey, active string, prod bool) ([]keyringEntry, error) {
+	if active == "" && len(entries) == 1 {
+		active = entries[0].ID
+	}
+
+	var keys []keyringEntry
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.ID == "" {
+			return nil, errors.New("signing key without an id")
+		}
+		if seen[entry.ID] {
+			return nil, fmt.Errorf("duplicate signing key id %q", entry.ID)
+		}
+		seen[entry.ID] = true
+
+		if entry.Retired {
+			if entry.ID == active {
+				return nil, fmt.Errorf("active signing key %q is retired", entry.ID)
+			}
+			continue
+		}
+
+		key, err := loadKeyMaterial(entry)
+		if err != nil {
+			return nil, err
+		}
+		if prod && string(key) == config.DefaultAuthKey {
+			return nil, ErrDefaultKey
+		}
+
+		if entry.ID == active {
+			keys = append([]keyringEntry{{id: entry.ID, key: key}}, keys...)
+		} else {
+			keys = append(keys, keyringEntry{id: entry.ID, key: key})
+		}
+	}
+
+	if len(keys) == 0 || keys[0].id != active {
+		return nil, fmt.Errorf("active signing key %q is not configured", active)
+	}
+	return keys, nil
+}
+
+// loadKeyMaterial Read a key from the config, a file or the environment
+func loadKeyMaterial(entry config.SigningKey) ([]byte, error) {
+	var material string
+	switch {
+	case entry.File != "":
+		data, err := os.ReadFile(entry.File)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", entry.ID, err)
+		}
+		material = strings.TrimRight(string(data), "\r\n")
+	case entry.Env != "":
+		material = os.Getenv(entry.Env)
+		if material == "" {
+			return nil, fmt.Errorf("signing key %q: environment variable %s is empty", entry.ID, entry.Env)
+		}
+	default:
+		material = entry.Key
+	}
+
+	if len(material) == 2*branca.KEY_SIZE {
+		if key, err := hex.DecodeString(material); err == nil {
+			return key, nil
+		}
+	}
+	if len(material) != branca.KEY_SIZE {
+		return nil, fmt.Errorf("signing key %q must be %d bytes or %d hex characters", entry.ID, branca.KEY_SIZE, 2*branca.KEY_SIZE)
+	}
+	return []byte(material), nil
+}
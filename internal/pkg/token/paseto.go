@@ -0,0 +1,257 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// PASETOVersion Version and purpose of the PASETO tokens issued here
+const PASETOVersion = "v4.public"
+
+const pasetoHeader = PASETOVersion + "."
+
+var ErrTokenMalformed = errors.New("malformed token")
+
+// PublicKey Key that verifies tokens offline
+type PublicKey struct {
+	ID      string
+	Version string
+	Key     ed25519.PublicKey
+	Active  bool // Signs new tokens
+}
+
+// pasetoToken PASETO v4.public access tokens. The claims are readable by clients and
+// verifiable with the published Ed25519 keys, the signing key ID travels in the footer.
+type pasetoToken struct {
+	revocable
+	keys     []pasetoKey // Active key first
+	issuer   string
+	audience string
+}
+
+type pasetoKey struct {
+	id      string
+	private ed25519.PrivateKey
+}
+
+// pasetoClaims Registered claims plus ours. IDs are strings since snowflakes overflow JSON numbers in browsers.
+type pasetoClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  string   `json:"aud,omitempty"`
+	IssuedAt  string   `json:"iat"`
+	ExpiresAt string   `json:"exp"`
+	TokenID   string   `json:"jti"`
+	Username  string   `json:"username"`
+	SessionID string   `json:"sid,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+}
+
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// NewPASETOToken New PASETO instance for access tokens.
+// Without configured keys a temporary one is generated outside prod.
+func NewPASETOToken(cfg *config.Config, ttl uint32, revocations RevocationStore) (Token, error) {
+	pasetoCfg := cfg.Auth.PASETO
+	prod := cfg.App.Env == "prod"
+
+	var (
+		seeds []keyringEntry
+		err   error
+	)
+	if len(pasetoCfg.Keyring) == 0 {
+		if prod {
+			return nil, errors.New("PASETO tokens need a configured keyring in prod")
+		}
+		seed := make([]byte, ed25519.SeedSize)
+		if _, err = rand.Read(seed); err != nil {
+			return nil, err
+		}
+		logger.Logger.Warn("No PASETO keys configured, signing with a temporary key")
+		seeds = []keyringEntry{{id: "temporary", key: seed}}
+	} else if seeds, err = loadKeys(pasetoCfg.Keyring, pasetoCfg.ActiveKey, prod); err != nil {
+		return nil, err
+	}
+
+	keys := make([]pasetoKey, 0, len(seeds))
+	for _, seed := range seeds {
+		keys = append(keys, pasetoKey{id: seed.id, private: ed25519.NewKeyFromSeed(seed.key)})
+	}
+
+	return &pasetoToken{
+		revocable: revocable{ttl: ttl, revocations: revocations},
+		keys:      keys,
+		issuer:    pasetoCfg.Issuer,
+		audience:  pasetoCfg.Audience,
+	}, nil
+}
+
+// Generate Generate a new PASETO token signed with the active key
+func (p *pasetoToken) Generate(username string, userId int64, sessionID int64) (string, error) {
+	if username == "" {
+		return "", errors.New("username cannot be blank")
+	}
+
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	claims := pasetoClaims{
+		Subject:   strconv.FormatInt(userId, 10),
+		Issuer:    p.issuer,
+		Audience:  p.audience,
+		IssuedAt:  now.Format(time.RFC3339),
+		ExpiresAt: now.Add(time.Duration(p.ttl) * time.Second).Format(time.RFC3339),
+		TokenID:   tokenID,
+		Username:  username,
+	}
+	if sessionID != 0 {
+		claims.SessionID = strconv.FormatInt(sessionID, 10)
+	}
+
+	message, err := json.Marshal(claims)
+	if err != nil {
+		logger.Logger.Error("Error marshalling token claims", zap.Error(err))
+		return "", err
+	}
+	active := p.keys[0]
+	footer, err := json.Marshal(pasetoFooter{KeyID: active.id})
+	if err != nil {
+		return "", err
+	}
+
+	signature := ed25519.Sign(active.private, pae([]byte(pasetoHeader), message, footer, nil))
+	return pasetoHeader +
+		base64.RawURLEncoding.EncodeToString(append(message, signature...)) + "." +
+		base64.RawURLEncoding.EncodeToString(footer), nil
+}
+
+// Parse Verify the signature and claims of a PASETO token
+func (p *pasetoToken) Parse(token string) (UserClaims, error) {
+	message, err := p.verify(token)
+	if err != nil {
+		logger.Logger.Error("Error verifying token", zap.Error(err))
+		return UserClaims{}, err
+	}
+
+	var claims pasetoClaims
+	if err = json.Unmarshal(message, &claims); err != nil {
+		logger.Logger.Error("Error unmarshalling token", zap.Error(err))
+		return UserClaims{}, err
+	}
+	if claims.Issuer != p.issuer || claims.Audience != p.audience {
+		return UserClaims{}, ErrTokenMalformed
+	}
+
+	userInfo := UserClaims{
+		Username: claims.Username,
+		TokenID:  claims.TokenID,
+		Scopes:   claims.Scopes,
+	}
+	if userInfo.UserID, err = strconv.ParseInt(claims.Subject, 10, 64); err != nil {
+		return UserClaims{}, ErrTokenMalformed
+	}
+	if claims.SessionID != "" {
+		if userInfo.SessionID, err = strconv.ParseInt(claims.SessionID, 10, 64); err != nil {
+			return UserClaims{}, ErrTokenMalformed
+		}
+	}
+	if userInfo.IssuedAt, err = time.Parse(time.RFC3339, claims.IssuedAt); err != nil {
+		return UserClaims{}, ErrTokenMalformed
+	}
+	if userInfo.ExpiresAt, err = time.Parse(time.RFC3339, claims.ExpiresAt); err != nil {
+		return UserClaims{}, ErrTokenMalformed
+	}
+
+	// Expired inspection
+	if !time.Now().Before(userInfo.ExpiresAt) {
+		return UserClaims{}, ErrTokenExpired
+	}
+
+	// Revocation inspection
+	if err = p.checkRevoked(&userInfo); err != nil {
+		return UserClaims{}, err
+	}
+
+	return userInfo, nil
+}
+
+// PublicKeys Verification keys of every accepted signing key
+func (p *pasetoToken) PublicKeys() []PublicKey {
+	keys := make([]PublicKey, 0, len(p.keys))
+	for i, key := range p.keys {
+		keys = append(keys, PublicKey{
+			ID:      key.id,
+			Version: PASETOVersion,
+			Key:     key.private.Public().(ed25519.PublicKey),
+			Active:  i == 0,
+		})
+	}
+	return keys
+}
+
+// verify Check the token signature against the key named in its footer and return the message
+func (p *pasetoToken) verify(token string) ([]byte, error) {
+	if !strings.HasPrefix(token, pasetoHeader) {
+		return nil, ErrTokenMalformed
+	}
+	parts := strings.Split(token[len(pasetoHeader):], ".")
+	if len(parts) != 2 {
+		return nil, ErrTokenMalformed
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(body) < ed25519.SignatureSize {
+		return nil, ErrTokenMalformed
+	}
+	footer, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	var keyID pasetoFooter
+	if err = json.Unmarshal(footer, &keyID); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	var public ed25519.PublicKey
+	for _, key := range p.keys {
+		if key.id == keyID.KeyID {
+			public = key.private.Public().(ed25519.PublicKey)
+			break
+		}
+	}
+	if public == nil {
+		return nil, errors.New("unknown signing key")
+	}
+
+	message, signature := body[:len(body)-ed25519.SignatureSize], body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(public, pae([]byte(pasetoHeader), message, footer, nil), signature) {
+		return nil, errors.New("invalid token signature")
+	}
+	return message, nil
+}
+
+// pae Pre-authentication encoding from the PASETO specification
+func pae(pieces ...[]byte) []byte {
+	out := binary.LittleEndian.AppendUint64(nil, uint64(len(pieces)))
+	for _, piece := range pieces {
+		out = binary.LittleEndian.AppendUint64(out, uint64(len(piece)))
+		out = append(out, piece...)
+	}
+	return out
+}
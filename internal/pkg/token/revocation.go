@@ -0,0 +1,170 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RevocationStore Records revoked tokens until they would have expired anyway
+type RevocationStore interface {
+	// Revoke Revoke a single token until its expiry
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	// RevokeOnce Revoke a single token, reporting false if it was already revoked
+	RevokeOnce(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error)
+	// IsRevoked Report whether a single token has been revoked
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	// RevokeUserBefore Revoke every token issued to the user up to the given time
+	RevokeUserBefore(ctx context.Context, userID int64, before time.Time, retention time.Duration) error
+	// UserRevokedBefore Return the user's revocation cutoff, zero if none
+	UserRevokedBefore(ctx context.Context, userID int64) (time.Time, error)
+}
+
+// redisRevocationStore Revocation store shared by every gateway instance
+type redisRevocationStore struct {
+	client *redis.Client
+}
+
+// NewRedisRevocationStore New Redis backed revocation store
+func NewRedisRevocationStore(client *redis.Client) RevocationStore {
+	return &redisRevocationStore{client: client}
+}
+
+func (r *redisRevocationStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return r.client.Set(ctx, revokedTokenKey(tokenID), 1, ttl).Err()
+}
+
+func (r *redisRevocationStore) RevokeOnce(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return false, nil
+	}
+	return r.client.SetNX(ctx, revokedTokenKey(tokenID), 1, ttl).Result()
+}
+
+func (r *redisRevocationStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	n, err := r.client.Exists(ctx, revokedTokenKey(tokenID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *redisRevocationStore) RevokeUserBefore(ctx context.Context, userID int64, before time.Time, retention time.Duration) error {
+	return r.client.Set(ctx, revokedUserKey(userID), before.Unix(), retention).Err()
+}
+
+func (r *redisRevocationStore) UserRevokedBefore(ctx context.Context, userID int64) (time.Time, error) {
+	unix, err := r.client.Get(ctx, revokedUserKey(userID)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return time.Unix(unix, 0), nil
+}
+
+func revokedTokenKey(tokenID string) string {
+	return "auth:revoked:token:" + tokenID
+}
+
+func revokedUserKey(userID int64) string {
+	return "auth:revoked:user:" + strconv.FormatInt(userID, 10)
+}
+
+// memoryRevocationStore Process local revocation store for tests and development
+type memoryRevocationStore struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time   // Token ID -> expiry
+	users  map[int64]memoryCutoff // User ID -> cutoff
+}
+
+type memoryCutoff struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+// NewMemoryRevocationStore New in-memory revocation store
+func NewMemoryRevocationStore() RevocationStore {
+	return &memoryRevocationStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[int64]memoryCutoff),
+	}
+}
+
+func (m *memoryRevocationStore) Revoke(_ context.Context, tokenID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purge(time.Now())
+	m.tokens[tokenID] = expiresAt
+	return nil
+}
+
+func (m *memoryRevocationStore) RevokeOnce(_ context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if current, ok := m.tokens[tokenID]; ok && now.Before(current) {
+		return false, nil
+	}
+	if !now.Before(expiresAt) {
+		return false, nil
+	}
+	m.purge(now)
+	m.tokens[tokenID] = expiresAt
+	return true, nil
+}
+
+func (m *memoryRevocationStore) IsRevoked(_ context.Context, tokenID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt, ok := m.tokens[tokenID]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (m *memoryRevocationStore) RevokeUserBefore(_ context.Context, userID int64, before time.Time, retention time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.purge(now)
+	m.users[userID] = memoryCutoff{before: before, expiresAt: now.Add(retention)}
+	return nil
+}
+
+func (m *memoryRevocationStore) UserRevokedBefore(_ context.Context, userID int64) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff, ok := m.users[userID]
+	if !ok || time.Now().After(cutoff.expiresAt) {
+		return time.Time{}, nil
+	}
+	return cutoff.before, nil
+}
+
+// purge Drop entries whose tokens have expired on their own
+func (m *memoryRevocationStore) purge(now time.Time) {
+	for id, expiresAt := range m.tokens {
+		if now.After(expiresAt) {
+			delete(m.tokens, id)
+		}
+	}
+	for id, cutoff := range m.users {
+		if now.After(cutoff.expiresAt) {
+			delete(m.users, id)
+		}
+	}
+}
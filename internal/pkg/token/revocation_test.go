@@ -0,0 +1,121 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger.Logger = zap.NewNop()
+}
+
+func TestMemoryRevokeOnce(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRevocationStore()
+	expiresAt := time.Now().Add(time.Minute)
+
+	first, err := store.RevokeOnce(ctx, "a", expiresAt)
+	if err != nil || !first {
+		t.Fatalf("first RevokeOnce = %v, %v, want true", first, err)
+	}
+	again, err := store.RevokeOnce(ctx, "a", expiresAt)
+	if err != nil || again {
+		t.Fatalf("second RevokeOnce = %v, %v, want false", again, err)
+	}
+	if revoked, _ := store.IsRevoked(ctx, "a"); !revoked {
+		t.Error("token not revoked after RevokeOnce")
+	}
+
+	// Other tokens are unaffected
+	if other, _ := store.RevokeOnce(ctx, "b", expiresAt); !other {
+		t.Error("RevokeOnce of another token = false, want true")
+	}
+}
+
+func TestMemoryRevokeOnceAfterRevoke(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRevocationStore()
+	expiresAt := time.Now().Add(time.Minute)
+
+	if err := store.Revoke(ctx, "a", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	if first, _ := store.RevokeOnce(ctx, "a", expiresAt); first {
+		t.Error("RevokeOnce of a revoked token = true, want false")
+	}
+}
+
+func TestMemoryRevokeOnceExpired(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRevocationStore()
+
+	// A token past its expiry is never accepted, so it cannot be burnt either
+	if first, _ := store.RevokeOnce(ctx, "a", time.Now().Add(-time.Second)); first {
+		t.Error("RevokeOnce of an expired token = true, want false")
+	}
+	if revoked, _ := store.IsRevoked(ctx, "a"); revoked {
+		t.Error("expired token recorded as revoked")
+	}
+
+	// Entries lapse with the token they record
+	if first, _ := store.RevokeOnce(ctx, "b", time.Now().Add(20*time.Millisecond)); !first {
+		t.Fatal("RevokeOnce = false, want true")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if revoked, _ := store.IsRevoked(ctx, "b"); revoked {
+		t.Error("token still revoked after its expiry")
+	}
+}
+
+func TestMemoryRevokeOnceConcurrent(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRevocationStore()
+	expiresAt := time.Now().Add(time.Minute)
+
+	var wg sync.WaitGroup
+	var wins atomic.Int32
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if first, _ := store.RevokeOnce(ctx, "a", expiresAt); first {
+				wins.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if wins.Load() != 1 {
+		t.Errorf("%d callers burnt the token, want exactly 1", wins.Load())
+	}
+}
+
+func TestMemoryRevokeUserBefore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRevocationStore()
+	before := time.Now().Truncate(time.Second)
+
+	if cutoff, _ := store.UserRevokedBefore(ctx, 1); !cutoff.IsZero() {
+		t.Errorf("cutoff = %v, want none", cutoff)
+	}
+	if err := store.RevokeUserBefore(ctx, 1, before, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if cutoff, _ := store.UserRevokedBefore(ctx, 1); !cutoff.Equal(before) {
+		t.Errorf("cutoff = %v, want %v", cutoff, before)
+	}
+	if cutoff, _ := store.UserRevokedBefore(ctx, 2); !cutoff.IsZero() {
+		t.Errorf("cutoff of another user = %v, want none", cutoff)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if cutoff, _ := store.UserRevokedBefore(ctx, 1); !cutoff.IsZero() {
+		t.Errorf("cutoff = %v after retention, want none", cutoff)
+	}
+}
@@ -0,0 +1,94 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var ErrSealInvalid = errors.New(strconv.Itoa(code.TokenInvalid))
+
+// Sealer Encrypts short-lived state handed to the client, such as login ceremony data.
+// Sealed values can be opened once, within their TTL, by a sealer of the same purpose.
+type Sealer struct {
+	keyring     *Keyring
+	ttl         uint32
+	revocations RevocationStore
+	purpose     string
+}
+
+type sealedEnvelope struct {
+	Purpose string          `json:"pur"`
+	ID      string          `json:"jti"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// NewSealer New branca sealer
+func NewSealer(keyring *Keyring, ttl uint32, revocations RevocationStore, purpose string) *Sealer {
+	return &Sealer{keyring: keyring, ttl: ttl, revocations: revocations, purpose: purpose}
+}
+
+// TTL Sealed value lifetime in seconds
+func (s *Sealer) TTL() uint32 {
+	return s.ttl
+}
+
+// Seal Encrypt the JSON encoding of value
+func (s *Sealer) Seal(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(sealedEnvelope{Purpose: s.purpose, ID: id, Data: data})
+	if err != nil {
+		return "", err
+	}
+
+	return s.keyring.Encode(payload)
+}
+
+// Open Decrypt a sealed value into value and burn it so it cannot be opened again
+func (s *Sealer) Open(sealed string, value any) error {
+	raw, err := s.keyring.Decode(sealed)
+	if err != nil {
+		return ErrSealInvalid
+	}
+	if raw.IsExpired(s.ttl) {
+		return ErrTokenExpired
+	}
+
+	var envelope sealedEnvelope
+	if err = json.Unmarshal(raw.Payload(), &envelope); err != nil {
+		return ErrSealInvalid
+	}
+	if envelope.Purpose != s.purpose || envelope.ID == "" {
+		return ErrSealInvalid
+	}
+
+	expiresAt := raw.Timestamp().Add(time.Duration(s.ttl) * time.Second)
+	first, err := s.revocations.RevokeOnce(context.Background(), envelope.ID, expiresAt)
+	if err != nil {
+		logger.Logger.Error("Error burning sealed value", zap.Error(err))
+		return ErrRevocation
+	}
+	if !first {
+		return ErrSealInvalid
+	}
+
+	if err = json.Unmarshal(envelope.Data, value); err != nil {
+		return ErrSealInvalid
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+)
+
+type sealedState struct {
+	Challenge string `json:"challenge"`
+	UserID    int64  `json:"userID"`
+}
+
+// failingStore Revocation store that cannot be reached
+type failingStore struct {
+	RevocationStore
+}
+
+func (failingStore) RevokeOnce(context.Context, string, time.Time) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func newTestKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Auth.Keys = "0123456789abcdef0123456789abcdef"
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestSealerOpensOnce(t *testing.T) {
+	sealer := NewSealer(newTestKeyring(t), 60, NewMemoryRevocationStore(), "ceremony")
+
+	sealed, err := sealer.Seal(sealedState{Challenge: "abc", UserID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state sealedState
+	if err = sealer.Open(sealed, &state); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if state != (sealedState{Challenge: "abc", UserID: 7}) {
+		t.Errorf("state = %+v", state)
+	}
+
+	// Replaying the same value is refused
+	if err = sealer.Open(sealed, &state); !errors.Is(err, ErrSealInvalid) {
+		t.Errorf("second Open err = %v, want ErrSealInvalid", err)
+	}
+}
+
+func TestSealerPurpose(t *testing.T) {
+	keyring := newTestKeyring(t)
+	revocations := NewMemoryRevocationStore()
+	ceremony := NewSealer(keyring, 60, revocations, "ceremony")
+	state := NewSealer(keyring, 60, revocations, "state")
+
+	sealed, err := ceremony.Seal(sealedState{Challenge: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var value sealedState
+	if err = state.Open(sealed, &value); !errors.Is(err, ErrSealInvalid) {
+		t.Errorf("Open with another purpose err = %v, want ErrSealInvalid", err)
+	}
+	// A refused attempt does not burn the value for its real owner
+	if err = ceremony.Open(sealed, &value); err != nil {
+		t.Errorf("Open: %v", err)
+	}
+}
+
+func TestSealerRejectsTampered(t *testing.T) {
+	sealer := NewSealer(newTestKeyring(t), 60, NewMemoryRevocationStore(), "ceremony")
+
+	sealed, err := sealer.Seal(sealedState{Challenge: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Swap one base62 character in the ciphertext
+	swap := byte('A')
+	if sealed[20] == swap {
+		swap = 'B'
+	}
+	tampered := sealed[:20] + string(swap) + sealed[21:]
+
+	var value sealedState
+	if err = sealer.Open(tampered, &value); !errors.Is(err, ErrSealInvalid) {
+		t.Errorf("err = %v, want ErrSealInvalid", err)
+	}
+	if err = sealer.Open("", &value); !errors.Is(err, ErrSealInvalid) {
+		t.Errorf("empty value err = %v, want ErrSealInvalid", err)
+	}
+}
+
+func TestSealerRevocationUnavailable(t *testing.T) {
+	sealer := NewSealer(newTestKeyring(t), 60, failingStore{}, "ceremony")
+
+	sealed, err := sealer.Seal(sealedState{Challenge: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the store the value cannot be burnt, so it is not opened
+	var value sealedState
+	if err = sealer.Open(sealed, &value); !errors.Is(err, ErrRevocation) {
+		t.Errorf("err = %v, want ErrRevocation", err)
+	}
+}
+
+func TestTicketRevokeOnce(t *testing.T) {
+	tickets := NewPurposeToken(newTestKeyring(t), 60, NewMemoryRevocationStore(), "mfa")
+
+	ticket, err := tickets.Generate("alice", 7, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := tickets.Parse(ticket)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	first, err := tickets.RevokeOnce(&claims)
+	if err != nil || !first {
+		t.Fatalf("first RevokeOnce = %v, %v, want true", first, err)
+	}
+	if again, _ := tickets.RevokeOnce(&claims); again {
+		t.Error("second RevokeOnce = true, want false")
+	}
+	if _, err = tickets.Parse(ticket); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Parse after RevokeOnce err = %v, want ErrTokenRevoked", err)
+	}
+
+	// Claims without a token ID are never reported as burnt
+	if first, _ = tickets.RevokeOnce(&UserClaims{}); first {
+		t.Error("RevokeOnce without a token ID = true, want false")
+	}
+}
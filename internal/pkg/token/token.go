@@ -1,91 +1,116 @@
 package token
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"strconv"
+	"time"
 
 	"github.com/AurChatOrg/aurchat-server/internal/code"
-	"github.com/AurChatOrg/aurchat-server/internal/config"
 	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
-	"github.com/essentialkaos/branca/v2"
 	"go.uber.org/zap"
 )
 
-// Token Branca's encapsulation structure
-type Token struct {
-	branca branca.Branca
-	ttl    uint32
+var (
+	ErrTokenExpired = errors.New(strconv.Itoa(code.TokenExpired))
+	ErrTokenRevoked = errors.New(strconv.Itoa(code.TokenInvalid))
+	ErrTokenPurpose = errors.New(strconv.Itoa(code.TokenInvalid))
+	ErrRevocation   = errors.New(strconv.Itoa(code.CacheError))
+)
+
+// Token Issues and checks bearer tokens. Formats differ, revocation is shared.
+type Token interface {
+	TTL() uint32
+	Generate(username string, userId int64, sessionID int64) (string, error)
+	Parse(token string) (UserClaims, error)
+	Revoke(claims *UserClaims) error
+	RevokeOnce(claims *UserClaims) (bool, error)
+	RevokeUserBefore(userID int64, before time.Time) error
+	PublicKeys() []PublicKey // Keys other services verify tokens with, empty when only the server can
 }
 
 // UserClaims User information
 type UserClaims struct {
-	Username string `json:"username"`
-	UserID   int64  `json:"userID"`
-}
+	Username  string   `json:"username"`
+	UserID    int64    `json:"userID"`
+	TokenID   string   `json:"jti"`
+	SessionID int64    `json:"sid,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"` // Granted scopes, empty means unrestricted
+	Purpose   string   `json:"pur,omitempty"`    // Empty for access tokens
 
-// NewToken New branca instance
-func NewToken(cfg *config.Config, ttl uint32) *Token {
-	brc, err := branca.NewBranca([]byte(cfg.Auth.Keys)) // Create new branca struct
-
-	if err != nil {
-		logger.Logger.Fatal("Error creating Branca struct", zap.Error(err))
-	}
+	APITokenID uint `json:"-"` // Set when authenticated with an API token instead of a session
 
-	return &Token{branca: brc, ttl: ttl}
+	IssuedAt  time.Time `json:"-"` // Filled from the token timestamp
+	ExpiresAt time.Time `json:"-"`
 }
 
-// Generate Generate a new branca token
-func (b *Token) Generate(username string, userId int64) (string, error) {
-	if username == "" {
-		return "", errors.New("username cannot be blank")
-	}
+// revocable Lifetime and revocation checks shared by every token format
+type revocable struct {
+	ttl         uint32
+	revocations RevocationStore
+}
 
-	// Encode User information
-	userInfo := &UserClaims{
-		Username: username,
-		UserID:   userId,
-	}
+// TTL Token lifetime in seconds
+func (b *revocable) TTL() uint32 {
+	return b.ttl
+}
 
-	userData, err := json.Marshal(userInfo)
-	if err != nil {
-		logger.Logger.Error("Error marshalling user info", zap.Error(err))
-		return "", err
+// Revoke Revoke a single token until it expires
+func (b *revocable) Revoke(claims *UserClaims) error {
+	if claims.TokenID == "" {
+		return nil
 	}
+	return b.revocations.Revoke(context.Background(), claims.TokenID, claims.ExpiresAt)
+}
 
-	// Generate branca token
-	token, err := b.branca.EncodeToString([]byte(userData))
-	if err != nil {
-		logger.Logger.Error("Error encoding token", zap.Error(err))
-		return "", err
+// RevokeOnce Revoke a single token, reporting false if it was already revoked
+func (b *revocable) RevokeOnce(claims *UserClaims) (bool, error) {
+	if claims.TokenID == "" {
+		return false, nil
 	}
+	return b.revocations.RevokeOnce(context.Background(), claims.TokenID, claims.ExpiresAt)
+}
 
-	return token, nil
+// RevokeUserBefore Revoke every token issued to the user up to the given time
+func (b *revocable) RevokeUserBefore(userID int64, before time.Time) error {
+	// Older tokens expire on their own once a full TTL has passed
+	retention := time.Until(before.Add(time.Duration(b.ttl) * time.Second))
+	return b.revocations.RevokeUserBefore(context.Background(), userID, before, retention)
 }
 
-// Parse Parse branca token
-func (b *Token) Parse(token string) (UserClaims, error) {
-	// Decode token
-	raw, err := b.branca.DecodeString(token)
-	if err != nil {
-		logger.Logger.Error("Error decoding token", zap.Error(err))
-		return UserClaims{}, err
+func (b *revocable) checkRevoked(claims *UserClaims) error {
+	ctx := context.Background()
+
+	if claims.TokenID != "" {
+		revoked, err := b.revocations.IsRevoked(ctx, claims.TokenID)
+		if err != nil {
+			logger.Logger.Error("Error checking token revocation", zap.Error(err))
+			return ErrRevocation
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
 	}
 
-	// Decode JSON
-	var userInfo UserClaims
-	if err = json.Unmarshal([]byte(raw.Payload()), &userInfo); err != nil {
-		logger.Logger.Error("Error unmarshalling token", zap.Error(err))
-		return UserClaims{}, err
+	cutoff, err := b.revocations.UserRevokedBefore(ctx, claims.UserID)
+	if err != nil {
+		logger.Logger.Error("Error checking user revocation", zap.Error(err))
+		return ErrRevocation
 	}
-
-	// Expired inspection
-	expired := raw.IsExpired(b.ttl)
-	if expired {
-		logger.Logger.Error("Token is expired", zap.String("token", token))
-		return UserClaims{}, errors.New(strconv.Itoa(code.TokenExpired))
+	// Branca timestamps have second precision, so a token from the cutoff second is revoked too
+	if !cutoff.IsZero() && claims.IssuedAt.Unix() <= cutoff.Unix() {
+		return ErrTokenRevoked
 	}
 
-	// Return User information
-	return userInfo, nil
+	return nil
+}
+
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/normalize"
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables Characters that render like a Latin letter or digit, after case folding.
+// A subset of the Unicode confusables data covering the scripts seen in impersonation attempts.
+var confusables = map[rune]string{
+	// Digits and Latin
+	'0': "o", '1': "l", 'ı': "i", 'ɡ': "g", 'ℓ': "l", 'm': "rn",
+
+	// Cyrillic
+	'а': "a", 'в': "b", 'е': "e", 'һ': "h", 'н': "h", 'і': "i", 'ј': "j", 'к': "k",
+	'ӏ': "l", 'м': "rn", 'о': "o", 'р': "p", 'ԛ': "q", 'ѕ': "s", 'т': "t", 'у': "y",
+	'ԝ': "w", 'х': "x", 'ԁ': "d", 'ү': "y", 'ѡ': "w",
+
+	// Greek
+	'α': "a", 'β': "b", 'ε': "e", 'η': "n", 'ι': "i", 'κ': "k", 'μ': "rn", 'ν': "v",
+	'ο': "o", 'ρ': "p", 'τ': "t", 'υ': "u", 'χ': "x", 'γ': "y", 'ζ': "z", 'ω': "w",
+
+	// Armenian
+	'օ': "o", 'ս': "u", 'հ': "h", 'ո': "n", 'զ': "q", 'ց': "g",
+}
+
+// Skeleton Reduce a name to the form that decides whether two names look alike, in the spirit
+// of the Unicode skeleton algorithm. Case and combining marks are dropped as well, so "Аdmín"
+// and "admin" share a skeleton.
+func Skeleton(name string) string {
+	folded := norm.NFD.String(normalize.Fold(name))
+
+	var b strings.Builder
+	for _, r := range folded {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if replacement, ok := confusables[r]; ok {
+			b.WriteString(replacement)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFD.String(b.String())
+}
@@ -0,0 +1,53 @@
+package validation
+
+import (
+	"errors"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/normalize"
+	"golang.org/x/net/idna"
+)
+
+const (
+	EmailMaxLength      = 64 // Size of the users.email column
+	emailLocalMaxLength = 64
+)
+
+var ErrEmailFormat = errors.New(strconv.Itoa(code.InvalidEmailFormat))
+
+// Email Normalize an address and check it is a bare RFC 5322 addr-spec with a resolvable
+// domain name. Display names, quoted local parts and IP literals are refused.
+func Email(addr string) (string, error) {
+	addr = normalize.Identifier(addr)
+	if len(addr) > EmailMaxLength {
+		return "", ErrEmailFormat
+	}
+
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil || parsed.Name != "" || parsed.Address != addr {
+		return "", ErrEmailFormat
+	}
+
+	at := strings.LastIndexByte(addr, '@')
+	local, domain := addr[:at], addr[at+1:]
+	if len(local) > emailLocalMaxLength {
+		return "", ErrEmailFormat
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", ErrEmailFormat
+	}
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", ErrEmailFormat
+	}
+	if tld := labels[len(labels)-1]; strings.Trim(tld, "0123456789") == "" {
+		return "", ErrEmailFormat
+	}
+
+	return local + "@" + strings.ToLower(domain), nil
+}
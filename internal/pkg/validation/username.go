@@ -0,0 +1,93 @@
+// Package validation checks usernames and email addresses before they are stored.
+// Services call it directly, request DTOs through the tags added by Register.
+package validation
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/normalize"
+)
+
+const (
+	UsernameMinLength = 2
+	UsernameMaxLength = 20
+)
+
+var (
+	ErrUsernameFormat   = errors.New(strconv.Itoa(code.InvalidUsernameFormat))
+	ErrUsernameReserved = errors.New(strconv.Itoa(code.UsernameReserved))
+)
+
+// reservedNames Names that could pass for staff or system accounts, compared by skeleton
+var reservedNames = []string{
+	"abuse", "admin", "administrator", "anonymous", "api", "aurchat", "auth", "bot",
+	"everyone", "help", "here", "hostmaster", "login", "me", "mod", "moderator",
+	"noreply", "null", "official", "postmaster", "root", "security", "settings",
+	"signin", "signup", "staff", "support", "sysadmin", "system", "undefined",
+	"webmaster", "www",
+}
+
+var reservedSkeletons = func() map[string]bool {
+	skeletons := make(map[string]bool, len(reservedNames))
+	for _, name := range reservedNames {
+		skeletons[reservedKey(name)] = true
+	}
+	return skeletons
+}()
+
+// Username Normalize a username and check it. Letters and digits of any script are allowed,
+// joined by single '_', '.' or '-' separators.
+func Username(name string) (string, error) {
+	name = normalize.Identifier(name)
+
+	length := utf8.RuneCountInString(name)
+	if length < UsernameMinLength || length > UsernameMaxLength {
+		return "", ErrUsernameFormat
+	}
+
+	previousSeparator := true // Also rejects a leading separator
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			previousSeparator = false
+		case isSeparator(r):
+			if previousSeparator {
+				return "", ErrUsernameFormat
+			}
+			previousSeparator = true
+		default:
+			return "", ErrUsernameFormat
+		}
+	}
+	if previousSeparator {
+		return "", ErrUsernameFormat
+	}
+
+	if IsReserved(name) {
+		return "", ErrUsernameReserved
+	}
+	return name, nil
+}
+
+// IsReserved Report whether a username looks like a reserved name, separators and homoglyphs included
+func IsReserved(name string) bool {
+	return reservedSkeletons[reservedKey(name)]
+}
+
+func reservedKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if isSeparator(r) {
+			return -1
+		}
+		return r
+	}, Skeleton(name))
+}
+
+func isSeparator(r rune) bool {
+	return r == '_' || r == '.' || r == '-'
+}
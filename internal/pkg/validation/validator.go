@@ -0,0 +1,26 @@
+package validation
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// Tags added by Register
+const (
+	TagUsername = "username"
+	TagEmail    = "user_email"
+)
+
+// Register Add the username and email tags to a validator
+func Register(v *validator.Validate) error {
+	if err := v.RegisterValidation(TagUsername, func(fl validator.FieldLevel) bool {
+		_, err := Username(fl.Field().String())
+		return err == nil
+	}); err != nil {
+		return err
+	}
+
+	return v.RegisterValidation(TagEmail, func(fl validator.FieldLevel) bool {
+		_, err := Email(fl.Field().String())
+		return err == nil
+	})
+}
@@ -5,6 +5,9 @@ import (
 
 	"github.com/AurChatOrg/aurchat-server/internal/config"
 	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/validation"
+	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -23,10 +26,24 @@ func InitPostgres(cfg *config.Config) error {
 			initErr = err
 			return
 		}
-		if err = db.AutoMigrate(&model.User{}); err != nil {
+		if err = db.AutoMigrate(&model.User{}, &model.RefreshToken{}, &model.Session{}, &model.OneTimeToken{}, &model.RecoveryCode{}, &model.WebAuthnCredential{}, &model.LinkedIdentity{}, &model.Invite{}, &model.APIToken{}, &model.Group{}, &model.GroupMember{}); err != nil {
 			initErr = err
 			return
 		}
+		if err = backfillUsernameSkeletons(db); err != nil {
+			initErr = err
+			return
+		}
+		if err = createCaseIndexes(db, model.UserCaseIndexes); err != nil {
+			initErr = err
+			return
+		}
+		for _, index := range append(model.UserIndexes, model.GroupIndexes...) {
+			if err = db.Exec(index).Error; err != nil {
+				initErr = err
+				return
+			}
+		}
 
 		postgresDB, _ := db.DB()
 		postgresDB.SetMaxOpenConns(100)
@@ -35,3 +52,67 @@ func InitPostgres(cfg *config.Config) error {
 	})
 	return initErr
 }
+
+// createCaseIndexes Create the case-insensitive unique indexes. Accounts created before them may differ only by case,
+// in which case the index is skipped and the duplicates are logged for review instead of refusing to start.
+func createCaseIndexes(db *gorm.DB, indexes []model.CaseIndex) error {
+	for _, index := range indexes {
+		var duplicates []struct {
+			Value   string
+			UserIDs string
+		}
+		if err := db.Model(&model.User{}).
+			Select("lower(" + index.Column + ") AS value, string_agg(user_id::text, ',' ORDER BY user_id) AS user_ids").
+			Where("deleted_at IS NULL").
+			Group("lower(" + index.Column + ")").
+			Having("COUNT(*) > 1").
+			Scan(&duplicates).Error; err != nil {
+			return err
+		}
+
+		if len(duplicates) > 0 {
+			for _, duplicate := range duplicates {
+				logger.Logger.Warn("Accounts differ only by case", zap.String("column", index.Column),
+					zap.String("value", duplicate.Value), zap.String("userIDs", duplicate.UserIDs))
+			}
+			logger.Logger.Error("Skipping case-insensitive unique index until the duplicate accounts are resolved",
+				zap.String("column", index.Column), zap.Int("duplicates", len(duplicates)))
+			continue
+		}
+
+		if err := db.Exec(index.SQL).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillUsernameSkeletons Fill the skeleton of accounts created before it was stored.
+// An account whose name looks like an earlier one keeps an empty skeleton and is logged for review.
+func backfillUsernameSkeletons(db *gorm.DB) error {
+	var existing []string
+	if err := db.Model(&model.User{}).Where("username_skeleton <> ''").Pluck("username_skeleton", &existing).Error; err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, skeleton := range existing {
+		taken[skeleton] = true
+	}
+
+	var users []model.User
+	return db.Where("username_skeleton = ''").FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+		for _, user := range users {
+			skeleton := validation.Skeleton(user.Username)
+			if taken[skeleton] {
+				logger.Logger.Warn("Username looks like another account", zap.Int64("userID", user.UserID), zap.String("username", user.Username))
+				continue
+			}
+			taken[skeleton] = true
+
+			if err := tx.Model(&model.User{}).Where("id = ?", user.ID).Update("username_skeleton", skeleton).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	Redis         *redis.Client
+	redisInitOnce sync.Once
+	redisInitErr  error
+)
+
+// InitRedis Init Redis
+func InitRedis(cfg *config.Config) error {
+	redisInitOnce.Do(func() {
+		client := redis.NewClient(&redis.Options{
+			Addr: cfg.Redis.Addr,
+			DB:   cfg.Redis.DB,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			_ = client.Close()
+			redisInitErr = err
+			return
+		}
+
+		Redis = client
+	})
+	return redisInitErr
+}
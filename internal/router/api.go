@@ -2,20 +2,70 @@
 package router
 
 import (
+	"runtime"
+	"time"
+
 	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/validation"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/admin"
 	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/scim"
+	"github.com/bwmarrin/snowflake"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
 )
 
 // RegisterAPI mounts all /api/v1 endpoints.
 func RegisterAPI(route *gin.Engine, cfg *config.Config) {
+	registerValidations()
+	node, err := snowflake.NewNode(cfg.Snowflake.WorkerID) // One node, as user IDs are generated by several modules
+	if err != nil {
+		logger.Logger.Error("Create a new Snowflake Node error", zap.Error(err))
+	}
+	hasher := newHasher(cfg) // One pool, so every module's hashing shares the concurrency and queue bound
+
 	api := route.Group("/api/v1")
 	{
 		// Health
 		api.GET("/ping", ping)
 
 		// Auth API
-		auth.RegisterAuthAPI(api)
+		validator := auth.RegisterAuthAPI(api, node, hasher)
+
+		// Admin API
+		admin.RegisterAdminAPI(api, validator, hasher)
+
+		// SCIM API
+		scim.RegisterSCIMAPI(api, node, hasher)
+	}
+}
+
+// newHasher Argon2 hasher with the configured parameters, limiting concurrent work
+// so memory stays bounded under sign in storms
+func newHasher(cfg *config.Config) *hasher.Hasher {
+	concurrency := cfg.Hash.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	pool := hasher.NewPool(concurrency, cfg.Hash.QueueDepth, time.Duration(cfg.Hash.MaxWait)*time.Second)
+	return hasher.NewHasher(cfg.Hash.Memory, cfg.Hash.Inerations, uint8(runtime.NumCPU()), cfg.Hash.SaltLength, 32, pool)
+}
+
+// registerValidations Add the username and email tags to both the DTO and the request binding validators
+func registerValidations() {
+	engines := []*validator.Validate{dto.Validate}
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engines = append(engines, engine)
+	}
+	for _, engine := range engines {
+		if err := validation.Register(engine); err != nil {
+			logger.Logger.Fatal("Error registering validations", zap.Error(err))
+		}
 	}
 }
 
@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/repo"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/admin/handler"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/admin/repository"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/admin/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAdminAPI Register Admin API, restricted to administrators
+func RegisterAdminAPI(route *gin.RouterGroup, validator middleware.TokenValidator, hasher *hasher.Hasher) {
+	userRepo := repository.NewUserRepository(repo.Postgres)
+	inviteRepo := repository.NewInviteRepository(repo.Postgres)
+
+	hashService := service.NewHashService(userRepo, hasher)
+	hashHandler := handler.NewHashHandler(hashService)
+	inviteService := service.NewInviteService(inviteRepo)
+	inviteHandler := handler.NewInviteHandler(inviteService)
+
+	admin := route.Group("/admin", middleware.RequireSessionAuth(validator), middleware.RequireRole(model.RoleAdmin))
+	{
+		admin.GET("/password-hashes", hashHandler.Report)
+
+		admin.GET("/invites", inviteHandler.List)
+		admin.POST("/invites", inviteHandler.Create)
+		admin.DELETE("/invites/:id", inviteHandler.Revoke)
+	}
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/admin/service"
+	"github.com/gin-gonic/gin"
+)
+
+type HashHandler struct {
+	hashService service.HashService
+}
+
+func NewHashHandler(hashService service.HashService) *HashHandler {
+	return &HashHandler{hashService: hashService}
+}
+
+// Report godoc
+// @Summary      Password hash report
+// @Description  Count accounts per password hash parameter set, to follow the upgrade to the configured argon2 parameters
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  dto.HashReportResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /admin/password-hashes [get]
+func (h *HashHandler) Report(c *gin.Context) {
+	report, err := h.hashService.Report()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	resp := dto.HashReportResp{
+		CurrentParams: report.CurrentParams,
+		Outdated:      report.Outdated,
+		Groups:        make([]dto.HashGroupResp, 0, len(report.Groups)),
+	}
+	for _, group := range report.Groups {
+		resp.Groups = append(resp.Groups, dto.HashGroupResp{
+			Algorithm: group.Algorithm,
+			Params:    group.Params,
+			Count:     group.Count,
+			Current:   group.Current,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
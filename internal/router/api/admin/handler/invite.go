@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/admin/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type InviteHandler struct {
+	inviteService service.InviteService
+}
+
+func NewInviteHandler(inviteService service.InviteService) *InviteHandler {
+	return &InviteHandler{inviteService: inviteService}
+}
+
+// Create godoc
+// @Summary      Create invite
+// @Description  Create an invite code for invite-only registration. The code is only returned in this response
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body body dto.CreateInviteReq true "Invite limits"
+// @Success      201  {object}  dto.InviteResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /admin/invites [post]
+func (h *InviteHandler) Create(c *gin.Context) {
+	var req dto.CreateInviteReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	user := middleware.MustCurrentUser(c)
+	invite, inviteCode, err := h.inviteService.Create(user.UserID, req.MaxUses, req.ExpiresIn, req.Note)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	resp := inviteResp(invite)
+	resp.Code = inviteCode
+	c.JSON(http.StatusCreated, resp)
+}
+
+// List godoc
+// @Summary      List invites
+// @Description  List every invite with its usage, without the codes
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   dto.InviteResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /admin/invites [get]
+func (h *InviteHandler) List(c *gin.Context) {
+	invites, err := h.inviteService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	resp := make([]dto.InviteResp, 0, len(invites))
+	for i := range invites {
+		resp = append(resp, inviteResp(&invites[i]))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Revoke godoc
+// @Summary      Revoke invite
+// @Description  Stop an invite from registering further accounts, accounts it already registered are kept
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path int true "Invite ID"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /admin/invites/{id} [delete]
+func (h *InviteHandler) Revoke(c *gin.Context) {
+	inviteID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	if err = h.inviteService.Revoke(uint(inviteID)); err != nil {
+		if err == service.ErrInviteNotFound {
+			c.JSON(http.StatusNotFound, dto.ErrorResp{
+				Code: code.InviteNotFound,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+				Code: code.ServerUnknownError,
+			})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func inviteResp(invite *model.Invite) dto.InviteResp {
+	return dto.InviteResp{
+		ID:        invite.ID,
+		Note:      invite.Note,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		CreatedBy: invite.CreatedBy,
+		ExpiresAt: invite.ExpiresAt,
+		RevokedAt: invite.RevokedAt,
+		CreatedAt: invite.CreatedAt,
+	}
+}
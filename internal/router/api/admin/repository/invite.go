@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+)
+
+type InviteRepository interface {
+	Create(invite *model.Invite) error
+	List() ([]model.Invite, error)
+	Revoke(id uint) error
+}
+
+type inviteRepository struct {
+	db *gorm.DB
+}
+
+func NewInviteRepository(db *gorm.DB) InviteRepository {
+	return &inviteRepository{db: db}
+}
+
+// Create Store a new invite
+func (r *inviteRepository) Create(invite *model.Invite) error {
+	return r.db.Create(invite).Error
+}
+
+// List Every invite, newest first
+func (r *inviteRepository) List() ([]model.Invite, error) {
+	var invites []model.Invite
+	if err := r.db.Order("id DESC").Find(&invites).Error; err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// Revoke Stop an invite from registering further accounts
+func (r *inviteRepository) Revoke(id uint) error {
+	result := r.db.Model(&model.Invite{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.InviteNotFound))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// HashParamCount Number of accounts whose password hash uses one algorithm and parameter set
+type HashParamCount struct {
+	Algorithm string
+	Params    string
+	Count     int64
+}
+
+type UserRepository interface {
+	CountByHashParams() ([]HashParamCount, error)
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+// CountByHashParams Group accounts by the algorithm and parameter fields of their hash,
+// such as "argon2id" and "m=65536,t=3,p=4,s=16,k=32", or "2b" and "12" for imported bcrypt hashes.
+// Argon2 salt and key lengths in bytes are read from their unpadded base64 fields.
+// Passwordless accounts have no hash to count.
+func (r *userRepository) CountByHashParams() ([]HashParamCount, error) {
+	var counts []HashParamCount
+	err := r.db.Table("users").
+		Select(`CASE WHEN password LIKE '$%' THEN split_part(password, '$', 2) ELSE split_part(password, '$', 1) END AS algorithm,
+			CASE
+				WHEN password LIKE '$argon2%' THEN split_part(password, '$', 4) ||
+					',s=' || length(split_part(password, '$', 5)) * 3 / 4 ||
+					',k=' || length(split_part(password, '$', 6)) * 3 / 4
+				WHEN password LIKE '$%' THEN split_part(password, '$', 3)
+				ELSE split_part(password, '$', 2)
+			END AS params,
+			count(*) AS count`).
+		Where("deleted_at IS NULL AND password <> ''").
+		Group("1, 2").
+		Order("count DESC").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/admin/repository"
+)
+
+const currentAlgorithm = "argon2id"
+
+// HashGroup Accounts sharing a hash parameter set
+type HashGroup struct {
+	Algorithm string
+	Params    string
+	Count     int64
+	Current   bool // Meets the configured parameters, no upgrade pending
+}
+
+// HashReport Progress of the password hash migration to the configured parameters
+type HashReport struct {
+	CurrentParams string
+	Groups        []HashGroup
+	Outdated      int64 // Accounts upgraded on their next sign in
+}
+
+type HashService interface {
+	Report() (*HashReport, error)
+}
+
+type hashService struct {
+	userRepo repository.UserRepository
+	hasher   *hasher.Hasher
+}
+
+func NewHashService(userRepo repository.UserRepository, hasher *hasher.Hasher) HashService {
+	return &hashService{userRepo: userRepo, hasher: hasher}
+}
+
+func (s *hashService) Report() (*HashReport, error) {
+	counts, err := s.userRepo.CountByHashParams()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &HashReport{CurrentParams: s.hasher.Params()}
+	for _, count := range counts {
+		current := count.Algorithm == currentAlgorithm && s.hasher.IsCurrent(count.Params)
+		if !current {
+			report.Outdated += count.Count
+		}
+		report.Groups = append(report.Groups, HashGroup{
+			Algorithm: count.Algorithm,
+			Params:    count.Params,
+			Count:     count.Count,
+			Current:   current,
+		})
+	}
+	return report, nil
+}
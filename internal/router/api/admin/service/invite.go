@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/admin/repository"
+)
+
+var ErrInviteNotFound = errors.New(strconv.Itoa(code.InviteNotFound))
+
+type InviteService interface {
+	Create(creatorID int64, maxUses int, expiresIn uint32, note string) (*model.Invite, string, error)
+	List() ([]model.Invite, error)
+	Revoke(id uint) error
+}
+
+type inviteService struct {
+	inviteRepo repository.InviteRepository
+}
+
+func NewInviteService(inviteRepo repository.InviteRepository) InviteService {
+	return &inviteService{inviteRepo: inviteRepo}
+}
+
+// Create Generate an invite and return its code, which is only stored hashed
+func (s *inviteService) Create(creatorID int64, maxUses int, expiresIn uint32, note string) (*model.Invite, string, error) {
+	inviteCode, err := token.NewOpaque()
+	if err != nil {
+		return nil, "", err
+	}
+
+	invite := &model.Invite{
+		CodeHash:  token.HashOpaque(inviteCode),
+		CreatedBy: creatorID,
+		Note:      note,
+		MaxUses:   maxUses,
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(expiresIn) * time.Second)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err = s.inviteRepo.Create(invite); err != nil {
+		return nil, "", err
+	}
+	return invite, inviteCode, nil
+}
+
+func (s *inviteService) List() ([]model.Invite, error) {
+	return s.inviteRepo.List()
+}
+
+func (s *inviteService) Revoke(id uint) error {
+	if err := s.inviteRepo.Revoke(id); err != nil {
+		if err.Error() == strconv.Itoa(code.InviteNotFound) {
+			return ErrInviteNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -1,37 +1,321 @@
 package auth
 
 import (
-	"runtime"
+	"time"
 
 	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/challenge"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/directory"
 	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
 	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/mailer"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/oidc"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/policy"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/saml"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/throttle"
 	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
 	"github.com/AurChatOrg/aurchat-server/internal/repo"
 	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/handler"
 	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
 	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
 	"github.com/bwmarrin/snowflake"
 	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"go.uber.org/zap"
 )
 
-// RegisterAuthAPI Register Auth API
-func RegisterAuthAPI(route *gin.RouterGroup) {
-	hasher := hasher.NewHasher(config.Cfg.Hash.Memory, config.Cfg.Hash.Inerations, uint8(runtime.NumCPU()), config.Cfg.Hash.SaltLength, 32)
-	tokenGen := token.NewToken(config.Cfg, 3600*24*30)
-	node, err := snowflake.NewNode(config.Cfg.Snowflake.WorkerID) // Create a SnowFlake Node
+// RegisterAuthAPI Register Auth API, returning the token validator for other modules' middleware
+func RegisterAuthAPI(route *gin.RouterGroup, node *snowflake.Node, hasher *hasher.Hasher) middleware.TokenValidator {
+	revocations := newRevocationStore()
+	passwordPolicy, err := policy.NewPasswordPolicy(config.Cfg.Auth.Password)
 	if err != nil {
-		logger.Logger.Error("Create a new Snowflake Node error", zap.Error(err))
+		logger.Logger.Fatal("Error loading password policy", zap.Error(err))
 	}
+	keyring, err := token.NewKeyring(config.Cfg)
+	if err != nil {
+		logger.Logger.Fatal("Error loading signing keys", zap.Error(err))
+	}
+	logger.Logger.Info("Loaded signing keys", zap.String("active", keyring.ActiveID()))
+	tokenGen := newAccessTokens(keyring, revocations)
+	ticketGen := token.NewPurposeToken(keyring, service.MFATicketTTL, revocations, service.MFATicketPurpose)
 
 	userRepo := repository.NewUserRepository(repo.Postgres)
-	service := service.NewAuthService(userRepo, hasher, tokenGen, node)
-	handler := handler.NewAuthHandler(service)
+	refreshRepo := repository.NewRefreshTokenRepository(repo.Postgres)
+	sessionRepo := repository.NewSessionRepository(repo.Postgres)
+	oneTimeTokenRepo := repository.NewOneTimeTokenRepository(repo.Postgres)
+	recoveryRepo := repository.NewRecoveryCodeRepository(repo.Postgres)
+	inviteRepo := repository.NewInviteRepository(repo.Postgres)
+	apiTokenRepo := repository.NewAPITokenRepository(repo.Postgres)
+	webAuthnRepo := repository.NewWebAuthnCredentialRepository(repo.Postgres)
+	identityRepo := repository.NewLinkedIdentityRepository(repo.Postgres)
+	mail := mailer.NewMailer(config.Cfg)
+
+	lockout := config.Cfg.Auth.Lockout
+	attempts := newAttemptStore()
+	userLimiter := throttle.NewLimiter(attempts, "user:", lockout.UserAttempts,
+		time.Duration(lockout.BaseDelay)*time.Second, time.Duration(lockout.MaxDelay)*time.Second, time.Duration(lockout.Window)*time.Second)
+	ipLimiter := throttle.NewLimiter(attempts, "ip:", lockout.IPAttempts,
+		time.Duration(lockout.BaseDelay)*time.Second, time.Duration(lockout.MaxDelay)*time.Second, time.Duration(lockout.Window)*time.Second)
+
+	challengeService := service.NewChallengeService(newChallengeVerifier(keyring, revocations), service.ChallengeRules{
+		SignUp:        config.Cfg.Auth.Challenge.SignUp,
+		PasswordReset: config.Cfg.Auth.Challenge.PasswordReset,
+		MagicLink:     config.Cfg.Auth.Challenge.MagicLink,
+		SignInAfter:   config.Cfg.Auth.Challenge.SignInAfter,
+	}, userLimiter, ipLimiter)
+	mailThrottle := service.NewMailThrottle(
+		throttle.NewLimiter(attempts, "mail-email:", service.MailEmailFree, service.MailBaseDelay, service.MailMaxDelay, service.MailLimitReset),
+		throttle.NewLimiter(attempts, "mail-ip:", service.MailIPFree, service.MailBaseDelay, service.MailMaxDelay, service.MailLimitReset),
+	)
+
+	verificationService := service.NewVerificationService(userRepo, oneTimeTokenRepo, mail, config.Cfg.App.BaseURL, mailThrottle)
+	authService := service.NewAuthService(service.AuthDeps{
+		UserRepo:     userRepo,
+		RefreshRepo:  refreshRepo,
+		SessionRepo:  sessionRepo,
+		RecoveryRepo: recoveryRepo,
+		InviteRepo:   inviteRepo,
+		APITokenRepo: apiTokenRepo,
+		Hasher:       hasher,
+		Policy:       passwordPolicy,
+		TokenGen:     tokenGen,
+		TicketGen:    ticketGen,
+		IDGenerator:  node,
+		RefreshTTL:   config.Cfg.Auth.RefreshTTL,
+
+		Verification:         verificationService,
+		RequireVerifiedEmail: config.Cfg.Auth.RequireVerifiedEmail,
+		Registration:         registrationMode(),
+		Passwordless:         config.Cfg.Auth.MagicLink.Enabled,
+		Challenges:           challengeService,
+
+		UserLimiter: userLimiter,
+		IPLimiter:   ipLimiter,
+
+		Authenticators: newAuthenticators(userRepo, hasher, node),
+	})
+	sessionService := service.NewSessionService(sessionRepo, refreshRepo)
+	mfaService := service.NewMFAService(userRepo, recoveryRepo, hasher, userLimiter)
+	passwordService := service.NewPasswordService(
+		userRepo, oneTimeTokenRepo, hasher, passwordPolicy, mail, authService, sessionService, challengeService, mailThrottle, userLimiter, config.Cfg.App.BaseURL,
+	)
+	botService := service.NewBotService(userRepo, apiTokenRepo, node, config.Cfg.Auth.MaxBots)
+	apiTokenService := service.NewAPITokenService(apiTokenRepo, botService)
+	magicLinkService := service.NewMagicLinkService(
+		userRepo, oneTimeTokenRepo, mail, authService, challengeService, mailThrottle,
+		time.Duration(config.Cfg.Auth.MagicLink.TTL)*time.Second, config.Cfg.App.BaseURL,
+	)
+	webAuthnService := service.NewWebAuthnService(
+		newWebAuthn(), userRepo, webAuthnRepo,
+		token.NewSealer(keyring, service.WebAuthnCeremonyTTL, revocations, service.WebAuthnCeremonyPurpose),
+		authService, mfaService,
+	)
+	oidcService := service.NewOIDCService(
+		oidc.NewRegistry(config.Cfg.OIDC, config.Cfg.App.BaseURL), userRepo, identityRepo,
+		token.NewSealer(keyring, service.OIDCStateTTL, revocations, service.OIDCStatePurpose),
+		hasher, node, authService, verificationService, mfaService,
+	)
+	samlRegistry, err := saml.NewRegistry(config.Cfg.SAML, config.Cfg.App.BaseURL)
+	if err != nil {
+		logger.Logger.Fatal("Error loading SAML providers", zap.Error(err))
+	}
+	samlService := service.NewSAMLService(
+		samlRegistry, userRepo, identityRepo,
+		token.NewSealer(keyring, service.SAMLStateTTL, revocations, service.SAMLStatePurpose),
+		revocations, hasher, node, authService,
+	)
+	authHandler := handler.NewAuthHandler(authService)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	challengeHandler := handler.NewChallengeHandler(challengeService)
+	verificationHandler := handler.NewVerificationHandler(verificationService)
+	passwordHandler := handler.NewPasswordHandler(passwordService)
+	magicLinkHandler := handler.NewMagicLinkHandler(magicLinkService)
+	botHandler := handler.NewBotHandler(botService)
+	apiTokenHandler := handler.NewAPITokenHandler(apiTokenService)
+	mfaHandler := handler.NewMFAHandler(authService, mfaService)
+	webAuthnHandler := handler.NewWebAuthnHandler(webAuthnService)
+	oidcHandler := handler.NewOIDCHandler(oidcService)
+	samlHandler := handler.NewSAMLHandler(samlService)
+	// The auth API manages the account itself, so API tokens are refused throughout
+	requireAuth := middleware.RequireSessionAuth(authService)
 
 	auth := route.Group("/auth")
 	{
-		auth.POST("/signIn", handler.SignIn)
-		auth.POST("/signUp", handler.SignUp)
+		auth.POST("/signIn", authHandler.SignIn)
+		auth.POST("/signUp", authHandler.SignUp)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/signOut", requireAuth, authHandler.SignOut)
+		auth.POST("/signOutAll", requireAuth, authHandler.SignOutAll)
+		auth.GET("/keys", authHandler.PublicKeys)
+		auth.GET("/challenge", challengeHandler.Issue)
+		auth.POST("/verify-email", verificationHandler.Verify)
+		auth.POST("/verify-email/resend", verificationHandler.Resend)
+		auth.POST("/password/forgot", passwordHandler.Forgot)
+		auth.POST("/password/reset", passwordHandler.Reset)
+		auth.POST("/password/change", requireAuth, passwordHandler.Change)
+		if config.Cfg.Auth.MagicLink.Enabled {
+			auth.POST("/magic-link", magicLinkHandler.Send)
+			auth.POST("/magic-link/consume", magicLinkHandler.Consume)
+		}
+
+		mfa := auth.Group("/mfa")
+		{
+			mfa.POST("/verify", mfaHandler.Verify)
+			mfa.POST("/totp/setup", requireAuth, mfaHandler.SetupTOTP)
+			mfa.POST("/totp/confirm", requireAuth, mfaHandler.ConfirmTOTP)
+			mfa.POST("/totp/disable", requireAuth, mfaHandler.DisableTOTP)
+			mfa.POST("/recovery-codes", requireAuth, mfaHandler.RegenerateRecoveryCodes)
+		}
+
+		passkeys := auth.Group("/webauthn")
+		{
+			passkeys.POST("/register/begin", requireAuth, webAuthnHandler.BeginRegistration)
+			passkeys.POST("/register/finish", requireAuth, webAuthnHandler.FinishRegistration)
+			passkeys.POST("/login/begin", webAuthnHandler.BeginLogin)
+			passkeys.POST("/login/finish", webAuthnHandler.FinishLogin)
+			passkeys.GET("/credentials", requireAuth, webAuthnHandler.List)
+			passkeys.PATCH("/credentials/:id", requireAuth, webAuthnHandler.Rename)
+			passkeys.DELETE("/credentials/:id", requireAuth, webAuthnHandler.Delete)
+		}
+
+		sso := auth.Group("/oidc")
+		{
+			sso.GET("/providers", oidcHandler.Providers)
+			sso.GET("/:provider/login", oidcHandler.Login)
+			sso.POST("/:provider/link", requireAuth, oidcHandler.Link)
+			sso.GET("/:provider/callback", oidcHandler.Callback)
+		}
+		samlSSO := auth.Group("/saml")
+		{
+			samlSSO.GET("/providers", samlHandler.Providers)
+			samlSSO.GET("/:provider/metadata", samlHandler.Metadata)
+			samlSSO.GET("/:provider/login", samlHandler.Login)
+			samlSSO.POST("/:provider/acs", samlHandler.ACS)
+		}
+		auth.GET("/identities", requireAuth, oidcHandler.Identities)
+		auth.DELETE("/identities/:id", requireAuth, oidcHandler.Unlink)
+
+		bots := auth.Group("/bots", requireAuth)
+		{
+			bots.GET("", botHandler.List)
+			bots.POST("", botHandler.Create)
+			bots.DELETE("/:id", botHandler.Delete)
+		}
+
+		tokens := auth.Group("/tokens", requireAuth)
+		{
+			tokens.GET("", apiTokenHandler.List)
+			tokens.POST("", apiTokenHandler.Create)
+			tokens.POST("/:id/rotate", apiTokenHandler.Rotate)
+			tokens.DELETE("/:id", apiTokenHandler.Revoke)
+		}
+
+		sessions := auth.Group("/sessions", requireAuth)
+		{
+			sessions.GET("", sessionHandler.List)
+			sessions.DELETE("", sessionHandler.RevokeOthers)
+			sessions.PATCH("/:id", sessionHandler.Rename)
+			sessions.DELETE("/:id", sessionHandler.Revoke)
+		}
+	}
+
+	return authService
+}
+
+// newRevocationStore Share revocations through Redis when configured, otherwise keep them in process
+func newRevocationStore() token.RevocationStore {
+	if repo.Redis == nil {
+		logger.Logger.Warn("Redis is not configured, token revocations are kept in memory")
+		return token.NewMemoryRevocationStore()
+	}
+	return token.NewRedisRevocationStore(repo.Redis)
+}
+
+// newAttemptStore Share sign in failures through Redis when configured, otherwise count them in process
+func newAttemptStore() throttle.Store {
+	if repo.Redis == nil {
+		logger.Logger.Warn("Redis is not configured, sign in failures are counted in memory")
+		return throttle.NewMemoryStore()
+	}
+	return throttle.NewRedisStore(repo.Redis)
+}
+
+// newWebAuthn Relying party settings for passkey ceremonies
+func newWebAuthn() *webauthn.WebAuthn {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          config.Cfg.WebAuthn.RPID,
+		RPDisplayName: config.Cfg.WebAuthn.RPDisplayName,
+		RPOrigins:     config.Cfg.WebAuthn.RPOrigins,
+	})
+	if err != nil {
+		logger.Logger.Fatal("Error creating WebAuthn relying party", zap.Error(err))
+	}
+	return webAuthn
+}
+
+// newAccessTokens Access token format selected by the config
+func newAccessTokens(keyring *token.Keyring, revocations token.RevocationStore) token.Token {
+	switch config.Cfg.Auth.Format {
+	case "", "branca":
+		return token.NewBrancaToken(keyring, config.Cfg.Auth.TTL, revocations)
+	case "paseto":
+		tokenGen, err := token.NewPASETOToken(config.Cfg, config.Cfg.Auth.TTL, revocations)
+		if err != nil {
+			logger.Logger.Fatal("Error loading PASETO keys", zap.Error(err))
+		}
+		return tokenGen
+	default:
+		logger.Logger.Fatal("Unknown token format", zap.String("format", config.Cfg.Auth.Format))
+		return nil
+	}
+}
+
+// registrationMode Sign up mode from the config, open when unset
+func registrationMode() string {
+	switch mode := config.Cfg.Auth.Registration; mode {
+	case "":
+		return service.RegistrationOpen
+	case service.RegistrationOpen, service.RegistrationInvite, service.RegistrationClosed:
+		return mode
+	default:
+		logger.Logger.Fatal("Unknown registration mode", zap.String("registration", mode))
+		return ""
+	}
+}
+
+// newChallengeVerifier Challenge provider selected by the config, nil when challenges are disabled
+func newChallengeVerifier(keyring *token.Keyring, revocations token.RevocationStore) challenge.Verifier {
+	challengeCfg := config.Cfg.Auth.Challenge
+	switch challengeCfg.Provider {
+	case "":
+		return nil
+	case challenge.TypeProofOfWork:
+		sealer := token.NewSealer(keyring, challengeCfg.PoW.TTL, revocations, service.ChallengePurpose)
+		return challenge.NewProofOfWork(sealer, challengeCfg.PoW.Difficulty)
+	case challenge.TypeCaptcha:
+		if challengeCfg.Captcha.VerifyURL == "" || challengeCfg.Captcha.Secret == "" {
+			logger.Logger.Fatal("CAPTCHA challenges need a verify URL and secret")
+		}
+		return challenge.NewCaptcha(challengeCfg.Captcha.VerifyURL, challengeCfg.Captcha.SiteKey, challengeCfg.Captcha.Secret,
+			time.Duration(challengeCfg.Captcha.Timeout)*time.Second)
+	default:
+		logger.Logger.Fatal("Unknown challenge provider", zap.String("provider", challengeCfg.Provider))
+		return nil
+	}
+}
+
+// newAuthenticators Sign in checks the directory first when LDAP is enabled, then local passwords
+func newAuthenticators(userRepo repository.UserRepository, hasher *hasher.Hasher, node *snowflake.Node) []service.Authenticator {
+	local := service.NewLocalAuthenticator(userRepo, hasher)
+	ldapCfg := config.Cfg.LDAP
+	if !ldapCfg.Enabled {
+		return []service.Authenticator{local}
+	}
+
+	if ldapCfg.URL == "" || (ldapCfg.UserDN == "" && ldapCfg.BaseDN == "") {
+		logger.Logger.Fatal("LDAP needs a server URL and either a user DN template or a base DN")
 	}
+	ldap := service.NewLDAPAuthenticator(directory.NewClient(ldapCfg), userRepo, node, ldapCfg)
+	return []service.Authenticator{ldap, local}
 }
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type APITokenHandler struct {
+	apiTokenService service.APITokenService
+}
+
+func NewAPITokenHandler(apiTokenService service.APITokenService) *APITokenHandler {
+	return &APITokenHandler{apiTokenService: apiTokenService}
+}
+
+// Create godoc
+// @Summary      Create API token
+// @Description  Create a scoped API token acting as the current user or one of their bots. The token is only returned in this response
+// @Tags         API token
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body body dto.CreateAPITokenReq true "Token name, scopes and expiry"
+// @Success      201  {object}  dto.APITokenResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/tokens [post]
+func (h *APITokenHandler) Create(c *gin.Context) {
+	var req dto.CreateAPITokenReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	apiToken, raw, err := h.apiTokenService.Create(middleware.MustCurrentUser(c), req.BotID, req.Name, req.Scopes, req.ExpiresIn)
+	if err != nil {
+		writeBotError(c, err)
+		return
+	}
+
+	resp := apiTokenResp(apiToken)
+	resp.Token = raw
+	c.JSON(http.StatusCreated, resp)
+}
+
+// List godoc
+// @Summary      List API tokens
+// @Description  List the API tokens of the current user and of their bots, without the tokens themselves
+// @Tags         API token
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   dto.APITokenResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/tokens [get]
+func (h *APITokenHandler) List(c *gin.Context) {
+	apiTokens, err := h.apiTokenService.List(middleware.MustCurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	resp := make([]dto.APITokenResp, 0, len(apiTokens))
+	for _, apiToken := range apiTokens {
+		resp = append(resp, apiTokenResp(apiToken))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Rotate godoc
+// @Summary      Rotate API token
+// @Description  Replace the secret of an API token, keeping its name, scopes and expiry. The old token stops working at once
+// @Tags         API token
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path int true "API token ID"
+// @Success      200  {object}  dto.APITokenResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/tokens/{id}/rotate [post]
+func (h *APITokenHandler) Rotate(c *gin.Context) {
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	apiToken, raw, err := h.apiTokenService.Rotate(middleware.MustCurrentUser(c), uint(tokenID))
+	if err != nil {
+		writeBotError(c, err)
+		return
+	}
+
+	resp := apiTokenResp(apiToken)
+	resp.Token = raw
+	c.JSON(http.StatusOK, resp)
+}
+
+// Revoke godoc
+// @Summary      Revoke API token
+// @Description  Revoke an API token of the current user or one of their bots
+// @Tags         API token
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path int true "API token ID"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/tokens/{id} [delete]
+func (h *APITokenHandler) Revoke(c *gin.Context) {
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	if err = h.apiTokenService.Revoke(middleware.MustCurrentUser(c), uint(tokenID)); err != nil {
+		writeBotError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func apiTokenResp(apiToken *model.APIToken) dto.APITokenResp {
+	return dto.APITokenResp{
+		ID:         apiToken.ID,
+		Name:       apiToken.Name,
+		Hint:       apiToken.Hint,
+		Scopes:     strings.Split(apiToken.Scopes, ","),
+		UserID:     apiToken.UserID,
+		ExpiresAt:  apiToken.ExpiresAt,
+		LastUsedAt: apiToken.LastUsedAt,
+		CreatedAt:  apiToken.CreatedAt,
+	}
+}
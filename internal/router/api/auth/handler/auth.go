@@ -1,12 +1,20 @@
 package handler
 
 import (
+	"encoding/base64"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/AurChatOrg/aurchat-server/internal/code"
 	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/validation"
 	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 type AuthHandler struct {
@@ -19,14 +27,19 @@ func NewAuthHandler(authService service.AuthService) *AuthHandler {
 
 // SignIn godoc
 // @Summary      Sign in
-// @Description  Sign in to the account using the username and password, and return a token if they are correct
+// @Description  Sign in with a username or email, matched case insensitively, and the password, and return a token if they are correct.
+// @Description  Accounts with two-factor authentication get an MFA ticket to exchange at /auth/mfa/verify instead.
+// @Description  After repeated failures a challenge from /auth/challenge must be answered
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
 // @Param        body body dto.SignInReq true "Sign in information"
 // @Success      200  {object}  dto.TokenResp
+// @Success      202  {object}  dto.MFARequiredResp
 // @Failure      400  {object}  dto.ErrorResp
+// @Failure      429  {object}  dto.ErrorResp
 // @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
 // @Router       /auth/signIn [post]
 func (h *AuthHandler) SignIn(c *gin.Context) {
 	var req dto.SignInReq
@@ -38,8 +51,14 @@ func (h *AuthHandler) SignIn(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.SignIn(req.Username, req.Password)
+	client := clientInfo(c, req.DeviceName)
+	client.Challenge = req.Challenge
+
+	result, err := h.authService.SignIn(c.Request.Context(), req.Identifier, req.Password, client)
 	if err != nil {
+		if writeRetryLater(c, err) {
+			return
+		}
 		if authErr, ok := err.(*service.AuthError); ok {
 			c.JSON(http.StatusBadRequest, dto.ErrorResp{
 				Code: authErr.Code,
@@ -52,45 +71,98 @@ func (h *AuthHandler) SignIn(c *gin.Context) {
 		return
 	}
 
-	c.SetSameSite(http.SameSiteStrictMode)
-	c.SetCookie(
-		"token", // name
-		token,   // value
-		3600*24, // max-age
-		"/",     // path
-		"",      // domain
-		true,    // secure
-		true,    // httpOnly
-	)
-
-	c.JSON(http.StatusOK, dto.TokenResp{Token: token})
+	writeSignInResult(c, result)
 }
 
 // SignUp godoc
 // @Summary      Sign up
-// @Description  Register an account using a username, email and password, and return a token if no errors occur
+// @Description  Register an account using a username, email and password, and return a token if no errors occur. An invite code is required when registration is invite only, and a challenge answer when sign up challenges are enabled.
+// @Description  The password may be omitted when sign in links are enabled, creating an account that only signs in through them
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
 // @Param        body body dto.SignUpReq true "Sign up information"
 // @Success      200  {object}  dto.TokenResp
 // @Failure      400  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
 // @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
 // @Router       /auth/signUp [post]
 func (h *AuthHandler) SignUp(c *gin.Context) {
 	var req dto.SignUpReq
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResp{
-			Code: code.InvalidParameter,
+			Code: bindErrorCode(err),
 		})
 		return
 	}
 
-	token, err := h.authService.SignUp(req.Username, req.Password, req.Email)
+	client := clientInfo(c, req.DeviceName)
+	client.Challenge = req.Challenge
+
+	tokens, err := h.authService.SignUp(c.Request.Context(), req.Username, req.Password, req.Email, req.InviteCode, client)
 	if err != nil {
+		if writeRetryLater(c, err) {
+			return
+		}
 		if authErr, ok := err.(*service.AuthError); ok {
+			status := http.StatusBadRequest
+			if authErr == service.ErrRegistrationClosed {
+				status = http.StatusForbidden
+			}
+			c.JSON(status, dto.ErrorResp{
+				Code:    authErr.Code,
+				Reasons: authErr.Reasons,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+				Code: code.ServerUnknownError,
+			})
+		}
+		return
+	}
+
+	writeTokens(c, tokens)
+}
+
+// Refresh godoc
+// @Summary      Refresh token
+// @Description  Exchange a refresh token for a new token pair. The refresh token is rotated on every use and reusing an old one revokes the whole session
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.RefreshReq false "Refresh token, read from the refresh_token cookie when omitted"
+// @Success      200  {object}  dto.TokenResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req dto.RefreshReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, dto.ErrorResp{
+				Code: code.InvalidParameter,
+			})
+			return
+		}
+	}
+
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = c.Cookie(refreshTokenCookie)
+	}
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResp{
+			Code: code.Unauthorized,
+		})
+		return
+	}
+
+	tokens, err := h.authService.Refresh(req.RefreshToken)
+	if err != nil {
+		if authErr, ok := err.(*service.AuthError); ok {
+			c.JSON(http.StatusUnauthorized, dto.ErrorResp{
 				Code: authErr.Code,
 			})
 		} else {
@@ -101,16 +173,128 @@ func (h *AuthHandler) SignUp(c *gin.Context) {
 		return
 	}
 
-	c.SetSameSite(http.SameSiteStrictMode)
-	c.SetCookie(
-		"token", // name
-		token,   // value
-		3600*24, // max-age
-		"/",     // path
-		"",      // domain
-		true,    // secure
-		true,    // httpOnly
-	)
+	writeTokens(c, tokens)
+}
+
+// SignOut godoc
+// @Summary      Sign out
+// @Description  Revoke the current token and its session, and clear the token cookies
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      204
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/signOut [post]
+func (h *AuthHandler) SignOut(c *gin.Context) {
+	claims, _ := middleware.CurrentClaims(c)
+
+	if err := h.authService.SignOut(claims); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	clearTokens(c)
+	c.Status(http.StatusNoContent)
+}
+
+// SignOutAll godoc
+// @Summary      Sign out everywhere
+// @Description  Revoke every token issued to the current user on every device, including the API tokens of the user and their bots
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      204
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/signOutAll [post]
+func (h *AuthHandler) SignOutAll(c *gin.Context) {
+	user := middleware.MustCurrentUser(c)
+
+	if err := h.authService.SignOutAll(user.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	clearTokens(c)
+	c.Status(http.StatusNoContent)
+}
+
+// PublicKeys godoc
+// @Summary      Token verification keys
+// @Description  List the public keys that verify access tokens offline. Tokens name their key in the kid footer claim. Only available for PASETO tokens
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  dto.PublicKeysResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Router       /auth/keys [get]
+func (h *AuthHandler) PublicKeys(c *gin.Context) {
+	keys := h.authService.PublicKeys()
+	if len(keys) == 0 {
+		c.JSON(http.StatusNotFound, dto.ErrorResp{
+			Code: code.NotFound,
+		})
+		return
+	}
+
+	resp := dto.PublicKeysResp{Keys: make([]dto.PublicKeyResp, 0, len(keys))}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, dto.PublicKeyResp{
+			KeyID:     key.ID,
+			Version:   key.Version,
+			PublicKey: "k4.public." + base64.RawURLEncoding.EncodeToString(key.Key),
+			Active:    key.Active,
+		})
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, resp)
+}
 
-	c.JSON(http.StatusOK, dto.TokenResp{Token: token})
+// writeRetryLater Answer with Retry-After when the request was refused for now,
+// 429 for a lockout and 503 when password hashing is at capacity
+func writeRetryLater(c *gin.Context, err error) bool {
+	var tooMany *service.TooManyAttemptsError
+	if errors.As(err, &tooMany) {
+		seconds := int(math.Ceil(tooMany.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, dto.ErrorResp{
+			Code: code.TooManyRequests,
+		})
+		return true
+	}
+
+	if errors.Is(err, hasher.ErrBusy) {
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, dto.ErrorResp{
+			Code: code.ServiceUnavailable,
+		})
+		return true
+	}
+	return false
+}
+
+// bindErrorCode Report the username and email rules by their own codes, anything else as an invalid parameter
+func bindErrorCode(err error) int {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return code.InvalidParameter
+	}
+
+	for _, fieldErr := range fieldErrs {
+		switch fieldErr.Tag() {
+		case validation.TagUsername:
+			value, _ := fieldErr.Value().(string)
+			if _, err = validation.Username(value); errors.Is(err, validation.ErrUsernameReserved) {
+				return code.UsernameReserved
+			}
+			return code.InvalidUsernameFormat
+		case validation.TagEmail:
+			return code.InvalidEmailFormat
+		}
+	}
+	return code.InvalidParameter
 }
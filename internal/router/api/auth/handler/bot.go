@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type BotHandler struct {
+	botService service.BotService
+}
+
+func NewBotHandler(botService service.BotService) *BotHandler {
+	return &BotHandler{botService: botService}
+}
+
+// Create godoc
+// @Summary      Create bot
+// @Description  Create a bot account managed by the current user. Bots have no password and authenticate with API tokens
+// @Tags         Bot
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body body dto.CreateBotReq true "Bot username"
+// @Success      201  {object}  dto.BotResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/bots [post]
+func (h *BotHandler) Create(c *gin.Context) {
+	var req dto.CreateBotReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: bindErrorCode(err),
+		})
+		return
+	}
+
+	bot, err := h.botService.Create(middleware.MustCurrentUser(c), req.Username)
+	if err != nil {
+		writeBotError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, botResp(bot))
+}
+
+// List godoc
+// @Summary      List bots
+// @Description  List the bots managed by the current user
+// @Tags         Bot
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   dto.BotResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/bots [get]
+func (h *BotHandler) List(c *gin.Context) {
+	bots, err := h.botService.List(middleware.MustCurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	resp := make([]dto.BotResp, 0, len(bots))
+	for _, bot := range bots {
+		resp = append(resp, botResp(bot))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Delete godoc
+// @Summary      Delete bot
+// @Description  Delete a bot managed by the current user and revoke every API token it holds
+// @Tags         Bot
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path string true "Bot ID"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      403  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/bots/{id} [delete]
+func (h *BotHandler) Delete(c *gin.Context) {
+	botID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	if err = h.botService.Delete(middleware.MustCurrentUser(c), botID); err != nil {
+		writeBotError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func botResp(bot *model.User) dto.BotResp {
+	return dto.BotResp{
+		ID:        bot.UserID,
+		Username:  bot.Username,
+		CreatedAt: bot.CreatedAt,
+	}
+}
+
+// writeBotError Respond to bot and API token errors, missing ones as 404
+func writeBotError(c *gin.Context, err error) {
+	if authErr, ok := err.(*service.AuthError); ok {
+		status := http.StatusBadRequest
+		if authErr == service.ErrBotNotFound || authErr == service.ErrAPITokenNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, dto.ErrorResp{
+			Code: authErr.Code,
+		})
+	} else {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/challenge"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ChallengeHandler struct {
+	challengeService service.ChallengeService
+}
+
+func NewChallengeHandler(challengeService service.ChallengeService) *ChallengeHandler {
+	return &ChallengeHandler{challengeService: challengeService}
+}
+
+// Issue godoc
+// @Summary      Get challenge
+// @Description  Get an anti-bot challenge for an endpoint, to answer in the challenge field of its request
+// @Tags         Auth
+// @Produce      json
+// @Param        endpoint query string true "Guarded endpoint" Enums(signUp, signIn, passwordReset, magicLink)
+// @Success      200  {object}  dto.ChallengeResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/challenge [get]
+func (h *ChallengeHandler) Issue(c *gin.Context) {
+	endpoint := c.Query("endpoint")
+	if !challenge.IsEndpoint(endpoint) {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	issued, err := h.challengeService.Issue(endpoint)
+	if err != nil {
+		if err == service.ErrChallengeNotRequired {
+			c.JSON(http.StatusNotFound, dto.ErrorResp{
+				Code: code.ChallengeNotRequired,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+				Code: code.ServerUnknownError,
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ChallengeResp{
+		Type:       issued.Type,
+		Token:      issued.Token,
+		Difficulty: issued.Difficulty,
+		ExpiresIn:  issued.ExpiresIn,
+		SiteKey:    issued.SiteKey,
+	})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	refreshTokenCookie = "refresh_token"
+	refreshTokenPath   = "/api/v1/auth" // Refresh cookie is only sent to the auth API
+	magicLinkCookie    = "magic_link_nonce"
+)
+
+// writeTokens Set the token cookies and respond with the token pair
+func writeTokens(c *gin.Context, tokens *service.TokenPair) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(
+		middleware.TokenCookie, // name
+		tokens.AccessToken,     // value
+		int(tokens.ExpiresIn),  // max-age
+		"/",                    // path
+		"",                     // domain
+		true,                   // secure
+		true,                   // httpOnly
+	)
+	c.SetCookie(
+		refreshTokenCookie,           // name
+		tokens.RefreshToken,          // value
+		int(tokens.RefreshExpiresIn), // max-age
+		refreshTokenPath,             // path
+		"",                           // domain
+		true,                         // secure
+		true,                         // httpOnly
+	)
+
+	c.JSON(http.StatusOK, dto.TokenResp{
+		Token:            tokens.AccessToken,
+		RefreshToken:     tokens.RefreshToken,
+		ExpiresIn:        tokens.ExpiresIn,
+		RefreshExpiresIn: tokens.RefreshExpiresIn,
+	})
+}
+
+// writeSignInResult Respond with the token pair, or with the ticket for the two-factor step
+func writeSignInResult(c *gin.Context, result *service.SignInResult) {
+	if result.MFATicket != "" {
+		c.JSON(http.StatusAccepted, dto.MFARequiredResp{
+			MFARequired: true,
+			Ticket:      result.MFATicket,
+			ExpiresIn:   result.MFATicketExpiresIn,
+		})
+		return
+	}
+
+	writeTokens(c, result.Tokens)
+}
+
+// clientInfo Collect the device details recorded on a new session
+func clientInfo(c *gin.Context, deviceName string) service.ClientInfo {
+	return service.ClientInfo{
+		DeviceName: deviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+	}
+}
+
+// clearTokens Expire the token cookies
+func clearTokens(c *gin.Context) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(middleware.TokenCookie, "", -1, "/", "", true, true)
+	c.SetCookie(refreshTokenCookie, "", -1, refreshTokenPath, "", true, true)
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/gin-gonic/gin"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService service.MagicLinkService
+}
+
+func NewMagicLinkHandler(magicLinkService service.MagicLinkService) *MagicLinkHandler {
+	return &MagicLinkHandler{magicLinkService: magicLinkService}
+}
+
+// Send godoc
+// @Summary      Send sign in link
+// @Description  Email a single-use sign in link and set the nonce cookie it is bound to, so the link only works in this browser.
+// @Description  The response is the same whether or not the address belongs to an account
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.MagicLinkReq true "Account email"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      429  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/magic-link [post]
+func (h *MagicLinkHandler) Send(c *gin.Context) {
+	var req dto.MagicLinkReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	client := clientInfo(c, "")
+	client.Challenge = req.Challenge
+
+	nonce, err := h.magicLinkService.Send(c.Request.Context(), req.Email, client)
+	if err != nil {
+		writeMagicLinkError(c, err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(magicLinkCookie, nonce, int(h.magicLinkService.TTL().Seconds()), refreshTokenPath, "", true, true)
+	c.Status(http.StatusNoContent)
+}
+
+// Consume godoc
+// @Summary      Sign in with link
+// @Description  Exchange the token from a sign in link, in the browser holding its nonce cookie, for a token pair.
+// @Description  Accounts with two-factor authentication get an MFA ticket to exchange at /auth/mfa/verify instead
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.ConsumeMagicLinkReq true "Link token"
+// @Success      200  {object}  dto.TokenResp
+// @Success      202  {object}  dto.MFARequiredResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/magic-link/consume [post]
+func (h *MagicLinkHandler) Consume(c *gin.Context) {
+	var req dto.ConsumeMagicLinkReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	nonce, _ := c.Cookie(magicLinkCookie)
+	result, err := h.magicLinkService.Consume(req.Token, nonce, clientInfo(c, req.DeviceName))
+	if err != nil {
+		writeMagicLinkError(c, err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(magicLinkCookie, "", -1, refreshTokenPath, "", true, true)
+	writeSignInResult(c, result)
+}
+
+func writeMagicLinkError(c *gin.Context, err error) {
+	if writeRetryLater(c, err) {
+		return
+	}
+	if authErr, ok := err.(*service.AuthError); ok {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: authErr.Code,
+		})
+	} else {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	authService service.AuthService
+	mfaService  service.MFAService
+}
+
+func NewMFAHandler(authService service.AuthService, mfaService service.MFAService) *MFAHandler {
+	return &MFAHandler{authService: authService, mfaService: mfaService}
+}
+
+// Verify godoc
+// @Summary      Verify second factor
+// @Description  Exchange the MFA ticket from sign in and a TOTP or recovery code for a token. A wrong code invalidates the ticket
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.MFAVerifyReq true "Ticket and code"
+// @Success      200  {object}  dto.TokenResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/mfa/verify [post]
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var req dto.MFAVerifyReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	tokens, err := h.authService.VerifyMFA(req.Ticket, req.Code, clientInfo(c, req.DeviceName))
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	writeTokens(c, tokens)
+}
+
+// SetupTOTP godoc
+// @Summary      Start TOTP enrollment
+// @Description  Generate a TOTP secret and otpauth URI for the current user, pending confirmation
+// @Tags         MFA
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  dto.TOTPSetupResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/mfa/totp/setup [post]
+func (h *MFAHandler) SetupTOTP(c *gin.Context) {
+	user := middleware.MustCurrentUser(c)
+
+	setup, err := h.mfaService.SetupTOTP(user)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TOTPSetupResp{Secret: setup.Secret, URI: setup.URI})
+}
+
+// ConfirmTOTP godoc
+// @Summary      Confirm TOTP enrollment
+// @Description  Enable two-factor authentication with a code from the authenticator app and return the recovery codes
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body body dto.TOTPConfirmReq true "TOTP code"
+// @Success      200  {object}  dto.RecoveryCodesResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	var req dto.TOTPConfirmReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmTOTP(middleware.MustCurrentUser(c), req.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResp{RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+// @Summary      Disable TOTP
+// @Description  Turn two-factor authentication off. Requires the password and a TOTP or recovery code
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body body dto.MFAReauthReq true "Password and code"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      429  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
+// @Router       /auth/mfa/totp/disable [post]
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	var req dto.MFAReauthReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	if err := h.mfaService.DisableTOTP(c.Request.Context(), middleware.MustCurrentUser(c), req.Password, req.Code); err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replace every recovery code. Requires the password and a TOTP or recovery code
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body body dto.MFAReauthReq true "Password and code"
+// @Success      200  {object}  dto.RecoveryCodesResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      429  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
+// @Router       /auth/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.MFAReauthReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), middleware.MustCurrentUser(c), req.Password, req.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResp{RecoveryCodes: codes})
+}
+
+func writeMFAError(c *gin.Context, err error) {
+	if writeRetryLater(c, err) {
+		return
+	}
+	if authErr, ok := err.(*service.AuthError); ok {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: authErr.Code,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+		Code: code.ServerUnknownError,
+	})
+}
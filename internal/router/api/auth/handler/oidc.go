@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStatePath   = "/api/v1/auth/oidc" // State cookie is only sent to the OIDC API
+)
+
+type OIDCHandler struct {
+	oidcService service.OIDCService
+}
+
+func NewOIDCHandler(oidcService service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService}
+}
+
+// Providers godoc
+// @Summary      List identity providers
+// @Description  List the configured OpenID Connect providers to show as sign in options
+// @Tags         OIDC
+// @Produce      json
+// @Success      200  {array}   dto.OIDCProviderResp
+// @Router       /auth/oidc/providers [get]
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	providers := h.oidcService.Providers()
+
+	resp := make([]dto.OIDCProviderResp, 0, len(providers))
+	for _, provider := range providers {
+		resp = append(resp, dto.OIDCProviderResp{
+			Name:        provider.Name(),
+			DisplayName: provider.DisplayName(),
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Login godoc
+// @Summary      Sign in with an identity provider
+// @Description  Redirect the browser to the provider's authorization endpoint
+// @Tags         OIDC
+// @Param        provider path string true "Provider name"
+// @Success      302
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, sealed, err := h.oidcService.Begin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		writeOIDCError(c, err)
+		return
+	}
+
+	setOIDCState(c, sealed)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Link godoc
+// @Summary      Link an identity provider
+// @Description  Return the provider authorization URL, the callback links the identity to the current user. Requires the password, if the account has one, and a TOTP or recovery code when two-factor authentication is enabled
+// @Tags         OIDC
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        provider path string        true "Provider name"
+// @Param        body     body dto.ReauthReq true "Password and code"
+// @Success      200  {object}  dto.OIDCLinkResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      429  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
+// @Router       /auth/oidc/{provider}/link [post]
+func (h *OIDCHandler) Link(c *gin.Context) {
+	var req dto.ReauthReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	authURL, sealed, err := h.oidcService.Link(
+		c.Request.Context(), c.Param("provider"), middleware.MustCurrentUser(c), req.Password, req.Code,
+	)
+	if err != nil {
+		writeOIDCError(c, err)
+		return
+	}
+
+	setOIDCState(c, sealed)
+	c.JSON(http.StatusOK, dto.OIDCLinkResp{URL: authURL})
+}
+
+// Callback godoc
+// @Summary      Identity provider callback
+// @Description  Complete the authorization code flow. Returns a token after a sign in, or the linked identity after linking
+// @Tags         OIDC
+// @Produce      json
+// @Param        provider path  string true "Provider name"
+// @Param        code     query string true "Authorization code"
+// @Param        state    query string true "State"
+// @Success      200  {object}  dto.TokenResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
+// @Router       /auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	sealed, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStatePath, "", true, true)
+
+	if c.Query("error") != "" || c.Query("code") == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.IdentityProviderFailed,
+		})
+		return
+	}
+
+	result, err := h.oidcService.Callback(
+		c.Request.Context(), c.Param("provider"), c.Query("code"), c.Query("state"), sealed,
+		clientInfo(c, ""),
+	)
+	if err != nil {
+		writeOIDCError(c, err)
+		return
+	}
+
+	if result.Linked != nil {
+		c.JSON(http.StatusOK, linkedIdentityResp(result.Linked))
+		return
+	}
+	writeTokens(c, result.Tokens)
+}
+
+// Identities godoc
+// @Summary      List linked identities
+// @Description  List the identity provider accounts linked to the current user
+// @Tags         OIDC
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   dto.LinkedIdentityResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/identities [get]
+func (h *OIDCHandler) Identities(c *gin.Context) {
+	identities, err := h.oidcService.ListIdentities(middleware.MustCurrentUser(c).UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	resp := make([]dto.LinkedIdentityResp, 0, len(identities))
+	for _, identity := range identities {
+		resp = append(resp, linkedIdentityResp(identity))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Unlink godoc
+// @Summary      Unlink identity
+// @Description  Remove a linked identity provider account, it can no longer be used to sign in
+// @Tags         OIDC
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path int true "Linked identity ID"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/identities/{id} [delete]
+func (h *OIDCHandler) Unlink(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	user := middleware.MustCurrentUser(c)
+	if err := h.oidcService.Unlink(user.UserID, uint(id)); err != nil {
+		writeOIDCError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// setOIDCState Keep the sealed authorization state until the callback.
+// Lax, as the callback arrives through a cross-site redirect from the provider.
+func setOIDCState(c *gin.Context, sealed string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, sealed, service.OIDCStateTTL, oidcStatePath, "", true, true)
+}
+
+func linkedIdentityResp(identity *model.LinkedIdentity) dto.LinkedIdentityResp {
+	return dto.LinkedIdentityResp{
+		ID:        identity.ID,
+		Provider:  identity.Provider,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt,
+	}
+}
+
+func writeOIDCError(c *gin.Context, err error) {
+	if writeRetryLater(c, err) {
+		return
+	}
+	if authErr, ok := err.(*service.AuthError); ok {
+		status := http.StatusBadRequest
+		if authErr.Code == code.IdentityProviderNotFound || authErr.Code == code.IdentityNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, dto.ErrorResp{
+			Code: authErr.Code,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+		Code: code.ServerUnknownError,
+	})
+}
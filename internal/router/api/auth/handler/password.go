@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordHandler struct {
+	passwordService service.PasswordService
+}
+
+func NewPasswordHandler(passwordService service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{passwordService: passwordService}
+}
+
+// Forgot godoc
+// @Summary      Forgot password
+// @Description  Email a single-use password reset link. The response is the same whether or not the address belongs to an account.
+// @Description  A challenge answer is required when reset challenges are enabled
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.ForgotPasswordReq true "Account email"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      429  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/password/forgot [post]
+func (h *PasswordHandler) Forgot(c *gin.Context) {
+	var req dto.ForgotPasswordReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	client := clientInfo(c, "")
+	client.Challenge = req.Challenge
+
+	if err := h.passwordService.Forgot(c.Request.Context(), req.Email, client); err != nil {
+		if writeRetryLater(c, err) {
+			return
+		}
+		if authErr, ok := err.(*service.AuthError); ok {
+			c.JSON(http.StatusBadRequest, dto.ErrorResp{
+				Code: authErr.Code,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+				Code: code.ServerUnknownError,
+			})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Reset godoc
+// @Summary      Reset password
+// @Description  Set a new password with the token from the reset email. Every existing session is signed out
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.ResetPasswordReq true "Reset token and new password"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
+// @Router       /auth/password/reset [post]
+func (h *PasswordHandler) Reset(c *gin.Context) {
+	var req dto.ResetPasswordReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	if err := h.passwordService.Reset(c.Request.Context(), req.Token, req.Password); err != nil {
+		if writeRetryLater(c, err) {
+			return
+		}
+		if authErr, ok := err.(*service.AuthError); ok {
+			c.JSON(http.StatusBadRequest, dto.ErrorResp{
+				Code:    authErr.Code,
+				Reasons: authErr.Reasons,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+				Code: code.ServerUnknownError,
+			})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Change godoc
+// @Summary      Change password
+// @Description  Replace the password of the current user, or set the first one of a passwordless account. Every other session is signed out
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body body dto.ChangePasswordReq true "Current and new password"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      429  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
+// @Router       /auth/password/change [post]
+func (h *PasswordHandler) Change(c *gin.Context) {
+	var req dto.ChangePasswordReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	user := middleware.MustCurrentUser(c)
+	claims, _ := middleware.CurrentClaims(c)
+
+	if err := h.passwordService.Change(c.Request.Context(), user, claims.SessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		if writeRetryLater(c, err) {
+			return
+		}
+		if authErr, ok := err.(*service.AuthError); ok {
+			c.JSON(http.StatusBadRequest, dto.ErrorResp{
+				Code:    authErr.Code,
+				Reasons: authErr.Reasons,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+				Code: code.ServerUnknownError,
+			})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
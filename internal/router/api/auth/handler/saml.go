@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	samlStateCookie = "saml_state"
+	samlStatePath   = "/api/v1/auth/saml" // State cookie is only sent to the SAML API
+)
+
+type SAMLHandler struct {
+	samlService service.SAMLService
+}
+
+func NewSAMLHandler(samlService service.SAMLService) *SAMLHandler {
+	return &SAMLHandler{samlService: samlService}
+}
+
+// Providers godoc
+// @Summary      List SAML identity providers
+// @Description  List the configured SAML identity providers to show as sign in options
+// @Tags         SAML
+// @Produce      json
+// @Success      200  {array}   dto.SAMLProviderResp
+// @Router       /auth/saml/providers [get]
+func (h *SAMLHandler) Providers(c *gin.Context) {
+	providers := h.samlService.Providers()
+
+	resp := make([]dto.SAMLProviderResp, 0, len(providers))
+	for _, provider := range providers {
+		resp = append(resp, dto.SAMLProviderResp{
+			Name:        provider.Name(),
+			DisplayName: provider.DisplayName(),
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Metadata godoc
+// @Summary      Service provider metadata
+// @Description  SAML metadata to register AurChat with the identity provider
+// @Tags         SAML
+// @Produce      xml
+// @Param        provider path string true "Provider name"
+// @Success      200
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/saml/{provider}/metadata [get]
+func (h *SAMLHandler) Metadata(c *gin.Context) {
+	metadata, err := h.samlService.Metadata(c.Param("provider"))
+	if err != nil {
+		writeOIDCError(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// Login godoc
+// @Summary      Sign in with a SAML identity provider
+// @Description  Redirect the browser to the identity provider with a signed AuthnRequest
+// @Tags         SAML
+// @Param        provider path string true "Provider name"
+// @Success      302
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/saml/{provider}/login [get]
+func (h *SAMLHandler) Login(c *gin.Context) {
+	redirectURL, sealed, err := h.samlService.Begin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		writeOIDCError(c, err)
+		return
+	}
+
+	// None, as the identity provider posts the response back from its own site
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(samlStateCookie, sealed, service.SAMLStateTTL, samlStatePath, "", true, true)
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// ACS godoc
+// @Summary      Assertion consumer service
+// @Description  Verify the SAML response posted by the identity provider, for sign ins it started or that AurChat requested
+// @Tags         SAML
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        provider     path     string true  "Provider name"
+// @Param        SAMLResponse formData string true  "Base64 SAML response"
+// @Param        RelayState   formData string false "Relay state"
+// @Success      200  {object}  dto.TokenResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
+// @Router       /auth/saml/{provider}/acs [post]
+func (h *SAMLHandler) ACS(c *gin.Context) {
+	sealed, _ := c.Cookie(samlStateCookie)
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(samlStateCookie, "", -1, samlStatePath, "", true, true)
+
+	samlResponse := c.PostForm("SAMLResponse")
+	if samlResponse == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.IdentityProviderFailed,
+		})
+		return
+	}
+
+	tokens, err := h.samlService.Assert(
+		c.Request.Context(), c.Param("provider"), samlResponse, c.PostForm("RelayState"), sealed,
+		clientInfo(c, ""),
+	)
+	if err != nil {
+		writeOIDCError(c, err)
+		return
+	}
+
+	writeTokens(c, tokens)
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// List godoc
+// @Summary      List sessions
+// @Description  List the devices the current user is signed in on
+// @Tags         Session
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   dto.SessionResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/sessions [get]
+func (h *SessionHandler) List(c *gin.Context) {
+	user := middleware.MustCurrentUser(c)
+	claims, _ := middleware.CurrentClaims(c)
+
+	sessions, err := h.sessionService.List(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	resp := make([]dto.SessionResp, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, dto.SessionResp{
+			ID:         session.SessionID,
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.SessionID == claims.SessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Rename godoc
+// @Summary      Rename session
+// @Description  Change the device name of one of the current user's sessions
+// @Tags         Session
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path string true "Session ID"
+// @Param        body body dto.RenameSessionReq true "New device name"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/sessions/{id} [patch]
+func (h *SessionHandler) Rename(c *gin.Context) {
+	var req dto.RenameSessionReq
+
+	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	user := middleware.MustCurrentUser(c)
+	if err := h.sessionService.Rename(user.UserID, sessionID, req.DeviceName); err != nil {
+		writeSessionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Revoke godoc
+// @Summary      Revoke session
+// @Description  Sign out one of the current user's sessions, its tokens stop working immediately
+// @Tags         Session
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path string true "Session ID"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/sessions/{id} [delete]
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	user := middleware.MustCurrentUser(c)
+	if err := h.sessionService.Revoke(user.UserID, sessionID); err != nil {
+		writeSessionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeOthers godoc
+// @Summary      Revoke other sessions
+// @Description  Sign out every session of the current user except the one making the request
+// @Tags         Session
+// @Produce      json
+// @Security     BearerAuth
+// @Success      204
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/sessions [delete]
+func (h *SessionHandler) RevokeOthers(c *gin.Context) {
+	user := middleware.MustCurrentUser(c)
+	claims, _ := middleware.CurrentClaims(c)
+
+	if err := h.sessionService.RevokeOthers(user.UserID, claims.SessionID); err != nil {
+		writeSessionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func writeSessionError(c *gin.Context, err error) {
+	if authErr, ok := err.(*service.AuthError); ok {
+		c.JSON(http.StatusNotFound, dto.ErrorResp{
+			Code: authErr.Code,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+		Code: code.ServerUnknownError,
+	})
+}
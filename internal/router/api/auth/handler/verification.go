@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/gin-gonic/gin"
+)
+
+type VerificationHandler struct {
+	verificationService service.VerificationService
+}
+
+func NewVerificationHandler(verificationService service.VerificationService) *VerificationHandler {
+	return &VerificationHandler{verificationService: verificationService}
+}
+
+// Verify godoc
+// @Summary      Verify email
+// @Description  Confirm the account email address with the token from the verification email
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.VerifyEmailReq true "Verification token"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/verify-email [post]
+func (h *VerificationHandler) Verify(c *gin.Context) {
+	var req dto.VerifyEmailReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	if err := h.verificationService.Verify(req.Token); err != nil {
+		if authErr, ok := err.(*service.AuthError); ok {
+			c.JSON(http.StatusBadRequest, dto.ErrorResp{
+				Code: authErr.Code,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+				Code: code.ServerUnknownError,
+			})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Resend godoc
+// @Summary      Resend verification email
+// @Description  Send a new verification email. The response is the same whether or not the address belongs to an account
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.ResendVerificationReq true "Account email"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      429  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/verify-email/resend [post]
+func (h *VerificationHandler) Resend(c *gin.Context) {
+	var req dto.ResendVerificationReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	if err := h.verificationService.Resend(req.Email, c.ClientIP()); err != nil {
+		if writeRetryLater(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,308 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/AurChatOrg/aurchat-server/internal/router/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+const (
+	ceremonyCookie = "webauthn_ceremony"
+	ceremonyPath   = "/api/v1/auth/webauthn" // Ceremony cookie is only sent to the WebAuthn API
+)
+
+type WebAuthnHandler struct {
+	webAuthnService service.WebAuthnService
+}
+
+func NewWebAuthnHandler(webAuthnService service.WebAuthnService) *WebAuthnHandler {
+	return &WebAuthnHandler{webAuthnService: webAuthnService}
+}
+
+// BeginRegistration godoc
+// @Summary      Start passkey registration
+// @Description  Return the credential creation options for navigator.credentials.create. Requires the password, if the account has one, and a TOTP or recovery code when two-factor authentication is enabled
+// @Tags         WebAuthn
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body body dto.ReauthReq true "Password and code"
+// @Success      200  {object}  protocol.CredentialCreation
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      429  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Failure      503  {object}  dto.ErrorResp
+// @Router       /auth/webauthn/register/begin [post]
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	var req dto.ReauthReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	creation, ceremony, err := h.webAuthnService.BeginRegistration(
+		c.Request.Context(), middleware.MustCurrentUser(c), req.Password, req.Code,
+	)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	setCeremony(c, ceremony)
+	c.JSON(http.StatusOK, creation)
+}
+
+// FinishRegistration godoc
+// @Summary      Finish passkey registration
+// @Description  Verify the attestation returned by navigator.credentials.create and store the passkey
+// @Tags         WebAuthn
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name query string false "Passkey name"
+// @Param        body body object true "PublicKeyCredential from the browser"
+// @Success      200  {object}  dto.PasskeyResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/webauthn/register/finish [post]
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	ceremony := takeCeremony(c)
+
+	response, err := protocol.ParseCredentialCreationResponseBody(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	credential, err := h.webAuthnService.FinishRegistration(
+		middleware.MustCurrentUser(c), ceremony, c.Query("name"), response,
+	)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.PasskeyResp{
+		ID:         credential.ID,
+		Name:       credential.Name,
+		Synced:     credential.BackupState,
+		CreatedAt:  credential.CreatedAt,
+		LastUsedAt: credential.LastUsedAt,
+	})
+}
+
+// BeginLogin godoc
+// @Summary      Start passkey sign in
+// @Description  Return the assertion options for navigator.credentials.get. Leave the username empty to sign in with a discoverable passkey, an unknown username or one without passkeys gets the same discoverable options
+// @Tags         WebAuthn
+// @Accept       json
+// @Produce      json
+// @Param        body body dto.PasskeyLoginBeginReq false "Username"
+// @Success      200  {object}  protocol.CredentialAssertion
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/webauthn/login/begin [post]
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	var req dto.PasskeyLoginBeginReq
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResp{
+				Code: code.InvalidParameter,
+			})
+			return
+		}
+	}
+
+	assertion, ceremony, err := h.webAuthnService.BeginLogin(req.Username)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	setCeremony(c, ceremony)
+	c.JSON(http.StatusOK, assertion)
+}
+
+// FinishLogin godoc
+// @Summary      Finish passkey sign in
+// @Description  Verify the assertion returned by navigator.credentials.get and return a token
+// @Tags         WebAuthn
+// @Accept       json
+// @Produce      json
+// @Param        deviceName query string false "Device name recorded on the session"
+// @Param        body body object true "PublicKeyCredential from the browser"
+// @Success      200  {object}  dto.TokenResp
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/webauthn/login/finish [post]
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	ceremony := takeCeremony(c)
+
+	response, err := protocol.ParseCredentialRequestResponseBody(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	tokens, err := h.webAuthnService.FinishLogin(ceremony, response, clientInfo(c, c.Query("deviceName")))
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	writeTokens(c, tokens)
+}
+
+// List godoc
+// @Summary      List passkeys
+// @Description  List the passkeys registered by the current user
+// @Tags         WebAuthn
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   dto.PasskeyResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/webauthn/credentials [get]
+func (h *WebAuthnHandler) List(c *gin.Context) {
+	credentials, err := h.webAuthnService.List(middleware.MustCurrentUser(c).UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+			Code: code.ServerUnknownError,
+		})
+		return
+	}
+
+	resp := make([]dto.PasskeyResp, 0, len(credentials))
+	for _, credential := range credentials {
+		resp = append(resp, dto.PasskeyResp{
+			ID:         credential.ID,
+			Name:       credential.Name,
+			Synced:     credential.BackupState,
+			CreatedAt:  credential.CreatedAt,
+			LastUsedAt: credential.LastUsedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Rename godoc
+// @Summary      Rename passkey
+// @Description  Change the name of one of the current user's passkeys
+// @Tags         WebAuthn
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path int true "Passkey ID"
+// @Param        body body dto.RenamePasskeyReq true "New name"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/webauthn/credentials/{id} [patch]
+func (h *WebAuthnHandler) Rename(c *gin.Context) {
+	var req dto.RenamePasskeyReq
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	user := middleware.MustCurrentUser(c)
+	if err := h.webAuthnService.Rename(user.UserID, uint(id), req.Name); err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Delete godoc
+// @Summary      Delete passkey
+// @Description  Remove one of the current user's passkeys, it can no longer be used to sign in
+// @Tags         WebAuthn
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path int true "Passkey ID"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResp
+// @Failure      401  {object}  dto.ErrorResp
+// @Failure      404  {object}  dto.ErrorResp
+// @Failure      500  {object}  dto.ErrorResp
+// @Router       /auth/webauthn/credentials/{id} [delete]
+func (h *WebAuthnHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResp{
+			Code: code.InvalidParameter,
+		})
+		return
+	}
+
+	user := middleware.MustCurrentUser(c)
+	if err := h.webAuthnService.Delete(user.UserID, uint(id)); err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// setCeremony Keep the sealed ceremony state until the finish call
+func setCeremony(c *gin.Context, ceremony string) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(ceremonyCookie, ceremony, service.WebAuthnCeremonyTTL, ceremonyPath, "", true, true)
+}
+
+// takeCeremony Read the ceremony state and expire the cookie, it is single use either way
+func takeCeremony(c *gin.Context) string {
+	ceremony, _ := c.Cookie(ceremonyCookie)
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(ceremonyCookie, "", -1, ceremonyPath, "", true, true)
+	return ceremony
+}
+
+func writeWebAuthnError(c *gin.Context, err error) {
+	if writeRetryLater(c, err) {
+		return
+	}
+	if authErr, ok := err.(*service.AuthError); ok {
+		status := http.StatusBadRequest
+		if authErr.Code == code.PasskeyNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, dto.ErrorResp{
+			Code: authErr.Code,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, dto.ErrorResp{
+		Code: code.ServerUnknownError,
+	})
+}
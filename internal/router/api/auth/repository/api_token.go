@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+)
+
+// apiTokenTouchInterval Last use is recorded at most this often, so busy tokens do not write on every request
+const apiTokenTouchInterval = time.Minute
+
+type APITokenRepository interface {
+	Create(token *model.APIToken) error
+	FindByHash(hash string) (*model.APIToken, error)
+	FindByID(id uint) (*model.APIToken, error)
+	ListByUserIDs(userIDs []int64) ([]*model.APIToken, error)
+	Rotate(id uint, hash, hint string) error
+	Revoke(id uint) error
+	RevokeByUserID(userID int64) error
+	Touch(id uint, usedAt time.Time) error
+}
+
+type apiTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+func (r *apiTokenRepository) Create(token *model.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByHash Return an unrevoked, unexpired token
+func (r *apiTokenRepository) FindByHash(hash string) (*model.APIToken, error) {
+	var token model.APIToken
+	if err := r.db.First(&token, "token_hash = ?", hash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(strconv.Itoa(code.TokenInvalid))
+		}
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, errors.New(strconv.Itoa(code.TokenInvalid))
+	}
+	if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+		return nil, errors.New(strconv.Itoa(code.TokenExpired))
+	}
+	return &token, nil
+}
+
+// FindByID Return an unrevoked token
+func (r *apiTokenRepository) FindByID(id uint) (*model.APIToken, error) {
+	var token model.APIToken
+	if err := r.db.First(&token, "id = ? AND revoked_at IS NULL", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(strconv.Itoa(code.APITokenNotFound))
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListByUserIDs Unrevoked tokens acting as any of the users, newest first
+func (r *apiTokenRepository) ListByUserIDs(userIDs []int64) ([]*model.APIToken, error) {
+	var tokens []*model.APIToken
+	if err := r.db.Where("user_id IN ? AND revoked_at IS NULL", userIDs).
+		Order("id DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Rotate Replace the secret of a token, the old one stops working at once
+func (r *apiTokenRepository) Rotate(id uint, hash, hint string) error {
+	result := r.db.Model(&model.APIToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]any{"token_hash": hash, "hint": hint, "last_used_at": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.APITokenNotFound))
+	}
+	return nil
+}
+
+func (r *apiTokenRepository) Revoke(id uint) error {
+	result := r.db.Model(&model.APIToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.APITokenNotFound))
+	}
+	return nil
+}
+
+func (r *apiTokenRepository) RevokeByUserID(userID int64) error {
+	return r.db.Model(&model.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *apiTokenRepository) Touch(id uint, usedAt time.Time) error {
+	return r.db.Model(&model.APIToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, usedAt.Add(-apiTokenTouchInterval)).
+		Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InviteRepository interface {
+	Redeem(hash string) (*model.Invite, error)
+	Release(id uint) error
+}
+
+type inviteRepository struct {
+	db *gorm.DB
+}
+
+func NewInviteRepository(db *gorm.DB) InviteRepository {
+	return &inviteRepository{db: db}
+}
+
+// Redeem Take one use of a valid invite
+func (r *inviteRepository) Redeem(hash string) (*model.Invite, error) {
+	var invite model.Invite
+	// Conditional update so concurrent sign ups cannot exceed the maximum uses
+	result := r.db.Model(&invite).
+		Clauses(clause.Returning{}).
+		Where("code_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?) AND uses < max_uses", hash, time.Now()).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New(strconv.Itoa(code.InviteInvalid))
+	}
+	return &invite, nil
+}
+
+// Release Give back a use taken by a sign up that did not complete
+func (r *inviteRepository) Release(id uint) error {
+	return r.db.Model(&model.Invite{}).
+		Where("id = ? AND uses > 0", id).
+		Update("uses", gorm.Expr("uses - 1")).Error
+}
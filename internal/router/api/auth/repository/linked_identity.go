@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+)
+
+type LinkedIdentityRepository interface {
+	Create(identity *model.LinkedIdentity) error
+	FindByIssuerSubject(issuer, subject string) (*model.LinkedIdentity, error)
+	ListByUserID(userID int64) ([]*model.LinkedIdentity, error)
+	Delete(userID int64, id uint) error
+}
+
+type linkedIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewLinkedIdentityRepository(db *gorm.DB) LinkedIdentityRepository {
+	return &linkedIdentityRepository{db: db}
+}
+
+func (r *linkedIdentityRepository) Create(identity *model.LinkedIdentity) error {
+	if err := r.db.Create(identity).Error; err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return errors.New(strconv.Itoa(code.IdentityAlreadyLinked))
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *linkedIdentityRepository) FindByIssuerSubject(issuer, subject string) (*model.LinkedIdentity, error) {
+	var identity model.LinkedIdentity
+	if err := r.db.First(&identity, "issuer = ? AND subject = ?", issuer, subject).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(strconv.Itoa(code.IdentityNotFound))
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *linkedIdentityRepository) ListByUserID(userID int64) ([]*model.LinkedIdentity, error) {
+	var identities []*model.LinkedIdentity
+	if err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *linkedIdentityRepository) Delete(userID int64, id uint) error {
+	result := r.db.Unscoped().Where("id = ? AND user_id = ?", id, userID).Delete(&model.LinkedIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.IdentityNotFound))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+)
+
+type OneTimeTokenRepository interface {
+	Create(token *model.OneTimeToken) error
+	FindActive(purpose, hash string) (*model.OneTimeToken, error)
+	Consume(purpose, hash string) (*model.OneTimeToken, error)
+	InvalidateByUserID(userID int64, purpose string) error
+}
+
+type oneTimeTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewOneTimeTokenRepository(db *gorm.DB) OneTimeTokenRepository {
+	return &oneTimeTokenRepository{db: db}
+}
+
+func (r *oneTimeTokenRepository) Create(token *model.OneTimeToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindActive Return an unused, unexpired token without consuming it
+func (r *oneTimeTokenRepository) FindActive(purpose, hash string) (*model.OneTimeToken, error) {
+	var token model.OneTimeToken
+	if err := r.db.First(&token, "token_hash = ? AND purpose = ?", hash, purpose).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(strconv.Itoa(code.TokenInvalid))
+		}
+		return nil, err
+	}
+	if token.UsedAt != nil {
+		return nil, errors.New(strconv.Itoa(code.TokenInvalid))
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, errors.New(strconv.Itoa(code.TokenExpired))
+	}
+	return &token, nil
+}
+
+// Consume Mark an unused, unexpired token as used and return it
+func (r *oneTimeTokenRepository) Consume(purpose, hash string) (*model.OneTimeToken, error) {
+	var token model.OneTimeToken
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&token, "token_hash = ? AND purpose = ?", hash, purpose).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New(strconv.Itoa(code.TokenInvalid))
+			}
+			return err
+		}
+		if token.UsedAt != nil {
+			return errors.New(strconv.Itoa(code.TokenInvalid))
+		}
+		if time.Now().After(token.ExpiresAt) {
+			return errors.New(strconv.Itoa(code.TokenExpired))
+		}
+
+		// Conditional update so a concurrent consumer cannot use the token twice
+		now := time.Now()
+		result := tx.Model(&model.OneTimeToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(strconv.Itoa(code.TokenInvalid))
+		}
+		token.UsedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// InvalidateByUserID Burn every outstanding token of the purpose for the user
+func (r *oneTimeTokenRepository) InvalidateByUserID(userID int64, purpose string) error {
+	return r.db.Model(&model.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	Replace(userID int64, hashes []string) error
+	Consume(userID int64, hash string) (bool, error)
+	DeleteByUserID(userID int64) error
+}
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+// Replace Drop the user's codes and store a new set
+func (r *recoveryCodeRepository) Replace(userID int64, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]model.RecoveryCode, 0, len(hashes))
+		for _, hash := range hashes {
+			codes = append(codes, model.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Consume Mark a matching unused code as used, reporting whether one existed
+func (r *recoveryCodeRepository) Consume(userID int64, hash string) (bool, error) {
+	result := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *recoveryCodeRepository) DeleteByUserID(userID int64) error {
+	return r.db.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(token *model.RefreshToken) error
+	FindByHash(hash string) (*model.RefreshToken, error)
+	MarkUsed(id uint) (bool, error)
+	RevokeFamily(familyID int64) error
+	RevokeFamilies(familyIDs []int64) error
+	RevokeByUserID(userID int64) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(token *model.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *refreshTokenRepository) FindByHash(hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := r.db.First(&token, "token_hash = ?", hash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(strconv.Itoa(code.TokenInvalid))
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed Atomically flag a token as rotated, reporting false if it was already used
+func (r *refreshTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(familyID int64) error {
+	return r.db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepository) RevokeFamilies(familyIDs []int64) error {
+	if len(familyIDs) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.RefreshToken{}).
+		Where("family_id IN ? AND revoked_at IS NULL", familyIDs).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepository) RevokeByUserID(userID int64) error {
+	return r.db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(session *model.Session) error
+	FindBySessionID(sessionID int64) (*model.Session, error)
+	ListActiveByUserID(userID int64) ([]*model.Session, error)
+	Rename(userID, sessionID int64, deviceName string) error
+	Touch(sessionID int64, seenAt time.Time) error
+	Revoke(sessionID int64) error
+	RevokeByUserID(userID int64, exceptSessionID int64) ([]int64, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *model.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) FindBySessionID(sessionID int64) (*model.Session, error) {
+	var session model.Session
+	if err := r.db.First(&session, "session_id = ?", sessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(strconv.Itoa(code.SessionNotFound))
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) ListActiveByUserID(userID int64) ([]*model.Session, error) {
+	var sessions []*model.Session
+	if err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) Rename(userID, sessionID int64, deviceName string) error {
+	result := r.db.Model(&model.Session{}).
+		Where("session_id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("device_name", deviceName)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.SessionNotFound))
+	}
+	return nil
+}
+
+func (r *sessionRepository) Touch(sessionID int64, seenAt time.Time) error {
+	return r.db.Model(&model.Session{}).
+		Where("session_id = ?", sessionID).
+		Update("last_seen_at", seenAt).Error
+}
+
+func (r *sessionRepository) Revoke(sessionID int64) error {
+	return r.db.Model(&model.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeByUserID Revoke every active session of the user except one, returning the revoked IDs
+func (r *sessionRepository) RevokeByUserID(userID int64, exceptSessionID int64) ([]int64, error) {
+	var sessionIDs []int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Session{}).
+			Where("user_id = ? AND session_id != ? AND revoked_at IS NULL", userID, exceptSessionID).
+			Pluck("session_id", &sessionIDs).Error; err != nil {
+			return err
+		}
+		if len(sessionIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&model.Session{}).
+			Where("session_id IN ?", sessionIDs).
+			Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessionIDs, nil
+}
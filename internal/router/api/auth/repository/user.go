@@ -4,9 +4,12 @@ import (
 	"errors"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AurChatOrg/aurchat-server/internal/code"
 	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/normalize"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/validation"
 	"gorm.io/gorm"
 )
 
@@ -20,6 +23,7 @@ type UserRepository interface {
 	FindByID(id int64) (*model.User, error)
 	FindByUsername(username string) (*model.User, error)
 	FindByEmail(email string) (*model.User, error)
+	FindByIdentifier(identifier string) (*model.User, error)
 	Create(user *model.User) error
 	Update(user *model.User) error
 	ExistsByUsernameOrEmail(username, email string) (bool, error)
@@ -27,6 +31,15 @@ type UserRepository interface {
 	List(offset, limit int) ([]*model.User, error)
 	Count() (int64, error)
 	CheckUnique(username, email string) (bool, string, error)
+	MarkEmailVerified(id int64, at time.Time) error
+	UpdatePassword(id int64, hash string) error
+	UpdateTOTP(id int64, secret string, enabledAt *time.Time) error
+	AdvanceTOTPStep(id int64, step int64) (bool, error)
+	ListBotsByOwner(ownerID int64) ([]*model.User, error)
+	Search(condition string, args []any, offset, limit int) ([]*model.User, int64, error)
+	SetDisabled(id int64, at *time.Time) error
+	UpdateRole(id int64, role string) error
+	UpdateEmail(id int64, email string) error
 }
 
 type userRepository struct {
@@ -39,7 +52,8 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 
 func (r *userRepository) CheckUnique(username, email string) (bool, string, error) {
 	var existingUsers []model.User
-	if err := r.db.Where("username = ? OR email = ?", username, email).
+	username, email = normalize.Identifier(username), normalize.Identifier(email)
+	if err := r.db.Where("lower(username) = lower(?) OR lower(email) = lower(?) OR username_skeleton = ?", username, email, validation.Skeleton(username)).
 		Find(&existingUsers).Error; err != nil {
 		return false, "", err
 	}
@@ -49,18 +63,22 @@ func (r *userRepository) CheckUnique(username, email string) (bool, string, erro
 	}
 
 	for _, user := range existingUsers {
-		if user.Username == username {
+		if strings.EqualFold(user.Username, username) {
 			return false, "username", errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed))
 		}
-		if user.Email == email {
+		if strings.EqualFold(user.Email, email) {
 			return false, "email", errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed))
 		}
+		if user.UsernameSkeleton == validation.Skeleton(username) {
+			return false, "username", errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed))
+		}
 	}
 
 	return false, "unknown", errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed))
 }
 
 func (r *userRepository) Create(user *model.User) error {
+	user.UsernameSkeleton = validation.Skeleton(user.Username)
 	unique, conflictField, err := r.CheckUnique(user.Username, user.Email)
 	if err != nil {
 		return err
@@ -87,8 +105,9 @@ func (r *userRepository) handleCreateError(err error) error {
 		return nil
 	}
 
+	// A concurrent sign up won the unique index
 	if strings.Contains(err.Error(), "23505") {
-		return errors.New(strconv.Itoa(code.DatabaseError))
+		return errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed))
 	}
 
 	return err
@@ -96,7 +115,7 @@ func (r *userRepository) handleCreateError(err error) error {
 
 func (r *userRepository) FindByUsername(username string) (*model.User, error) {
 	var user model.User
-	if err := r.db.First(&user, "username = ?", username).Error; err != nil {
+	if err := r.db.First(&user, "lower(username) = lower(?)", normalize.Identifier(username)).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New(strconv.Itoa(code.UserNotFound))
 		}
@@ -107,7 +126,7 @@ func (r *userRepository) FindByUsername(username string) (*model.User, error) {
 
 func (r *userRepository) FindByEmail(email string) (*model.User, error) {
 	var user model.User
-	if err := r.db.First(&user, "email = ?", email).Error; err != nil {
+	if err := r.db.First(&user, "lower(email) = lower(?)", normalize.Identifier(email)).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New(strconv.Itoa(code.UserNotFound))
 		}
@@ -116,6 +135,14 @@ func (r *userRepository) FindByEmail(email string) (*model.User, error) {
 	return &user, nil
 }
 
+// FindByIdentifier Find a user by email when the identifier looks like one, by username otherwise
+func (r *userRepository) FindByIdentifier(identifier string) (*model.User, error) {
+	if normalize.IsEmail(identifier) {
+		return r.FindByEmail(identifier)
+	}
+	return r.FindByUsername(identifier)
+}
+
 func (r *userRepository) FindByID(id int64) (*model.User, error) {
 	var user model.User
 	if err := r.db.First(&user, "user_id = ?", id).Error; err != nil {
@@ -128,25 +155,31 @@ func (r *userRepository) FindByID(id int64) (*model.User, error) {
 }
 
 func (r *userRepository) Update(user *model.User) error {
+	user.UsernameSkeleton = validation.Skeleton(user.Username)
 	if user.Username == "" && user.Email == "" {
 		return r.db.Save(user).Error
 	}
 
 	var existingUser model.User
-	query := r.db.Where("user_id != ?", user.UserID)
-
+	var (
+		conflicts []string
+		args      []any
+	)
 	if user.Username != "" {
-		query = query.Or("username = ?", user.Username)
+		conflicts = append(conflicts, "lower(username) = lower(?)", "username_skeleton = ?")
+		args = append(args, user.Username, user.UsernameSkeleton)
 	}
 	if user.Email != "" {
-		query = query.Or("email = ?", user.Email)
+		conflicts = append(conflicts, "lower(email) = lower(?)")
+		args = append(args, user.Email)
 	}
+	query := r.db.Where("user_id != ?", user.UserID).Where("("+strings.Join(conflicts, " OR ")+")", args...)
 
 	if err := query.First(&existingUser).Error; err == nil {
-		if existingUser.Username == user.Username {
+		if strings.EqualFold(existingUser.Username, user.Username) || existingUser.UsernameSkeleton == user.UsernameSkeleton {
 			return errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed))
 		}
-		if existingUser.Email == user.Email {
+		if strings.EqualFold(existingUser.Email, user.Email) {
 			return errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed))
 		}
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -159,7 +192,7 @@ func (r *userRepository) Update(user *model.User) error {
 func (r *userRepository) ExistsByUsernameOrEmail(username, email string) (bool, error) {
 	var count int64
 	if err := r.db.Model(&model.User{}).
-		Where("username = ? OR email = ?", username, email).
+		Where("lower(username) = lower(?) OR lower(email) = lower(?)", normalize.Identifier(username), normalize.Identifier(email)).
 		Count(&count).Error; err != nil {
 		return false, err
 	}
@@ -192,3 +225,142 @@ func (r *userRepository) Count() (int64, error) {
 	}
 	return count, nil
 }
+
+func (r *userRepository) MarkEmailVerified(id int64, at time.Time) error {
+	result := r.db.Model(&model.User{}).
+		Where("user_id = ?", id).
+		Update("email_verified_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.UserNotFound))
+	}
+	return nil
+}
+
+func (r *userRepository) UpdatePassword(id int64, hash string) error {
+	result := r.db.Model(&model.User{}).
+		Where("user_id = ?", id).
+		Update("password", hash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.UserNotFound))
+	}
+	return nil
+}
+
+// UpdateTOTP Replace the TOTP secret and activation time, resetting the replay guard
+func (r *userRepository) UpdateTOTP(id int64, secret string, enabledAt *time.Time) error {
+	result := r.db.Model(&model.User{}).
+		Where("user_id = ?", id).
+		Updates(map[string]any{
+			"totp_secret":     secret,
+			"totp_enabled_at": enabledAt,
+			"totp_last_step":  0,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.UserNotFound))
+	}
+	return nil
+}
+
+// AdvanceTOTPStep Record an accepted time step, reporting false if it was already used
+func (r *userRepository) AdvanceTOTPStep(id int64, step int64) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("user_id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListBotsByOwner Bots managed by the user, oldest first
+func (r *userRepository) ListBotsByOwner(ownerID int64) ([]*model.User, error) {
+	var bots []*model.User
+	if err := r.db.Where("bot AND owner_id = ?", ownerID).Order("id").Find(&bots).Error; err != nil {
+		return nil, err
+	}
+	return bots, nil
+}
+
+// Search Human accounts matching a SQL condition, oldest first, with the total number of matches.
+// An empty condition matches every account.
+func (r *userRepository) Search(condition string, args []any, offset, limit int) ([]*model.User, int64, error) {
+	query := r.db.Model(&model.User{}).Where("NOT bot")
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+	// Shared by the count and the page
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []*model.User
+	if err := query.Order("id").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// SetDisabled Disable the account from the given time, or enable it again with nil
+func (r *userRepository) SetDisabled(id int64, at *time.Time) error {
+	result := r.db.Model(&model.User{}).
+		Where("user_id = ?", id).
+		Update("disabled_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.UserNotFound))
+	}
+	return nil
+}
+
+// UpdateRole Write only the role, leaving columns changed since the user was loaded alone
+func (r *userRepository) UpdateRole(id int64, role string) error {
+	result := r.db.Model(&model.User{}).
+		Where("user_id = ?", id).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.UserNotFound))
+	}
+	return nil
+}
+
+// UpdateEmail Write only the email, refusing one another account already uses
+func (r *userRepository) UpdateEmail(id int64, email string) error {
+	var taken int64
+	if err := r.db.Model(&model.User{}).
+		Where("user_id != ? AND lower(email) = lower(?)", id, email).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed))
+	}
+
+	result := r.db.Model(&model.User{}).
+		Where("user_id = ?", id).
+		Update("email", email)
+	if result.Error != nil {
+		// A concurrent write won the unique index
+		return r.handleCreateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.UserNotFound))
+	}
+	return nil
+}
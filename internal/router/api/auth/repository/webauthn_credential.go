@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+)
+
+type WebAuthnCredentialRepository interface {
+	Create(credential *model.WebAuthnCredential) error
+	ListByUserID(userID int64) ([]*model.WebAuthnCredential, error)
+	RecordUse(id uint, signCount uint32, backupState bool, usedAt time.Time) error
+	Rename(userID int64, id uint, name string) error
+	Delete(userID int64, id uint) error
+}
+
+type webAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnCredentialRepository(db *gorm.DB) WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{db: db}
+}
+
+func (r *webAuthnCredentialRepository) Create(credential *model.WebAuthnCredential) error {
+	return r.db.Create(credential).Error
+}
+
+func (r *webAuthnCredentialRepository) ListByUserID(userID int64) ([]*model.WebAuthnCredential, error) {
+	var credentials []*model.WebAuthnCredential
+	if err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// RecordUse Store the counter and backup state seen on a successful sign in
+func (r *webAuthnCredentialRepository) RecordUse(id uint, signCount uint32, backupState bool, usedAt time.Time) error {
+	return r.db.Model(&model.WebAuthnCredential{}).Where("id = ?", id).Updates(map[string]any{
+		"sign_count":   signCount,
+		"backup_state": backupState,
+		"last_used_at": usedAt,
+	}).Error
+}
+
+func (r *webAuthnCredentialRepository) Rename(userID int64, id uint, name string) error {
+	result := r.db.Model(&model.WebAuthnCredential{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("name", name)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.PasskeyNotFound))
+	}
+	return nil
+}
+
+func (r *webAuthnCredentialRepository) Delete(userID int64, id uint) error {
+	result := r.db.Unscoped().Where("id = ? AND user_id = ?", id, userID).Delete(&model.WebAuthnCredential{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.PasskeyNotFound))
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"go.uber.org/zap"
+)
+
+// APITokenScopes Scopes an API token can be granted
+var APITokenScopes = []string{
+	model.ScopeProfileRead,
+	model.ScopeRoomsRead,
+	model.ScopeRoomsWrite,
+	model.ScopeMessagesRead,
+	model.ScopeMessagesWrite,
+	model.ScopeFilesRead,
+	model.ScopeFilesWrite,
+}
+
+var ErrAPITokenNotFound = NewAuthError(
+	code.APITokenNotFound,
+	code.GetMessage(code.APITokenNotFound),
+	nil,
+)
+
+type APITokenService interface {
+	// Create Issue a token acting as the owner, or as one of their bots when botID is set
+	Create(owner *model.User, botID int64, name string, scopes []string, expiresIn uint32) (*model.APIToken, string, error)
+	// List Tokens of the owner and of their bots
+	List(owner *model.User) ([]*model.APIToken, error)
+	Rotate(owner *model.User, id uint) (*model.APIToken, string, error)
+	Revoke(owner *model.User, id uint) error
+}
+
+type apiTokenService struct {
+	apiTokenRepo repository.APITokenRepository
+	botService   BotService
+}
+
+func NewAPITokenService(apiTokenRepo repository.APITokenRepository, botService BotService) APITokenService {
+	return &apiTokenService{apiTokenRepo: apiTokenRepo, botService: botService}
+}
+
+func (s *apiTokenService) Create(owner *model.User, botID int64, name string, scopes []string, expiresIn uint32) (*model.APIToken, string, error) {
+	userID := owner.UserID
+	if botID != 0 {
+		bot, err := s.botService.FindOwned(owner, botID)
+		if err != nil {
+			return nil, "", err
+		}
+		userID = bot.UserID
+	}
+
+	raw, err := token.NewAPIToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiToken := &model.APIToken{
+		UserID:    userID,
+		CreatedBy: owner.UserID,
+		Name:      name,
+		Hint:      token.APITokenHint(raw),
+		TokenHash: token.HashOpaque(raw),
+		Scopes:    strings.Join(slices.Compact(slices.Sorted(slices.Values(scopes))), ","),
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(expiresIn) * time.Second)
+		apiToken.ExpiresAt = &expiresAt
+	}
+
+	if err = s.apiTokenRepo.Create(apiToken); err != nil {
+		return nil, "", err
+	}
+	return apiToken, raw, nil
+}
+
+func (s *apiTokenService) List(owner *model.User) ([]*model.APIToken, error) {
+	bots, err := s.botService.List(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := []int64{owner.UserID}
+	for _, bot := range bots {
+		userIDs = append(userIDs, bot.UserID)
+	}
+	return s.apiTokenRepo.ListByUserIDs(userIDs)
+}
+
+// Rotate Replace the secret of a token, keeping its name, scopes and expiry
+func (s *apiTokenService) Rotate(owner *model.User, id uint) (*model.APIToken, string, error) {
+	apiToken, err := s.findOwned(owner, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	raw, err := token.NewAPIToken()
+	if err != nil {
+		return nil, "", err
+	}
+	if err = s.apiTokenRepo.Rotate(id, token.HashOpaque(raw), token.APITokenHint(raw)); err != nil {
+		return nil, "", mapAPITokenError(err)
+	}
+
+	apiToken.Hint = token.APITokenHint(raw)
+	apiToken.LastUsedAt = nil
+	return apiToken, raw, nil
+}
+
+func (s *apiTokenService) Revoke(owner *model.User, id uint) error {
+	if _, err := s.findOwned(owner, id); err != nil {
+		return err
+	}
+	return mapAPITokenError(s.apiTokenRepo.Revoke(id))
+}
+
+// findOwned Return a token acting as the owner or one of their bots
+func (s *apiTokenService) findOwned(owner *model.User, id uint) (*model.APIToken, error) {
+	apiToken, err := s.apiTokenRepo.FindByID(id)
+	if err != nil {
+		return nil, mapAPITokenError(err)
+	}
+	if apiToken.UserID != owner.UserID {
+		if _, err = s.botService.FindOwned(owner, apiToken.UserID); err != nil {
+			if err == ErrBotNotFound {
+				return nil, ErrAPITokenNotFound
+			}
+			return nil, err
+		}
+	}
+	return apiToken, nil
+}
+
+func mapAPITokenError(err error) error {
+	if err != nil && err.Error() == strconv.Itoa(code.APITokenNotFound) {
+		return ErrAPITokenNotFound
+	}
+	return err
+}
+
+// validateAPIToken Resolve an API token into its user and claims, scoped to what the token was granted
+func (s *authService) validateAPIToken(raw string) (*model.User, *token.UserClaims, error) {
+	apiToken, err := s.apiTokenRepo.FindByHash(token.HashOpaque(raw))
+	if err != nil {
+		switch err.Error() {
+		case strconv.Itoa(code.TokenInvalid):
+			return nil, nil, ErrTokenInvalid
+		case strconv.Itoa(code.TokenExpired):
+			return nil, nil, ErrTokenExpired
+		}
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.FindByID(apiToken.UserID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nil, nil, ErrTokenInvalid
+		}
+		return nil, nil, err
+	}
+	if err = s.checkEnabled(user); err != nil {
+		return nil, nil, err
+	}
+
+	// Avoid a write on every request, like session last seen
+	if now := time.Now(); apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) > lastSeenInterval {
+		if err = s.apiTokenRepo.Touch(apiToken.ID, now); err != nil {
+			logger.Logger.Warn("Error recording API token use", zap.Uint("tokenID", apiToken.ID), zap.Error(err))
+		}
+	}
+
+	return user, &token.UserClaims{
+		Username:   user.Username,
+		UserID:     user.UserID,
+		Scopes:     strings.Split(apiToken.Scopes, ","),
+		APITokenID: apiToken.ID,
+		IssuedAt:   apiToken.CreatedAt,
+	}, nil
+}
+
+// checkEnabled Refuse a disabled account, and a bot whose owner is disabled
+func (s *authService) checkEnabled(user *model.User) error {
+	if user.DisabledAt != nil {
+		return ErrAccountDisabled
+	}
+	if !user.Bot || user.OwnerID == nil {
+		return nil
+	}
+
+	owner, err := s.userRepo.FindByID(*user.OwnerID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return ErrTokenInvalid
+		}
+		return err
+	}
+	if owner.DisabledAt != nil {
+		return ErrAccountDisabled
+	}
+	return nil
+}
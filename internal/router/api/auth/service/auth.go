@@ -1,12 +1,24 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
 	"github.com/AurChatOrg/aurchat-server/internal/code"
 	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/challenge"
 	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/normalize"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/policy"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/throttle"
 	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/validation"
 	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
 	"github.com/bwmarrin/snowflake"
+	"go.uber.org/zap"
 )
 
 var (
@@ -21,77 +33,676 @@ var (
 		code.GetMessage(code.ServerUnknownError),
 		nil,
 	)
+
+	ErrTokenExpired = NewAuthError(
+		code.TokenExpired,
+		code.GetMessage(code.TokenExpired),
+		nil,
+	)
+
+	ErrTokenInvalid = NewAuthError(
+		code.TokenInvalid,
+		code.GetMessage(code.TokenInvalid),
+		nil,
+	)
+
+	ErrRefreshTokenReused = NewAuthError(
+		code.RefreshTokenReused,
+		code.GetMessage(code.RefreshTokenReused),
+		nil,
+	)
+
+	ErrInvalidUsernameFormat = NewAuthError(
+		code.InvalidUsernameFormat,
+		code.GetMessage(code.InvalidUsernameFormat),
+		nil,
+	)
+
+	ErrUsernameReserved = NewAuthError(
+		code.UsernameReserved,
+		code.GetMessage(code.UsernameReserved),
+		nil,
+	)
+
+	ErrInvalidEmailFormat = NewAuthError(
+		code.InvalidEmailFormat,
+		code.GetMessage(code.InvalidEmailFormat),
+		nil,
+	)
+
+	ErrPasswordRequired = NewAuthError(
+		code.PasswordRequired,
+		code.GetMessage(code.PasswordRequired),
+		nil,
+	)
+
+	ErrRegistrationClosed = NewAuthError(
+		code.RegistrationClosed,
+		code.GetMessage(code.RegistrationClosed),
+		nil,
+	)
+
+	ErrInviteInvalid = NewAuthError(
+		code.InviteInvalid,
+		code.GetMessage(code.InviteInvalid),
+		nil,
+	)
+
+	ErrAccountDisabled = NewAuthError(
+		code.AccountDisabled,
+		code.GetMessage(code.AccountDisabled),
+		nil,
+	)
+
+	ErrPasswordManagedByDirectory = NewAuthError(
+		code.PasswordManagedByDirectory,
+		code.GetMessage(code.PasswordManagedByDirectory),
+		nil,
+	)
+)
+
+// Registration modes
+const (
+	RegistrationOpen   = "open"
+	RegistrationInvite = "invite" // Sign up needs an invite code
+	RegistrationClosed = "closed" // Accounts are only created by administrators or provisioning
 )
 
+// ClientInfo Device details recorded on the session created at sign in
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+	Challenge  string // Answer to an anti-bot challenge, empty when none was solved
+}
+
+// SignInResult Either a token pair, or a ticket to exchange at the two-factor step
+type SignInResult struct {
+	Tokens             *TokenPair
+	MFATicket          string
+	MFATicketExpiresIn uint32
+}
+
+// TokenPair Access token plus the refresh token that renews it
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	ExpiresIn        uint32 // Access token lifetime in seconds
+	RefreshExpiresIn uint32 // Refresh token lifetime in seconds
+}
+
 type AuthService interface {
-	SignIn(username, password string) (string, error)
-	SignUp(username, password, email string) (string, error)
-	ValidateToken(token string) (*model.User, error)
+	SignIn(ctx context.Context, identifier, password string, client ClientInfo) (*SignInResult, error)
+	VerifyMFA(ticket, passcode string, client ClientInfo) (*TokenPair, error)
+	SignUp(ctx context.Context, username, password, email, inviteCode string, client ClientInfo) (*TokenPair, error)
+	RegistrationOpen() bool
+	IssueSession(user *model.User, client ClientInfo) (*TokenPair, error)
+	FinishSignIn(user *model.User, client ClientInfo) (*SignInResult, error)
+	Refresh(refreshToken string) (*TokenPair, error)
+	SignOut(claims *token.UserClaims) error
+	SignOutAll(userID int64) error
+	ValidateToken(token string) (*model.User, *token.UserClaims, error)
+	PublicKeys() []token.PublicKey
 }
 
 type authService struct {
-	userRepo    repository.UserRepository
-	hasher      *hasher.Hasher
-	tokenGen    *token.Token
-	idGenerator *snowflake.Node
-}
-
-func NewAuthService(
-	userRepo repository.UserRepository,
-	hasher *hasher.Hasher,
-	tokenGen *token.Token,
-	node *snowflake.Node,
-) AuthService {
+	userRepo     repository.UserRepository
+	refreshRepo  repository.RefreshTokenRepository
+	sessionRepo  repository.SessionRepository
+	recoveryRepo repository.RecoveryCodeRepository
+	inviteRepo   repository.InviteRepository
+	apiTokenRepo repository.APITokenRepository
+	hasher       *hasher.Hasher
+	policy       *policy.PasswordPolicy
+	tokenGen     token.Token
+	ticketGen    token.Token
+	idGenerator  *snowflake.Node
+	refreshTTL   uint32
+
+	verification         VerificationService
+	requireVerifiedEmail bool
+	registration         string
+	passwordless         bool // Accounts may be created without a password
+	challenges           ChallengeService
+
+	userLimiter *throttle.Limiter
+	ipLimiter   *throttle.Limiter
+
+	authenticators []Authenticator // Tried in order until one accepts the password
+}
+
+// AuthDeps Dependencies of the auth service, named so the many repositories of one kind cannot be swapped
+type AuthDeps struct {
+	UserRepo     repository.UserRepository
+	RefreshRepo  repository.RefreshTokenRepository
+	SessionRepo  repository.SessionRepository
+	RecoveryRepo repository.RecoveryCodeRepository
+	InviteRepo   repository.InviteRepository
+	APITokenRepo repository.APITokenRepository
+	Hasher       *hasher.Hasher
+	Policy       *policy.PasswordPolicy
+	TokenGen     token.Token
+	TicketGen    token.Token
+	IDGenerator  *snowflake.Node
+	RefreshTTL   uint32
+
+	Verification         VerificationService
+	RequireVerifiedEmail bool
+	Registration         string
+	Passwordless         bool // Accounts may be created without a password
+	Challenges           ChallengeService
+
+	UserLimiter *throttle.Limiter
+	IPLimiter   *throttle.Limiter
+
+	Authenticators []Authenticator // Tried in order until one accepts the password
+}
+
+func NewAuthService(deps AuthDeps) AuthService {
 	return &authService{
-		userRepo:    userRepo,
-		hasher:      hasher,
-		tokenGen:    tokenGen,
-		idGenerator: node,
+		userRepo:     deps.UserRepo,
+		refreshRepo:  deps.RefreshRepo,
+		sessionRepo:  deps.SessionRepo,
+		recoveryRepo: deps.RecoveryRepo,
+		inviteRepo:   deps.InviteRepo,
+		apiTokenRepo: deps.APITokenRepo,
+		hasher:       deps.Hasher,
+		policy:       deps.Policy,
+		tokenGen:     deps.TokenGen,
+		ticketGen:    deps.TicketGen,
+		idGenerator:  deps.IDGenerator,
+		refreshTTL:   deps.RefreshTTL,
+
+		verification:         deps.Verification,
+		requireVerifiedEmail: deps.RequireVerifiedEmail,
+		registration:         deps.Registration,
+		passwordless:         deps.Passwordless,
+		challenges:           deps.Challenges,
+
+		userLimiter: deps.UserLimiter,
+		ipLimiter:   deps.IPLimiter,
+
+		authenticators: deps.Authenticators,
 	}
 }
 
-func (s *authService) SignIn(username, password string) (string, error) {
-	user, err := s.userRepo.FindByUsername(username)
+// SignIn Check the password of the account named by a username or an email against each
+// authenticator in turn, throttled per account and per client IP. Both kinds of identifier fail the same way.
+func (s *authService) SignIn(ctx context.Context, identifier, password string, client ClientInfo) (*SignInResult, error) {
+	user, err := s.userRepo.FindByIdentifier(identifier)
+	if err != nil && err.Error() != strconv.Itoa(code.UserNotFound) {
+		return nil, err
+	}
+
+	// Failures count against the account whichever identifier named it
+	userKey := normalize.Fold(identifier)
+	if user != nil {
+		userKey = normalize.Fold(user.Username)
+	}
+	if err := s.checkThrottle(userKey, client.IP); err != nil {
+		return nil, err
+	}
+	if err := s.challenges.CheckSignIn(ctx, userKey, client.Challenge, client.IP); err != nil {
+		return nil, err
+	}
+
+	accepted, err := authenticate(ctx, s.authenticators, identifier, password, user)
+	if errors.Is(err, ErrCredentialsRejected) {
+		return nil, s.recordFailure(userKey, client.IP, ErrAccountnameOrPassword)
+	}
 	if err != nil {
-		return "", ErrAccountnameOrPassword
+		return nil, err
+	}
+
+	// Failures are only forgiven once a session starts, not while the second factor is pending
+	result, err := s.FinishSignIn(accepted, client)
+	if err == nil && result.Tokens != nil {
+		s.resetThrottle(userKey)
+	}
+	return result, err
+}
+
+// FinishSignIn Issue a token pair to a user who proved their first factor,
+// or a ticket for the two-factor step when they enabled one
+func (s *authService) FinishSignIn(user *model.User, client ClientInfo) (*SignInResult, error) {
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	if s.requireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
 	}
 
-	if match, err := s.hasher.VerifyHash(password, user.Password); err != nil || !match {
-		if !match && err == nil {
-			return "", ErrAccountnameOrPassword
-		} else {
-			return "", ErrServerUnknown
+	if user.TOTPEnabledAt != nil {
+		ticket, err := s.ticketGen.Generate(user.Username, user.UserID, 0)
+		if err != nil {
+			return nil, err
 		}
+		return &SignInResult{MFATicket: ticket, MFATicketExpiresIn: s.ticketGen.TTL()}, nil
 	}
 
-	return s.tokenGen.Generate(user.Username, user.UserID)
+	tokens, err := s.startSession(user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &SignInResult{Tokens: tokens}, nil
 }
 
-func (s *authService) SignUp(username, password, email string) (string, error) {
+// VerifyMFA Exchange a sign in ticket and a second factor for a token pair.
+// Tickets are single use, a wrong code burns the ticket and restarts sign in.
+// Wrong codes count against the same account and IP limits as wrong passwords.
+func (s *authService) VerifyMFA(ticket, passcode string, client ClientInfo) (*TokenPair, error) {
+	claims, err := s.ticketGen.Parse(ticket)
+	if err != nil {
+		if errors.Is(err, token.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		if errors.Is(err, token.ErrRevocation) {
+			return nil, err
+		}
+		return nil, ErrTokenInvalid
+	}
+
+	userKey := normalize.Fold(claims.Username)
+	if err = s.checkThrottle(userKey, client.IP); err != nil {
+		return nil, err
+	}
+	first, err := s.ticketGen.RevokeOnce(&claims)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		return nil, ErrTokenInvalid
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	if err = verifySecondFactor(s.userRepo, s.recoveryRepo, user, passcode); err != nil {
+		if errors.Is(err, ErrMFACodeInvalid) {
+			return nil, s.recordFailure(userKey, client.IP, err)
+		}
+		return nil, err
+	}
+
+	tokens, err := s.startSession(user, client)
+	if err != nil {
+		return nil, err
+	}
+	s.resetThrottle(userKey)
+	return tokens, nil
+}
+
+func (s *authService) SignUp(ctx context.Context, username, password, email, inviteCode string, client ClientInfo) (*TokenPair, error) {
+	if s.registration == RegistrationClosed {
+		return nil, ErrRegistrationClosed
+	}
+	if s.registration == RegistrationInvite && inviteCode == "" {
+		return nil, ErrInviteInvalid
+	}
+	if err := s.challenges.Check(ctx, challenge.EndpointSignUp, client.Challenge, client.IP); err != nil {
+		return nil, err
+	}
+
+	username, email, err := validateAccount(username, email)
+	if err != nil {
+		return nil, err
+	}
+
+	// Passwordless accounts sign in through emailed links only
+	var hash string
+	if password != "" {
+		if err = checkPasswordPolicy(s.policy, password, username, email); err != nil {
+			return nil, err
+		}
+		if hash, err = s.hasher.Hash(ctx, password); err != nil {
+			return nil, err
+		}
+	} else if !s.passwordless {
+		return nil, ErrPasswordRequired
+	}
+
 	user := &model.User{
 		UserID:   s.idGenerator.Generate().Int64(),
 		Username: username,
 		Email:    email,
-		Password: s.hasher.Hash(password),
+		Password: hash,
+	}
+
+	// Open registration still records a valid invite, for tracking where accounts came from
+	if inviteCode != "" {
+		invite, err := s.inviteRepo.Redeem(token.HashOpaque(inviteCode))
+		if err != nil {
+			if err.Error() == strconv.Itoa(code.InviteInvalid) {
+				return nil, ErrInviteInvalid
+			}
+			return nil, err
+		}
+		user.InviteID = &invite.ID
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
-		return "", err
+		if user.InviteID != nil {
+			if releaseErr := s.inviteRepo.Release(*user.InviteID); releaseErr != nil {
+				logger.Logger.Warn("Error releasing invite use", zap.Uint("inviteID", *user.InviteID), zap.Error(releaseErr))
+			}
+		}
+		return nil, err
 	}
 
-	return s.tokenGen.Generate(user.Username, user.UserID)
+	// Sent in the background like a resend, so a slow mail server does not hold up sign up.
+	// A failed delivery can be retried through the resend endpoint.
+	go func() {
+		if err := s.verification.SendVerification(user); err != nil {
+			logger.Logger.Warn("Error sending verification email", zap.Int64("userID", user.UserID), zap.Error(err))
+		}
+	}()
+
+	return s.startSession(user, client)
 }
 
-func (s *authService) ValidateToken(tokenStr string) (*model.User, error) {
-	claims, err := s.tokenGen.Parse(tokenStr)
+// RegistrationOpen Report whether accounts may be created without an invite, such as on first external sign in
+func (s *authService) RegistrationOpen() bool {
+	return s.registration == RegistrationOpen
+}
+
+// IssueSession Start a session for a user authenticated by another method, such as a passkey
+func (s *authService) IssueSession(user *model.User, client ClientInfo) (*TokenPair, error) {
+	if s.requireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+	return s.startSession(user, client)
+}
+
+// Refresh Rotate a refresh token and issue a new token pair.
+// Presenting an already rotated token revokes its whole family.
+func (s *authService) Refresh(refreshToken string) (*TokenPair, error) {
+	stored, err := s.refreshRepo.FindByHash(token.HashOpaque(refreshToken))
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.TokenInvalid) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, ErrTokenInvalid
+	}
+	if stored.UsedAt != nil {
+		return nil, s.revokeReusedFamily(stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	session, err := s.sessionRepo.FindBySessionID(stored.FamilyID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.SessionNotFound) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+	if session.RevokedAt != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	// Guard against two concurrent rotations of the same token
+	rotated, err := s.refreshRepo.MarkUsed(stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, s.revokeReusedFamily(stored)
+	}
+
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	if err = s.sessionRepo.Touch(session.SessionID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, session.SessionID)
+}
+
+// SignOut Revoke the presented access token and end its session
+func (s *authService) SignOut(claims *token.UserClaims) error {
+	if err := s.tokenGen.Revoke(claims); err != nil {
+		return err
+	}
+
+	if claims.SessionID == 0 {
+		return nil
+	}
+	return revokeSession(s.sessionRepo, s.refreshRepo, claims.SessionID)
+}
+
+// SignOutAll Invalidate every token issued to the user so far, including the API tokens
+// of the user and their bots, which someone with access to the account could have minted
+func (s *authService) SignOutAll(userID int64) error {
+	if err := s.tokenGen.RevokeUserBefore(userID, time.Now()); err != nil {
+		return err
+	}
+	if _, err := s.sessionRepo.RevokeByUserID(userID, 0); err != nil {
+		return err
+	}
+	if err := s.refreshRepo.RevokeByUserID(userID); err != nil {
+		return err
+	}
+
+	if err := s.apiTokenRepo.RevokeByUserID(userID); err != nil {
+		return err
+	}
+	bots, err := s.userRepo.ListBotsByOwner(userID)
+	if err != nil {
+		return err
+	}
+	for _, bot := range bots {
+		if err = s.apiTokenRepo.RevokeByUserID(bot.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkThrottle Refuse the attempt while the username or the client IP is locked out
+func (s *authService) checkThrottle(userKey, ip string) error {
+	ctx := context.Background()
+
+	userWait, err := s.userLimiter.Check(ctx, userKey)
+	if err != nil {
+		return err
+	}
+	ipWait, err := s.ipLimiter.Check(ctx, ip)
+	if err != nil {
+		return err
+	}
+
+	if wait := max(userWait, ipWait); wait > 0 {
+		return &TooManyAttemptsError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordFailure Count a failed attempt against both keys, returning the failure to report
+func (s *authService) recordFailure(userKey, ip string, failure error) error {
+	ctx := context.Background()
+
+	if err := s.userLimiter.Fail(ctx, userKey); err != nil {
+		return err
+	}
+	if err := s.ipLimiter.Fail(ctx, ip); err != nil {
+		return err
+	}
+	return failure
+}
+
+// resetThrottle Forgive the account's failures once it has signed in
+func (s *authService) resetThrottle(userKey string) {
+	if err := s.userLimiter.Reset(context.Background(), userKey); err != nil {
+		logger.Logger.Warn("Error resetting sign in failures", zap.Error(err))
+	}
+}
+
+func (s *authService) revokeReusedFamily(stored *model.RefreshToken) error {
+	logger.Logger.Warn("Refresh token reuse detected",
+		zap.Int64("userID", stored.UserID),
+		zap.Int64("familyID", stored.FamilyID),
+	)
+	if err := revokeSession(s.sessionRepo, s.refreshRepo, stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// startSession Record a new session for the client and issue its first token pair
+func (s *authService) startSession(user *model.User, client ClientInfo) (*TokenPair, error) {
+	// Every sign in ends here, whichever factor it used
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	now := time.Now()
+	session := &model.Session{
+		SessionID:  s.idGenerator.Generate().Int64(),
+		UserID:     user.UserID,
+		DeviceName: truncate(client.DeviceName, 64),
+		UserAgent:  truncate(client.UserAgent, 255),
+		IP:         truncate(client.IP, 64),
+		LastSeenAt: now,
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, session.SessionID)
+}
+
+// issueTokens Generate an access token and a refresh token within the given session
+func (s *authService) issueTokens(user *model.User, sessionID int64) (*TokenPair, error) {
+	accessToken, err := s.tokenGen.Generate(user.Username, user.UserID, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.userRepo.FindByUsername(claims.Username)
+	refreshToken, err := token.NewOpaque()
 	if err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	if err = s.refreshRepo.Create(&model.RefreshToken{
+		FamilyID:  sessionID,
+		UserID:    user.UserID,
+		TokenHash: token.HashOpaque(refreshToken),
+		ExpiresAt: time.Now().Add(time.Duration(s.refreshTTL) * time.Second),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        s.tokenGen.TTL(),
+		RefreshExpiresIn: s.refreshTTL,
+	}, nil
+}
+
+// ValidateToken Resolve an access or API token into its user and claims
+func (s *authService) ValidateToken(tokenStr string) (*model.User, *token.UserClaims, error) {
+	if token.IsAPIToken(tokenStr) {
+		return s.validateAPIToken(tokenStr)
+	}
+
+	claims, err := s.tokenGen.Parse(tokenStr)
+	if err != nil {
+		if errors.Is(err, token.ErrTokenExpired) {
+			return nil, nil, ErrTokenExpired
+		}
+		if errors.Is(err, token.ErrRevocation) {
+			return nil, nil, err
+		}
+		return nil, nil, ErrTokenInvalid
+	}
+
+	if claims.SessionID != 0 {
+		if err = s.checkSession(&claims); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nil, nil, ErrTokenInvalid
+		}
+		return nil, nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	return user, &claims, nil
+}
+
+// PublicKeys Keys that verify access tokens offline, empty when the format is symmetric
+func (s *authService) PublicKeys() []token.PublicKey {
+	return s.tokenGen.PublicKeys()
+}
+
+// checkSession Reject tokens whose session was revoked and refresh its last seen time
+func (s *authService) checkSession(claims *token.UserClaims) error {
+	session, err := s.sessionRepo.FindBySessionID(claims.SessionID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.SessionNotFound) {
+			return ErrTokenInvalid
+		}
+		return err
+	}
+	if session.RevokedAt != nil || session.UserID != claims.UserID {
+		return ErrTokenInvalid
+	}
+
+	// Avoid a write on every request
+	if now := time.Now(); now.Sub(session.LastSeenAt) > lastSeenInterval {
+		if err = s.sessionRepo.Touch(session.SessionID, now); err != nil {
+			logger.Logger.Warn("Error updating session last seen", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// isHashAborted Report whether hashing never ran because the pool was full or the request ended
+func isHashAborted(err error) bool {
+	return errors.Is(err, hasher.ErrBusy) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// validateAccount Normalize and check the username and email of a new account
+func validateAccount(username, email string) (string, string, error) {
+	username, err := validation.Username(username)
+	if err != nil {
+		if errors.Is(err, validation.ErrUsernameReserved) {
+			return "", "", ErrUsernameReserved
+		}
+		return "", "", ErrInvalidUsernameFormat
+	}
+
+	if email, err = validation.Email(email); err != nil {
+		return "", "", ErrInvalidEmailFormat
+	}
+	return username, email, nil
 }
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/bwmarrin/snowflake"
+)
+
+// blockingVerification Holds every verification mail until released
+type blockingVerification struct {
+	VerificationService
+	release chan struct{}
+	sent    chan int64
+}
+
+func (v *blockingVerification) SendVerification(user *model.User) error {
+	<-v.release
+	v.sent <- user.UserID
+	return nil
+}
+
+// failingSessionRepo Refuses new sessions, so sign up stops right after the account is created
+type failingSessionRepo struct {
+	repository.SessionRepository
+}
+
+var errSessionStore = errors.New("session store unavailable")
+
+func (failingSessionRepo) Create(*model.Session) error {
+	return errSessionStore
+}
+
+func TestSignUpSendsVerificationInBackground(t *testing.T) {
+	node, err := snowflake.NewNode(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verification := &blockingVerification{release: make(chan struct{}), sent: make(chan int64, 1)}
+	userRepo := &fakeUserRepo{}
+	auth := NewAuthService(AuthDeps{
+		UserRepo:     userRepo,
+		SessionRepo:  failingSessionRepo{},
+		IDGenerator:  node,
+		Verification: verification,
+		Registration: RegistrationOpen,
+		Passwordless: true,
+		Challenges:   NewChallengeService(nil, ChallengeRules{}, nil, nil),
+	})
+
+	// Sign up returns while the mail server is still busy
+	done := make(chan error, 1)
+	go func() {
+		_, err := auth.SignUp(context.Background(), "alice", "", "alice@example.com", "", ClientInfo{})
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if !errors.Is(err, errSessionStore) {
+			t.Fatalf("err = %v, want the session store error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SignUp waited for the verification mail")
+	}
+
+	close(verification.release)
+	select {
+	case userID := <-verification.sent:
+		if len(userRepo.created) != 1 || userID != userRepo.created[0].UserID {
+			t.Errorf("verification sent to %d, want the new account", userID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("verification mail never sent")
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"go.uber.org/zap"
+)
+
+// ErrCredentialsRejected The authenticator does not accept the password, the next one is tried
+var ErrCredentialsRejected = errors.New("credentials rejected")
+
+// Authenticator Checks the password of a sign in. known is the local account the identifier
+// names, nil when there is none. Accepted sign ins return the account to sign in as.
+type Authenticator interface {
+	Authenticate(ctx context.Context, identifier, password string, known *model.User) (*model.User, error)
+}
+
+// authenticate Try each authenticator in turn until one accepts the password,
+// returning ErrCredentialsRejected when none does
+func authenticate(ctx context.Context, authenticators []Authenticator, identifier, password string, known *model.User) (*model.User, error) {
+	for _, authenticator := range authenticators {
+		accepted, err := authenticator.Authenticate(ctx, identifier, password, known)
+		if errors.Is(err, ErrCredentialsRejected) {
+			continue
+		}
+		return accepted, err
+	}
+	return nil, ErrCredentialsRejected
+}
+
+// localAuthenticator Checks passwords against the hashes stored with local accounts
+type localAuthenticator struct {
+	userRepo  repository.UserRepository
+	hasher    *hasher.Hasher
+	dummyHash string // Verified against for unknown usernames so timing matches a real account
+}
+
+func NewLocalAuthenticator(userRepo repository.UserRepository, hasher *hasher.Hasher) Authenticator {
+	dummyHash, err := hasher.Hash(context.Background(), "aurchat-dummy-password")
+	if err != nil {
+		logger.Logger.Fatal("Error creating dummy password hash", zap.Error(err))
+	}
+
+	return &localAuthenticator{
+		userRepo:  userRepo,
+		hasher:    hasher,
+		dummyHash: dummyHash,
+	}
+}
+
+func (a *localAuthenticator) Authenticate(ctx context.Context, _, password string, known *model.User) (*model.User, error) {
+	// Directory accounts only sign in through their directory
+	if known == nil || known.Password == "" || known.AuthSource != "" {
+		// Spend the same time as a wrong password so unknown usernames and passwordless accounts cannot be told apart
+		if _, err := a.hasher.VerifyHash(ctx, password, a.dummyHash); isHashAborted(err) {
+			return nil, err
+		}
+		return nil, ErrCredentialsRejected
+	}
+
+	if match, err := a.hasher.VerifyHash(ctx, password, known.Password); err != nil || !match {
+		// A hash that cannot be read never matches
+		if err == nil || errors.Is(err, hasher.ErrUnsupportedHash) {
+			return nil, ErrCredentialsRejected
+		} else if isHashAborted(err) {
+			return nil, err
+		} else {
+			return nil, ErrServerUnknown
+		}
+	}
+
+	a.upgradeHash(ctx, known, password)
+	return known, nil
+}
+
+// upgradeHash Re-hash a verified password stored with weaker parameters than the current ones.
+// Failing to upgrade does not fail the sign in, the next one retries.
+func (a *localAuthenticator) upgradeHash(ctx context.Context, user *model.User, password string) {
+	if !a.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hash, err := a.hasher.Hash(ctx, password)
+	if err != nil {
+		logger.Logger.Warn("Error upgrading password hash", zap.Int64("userID", user.UserID), zap.Error(err))
+		return
+	}
+	if err = a.userRepo.UpdatePassword(user.UserID, hash); err != nil {
+		logger.Logger.Warn("Error upgrading password hash", zap.Int64("userID", user.UserID), zap.Error(err))
+		return
+	}
+	user.Password = hash
+}
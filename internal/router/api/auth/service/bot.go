@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/validation"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/bwmarrin/snowflake"
+)
+
+var (
+	ErrBotNotFound = NewAuthError(
+		code.BotNotFound,
+		code.GetMessage(code.BotNotFound),
+		nil,
+	)
+
+	ErrBotLimitReached = NewAuthError(
+		code.BotLimitReached,
+		code.GetMessage(code.BotLimitReached),
+		nil,
+	)
+
+	ErrUsernameTaken = NewAuthError(
+		code.UserAlreadyExistsOrEmailAlreadyUsed,
+		code.GetMessage(code.UserAlreadyExistsOrEmailAlreadyUsed),
+		nil,
+	)
+)
+
+type BotService interface {
+	Create(owner *model.User, username string) (*model.User, error)
+	List(owner *model.User) ([]*model.User, error)
+	Delete(owner *model.User, botID int64) error
+	// FindOwned Return a bot managed by the owner
+	FindOwned(owner *model.User, botID int64) (*model.User, error)
+}
+
+type botService struct {
+	userRepo     repository.UserRepository
+	apiTokenRepo repository.APITokenRepository
+	idGenerator  *snowflake.Node
+	maxBots      int
+}
+
+func NewBotService(
+	userRepo repository.UserRepository,
+	apiTokenRepo repository.APITokenRepository,
+	idGenerator *snowflake.Node,
+	maxBots uint32,
+) BotService {
+	return &botService{
+		userRepo:     userRepo,
+		apiTokenRepo: apiTokenRepo,
+		idGenerator:  idGenerator,
+		maxBots:      int(maxBots),
+	}
+}
+
+// Create Register a bot managed by the owner. Bots have no password and a placeholder
+// address, so they can only authenticate with the API tokens their owner creates.
+func (s *botService) Create(owner *model.User, username string) (*model.User, error) {
+	username, err := validation.Username(username)
+	if err != nil {
+		if errors.Is(err, validation.ErrUsernameReserved) {
+			return nil, ErrUsernameReserved
+		}
+		return nil, ErrInvalidUsernameFormat
+	}
+
+	bots, err := s.userRepo.ListBotsByOwner(owner.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bots) >= s.maxBots {
+		return nil, ErrBotLimitReached
+	}
+
+	botID := s.idGenerator.Generate().Int64()
+	now := time.Now()
+	bot := &model.User{
+		UserID:          botID,
+		Username:        username,
+		Email:           strconv.FormatInt(botID, 10) + "@" + model.BotEmailDomain,
+		Bot:             true,
+		OwnerID:         &owner.UserID,
+		EmailVerifiedAt: &now,
+	}
+	if err = s.userRepo.Create(bot); err != nil {
+		if err.Error() == strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	return bot, nil
+}
+
+func (s *botService) List(owner *model.User) ([]*model.User, error) {
+	return s.userRepo.ListBotsByOwner(owner.UserID)
+}
+
+// Delete Remove a bot and revoke every token it holds
+func (s *botService) Delete(owner *model.User, botID int64) error {
+	if _, err := s.FindOwned(owner, botID); err != nil {
+		return err
+	}
+	if err := s.apiTokenRepo.RevokeByUserID(botID); err != nil {
+		return err
+	}
+	return s.userRepo.DeleteByID(botID)
+}
+
+func (s *botService) FindOwned(owner *model.User, botID int64) (*model.User, error) {
+	bot, err := s.userRepo.FindByID(botID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nil, ErrBotNotFound
+		}
+		return nil, err
+	}
+	if !bot.Bot || bot.OwnerID == nil || *bot.OwnerID != owner.UserID {
+		return nil, ErrBotNotFound
+	}
+	return bot, nil
+}
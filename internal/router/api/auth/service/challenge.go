@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/challenge"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/throttle"
+)
+
+// ChallengePurpose Keeps proof of work puzzles from opening as other sealed values
+const ChallengePurpose = "challenge"
+
+var (
+	ErrChallengeRequired = NewAuthError(
+		code.ChallengeRequired,
+		code.GetMessage(code.ChallengeRequired),
+		nil,
+	)
+
+	ErrChallengeFailed = NewAuthError(
+		code.ChallengeFailed,
+		code.GetMessage(code.ChallengeFailed),
+		nil,
+	)
+
+	ErrChallengeNotRequired = NewAuthError(
+		code.ChallengeNotRequired,
+		code.GetMessage(code.ChallengeNotRequired),
+		nil,
+	)
+)
+
+// ChallengeRules Endpoints guarded by a challenge
+type ChallengeRules struct {
+	SignUp        bool
+	PasswordReset bool
+	MagicLink     bool
+	SignInAfter   uint32 // Failed sign ins of a user or IP before one is required, 0 for never
+}
+
+type ChallengeService interface {
+	Issue(endpoint string) (*challenge.Challenge, error)
+	// Check Verify the answer when the endpoint always requires a challenge
+	Check(ctx context.Context, endpoint, answer, ip string) error
+	// CheckSignIn Verify the answer once the user or IP failed too many sign ins
+	CheckSignIn(ctx context.Context, userKey, answer, ip string) error
+}
+
+type challengeService struct {
+	verifier    challenge.Verifier // nil when challenges are disabled
+	rules       ChallengeRules
+	userLimiter *throttle.Limiter
+	ipLimiter   *throttle.Limiter
+}
+
+func NewChallengeService(verifier challenge.Verifier, rules ChallengeRules, userLimiter, ipLimiter *throttle.Limiter) ChallengeService {
+	return &challengeService{
+		verifier:    verifier,
+		rules:       rules,
+		userLimiter: userLimiter,
+		ipLimiter:   ipLimiter,
+	}
+}
+
+// Issue Create a challenge for an endpoint that can require one
+func (s *challengeService) Issue(endpoint string) (*challenge.Challenge, error) {
+	if !s.guards(endpoint) {
+		return nil, ErrChallengeNotRequired
+	}
+	return s.verifier.Issue(endpoint)
+}
+
+func (s *challengeService) Check(ctx context.Context, endpoint, answer, ip string) error {
+	if !s.guards(endpoint) || endpoint == challenge.EndpointSignIn {
+		return nil
+	}
+	return s.verify(ctx, endpoint, answer, ip)
+}
+
+func (s *challengeService) CheckSignIn(ctx context.Context, userKey, answer, ip string) error {
+	if !s.guards(challenge.EndpointSignIn) {
+		return nil
+	}
+
+	userFailures, err := s.userLimiter.Failures(ctx, userKey)
+	if err != nil {
+		return err
+	}
+	ipFailures, err := s.ipLimiter.Failures(ctx, ip)
+	if err != nil {
+		return err
+	}
+	if max(userFailures, ipFailures) < int64(s.rules.SignInAfter) {
+		return nil
+	}
+	return s.verify(ctx, challenge.EndpointSignIn, answer, ip)
+}
+
+// guards Report whether challenges are enabled for the endpoint
+func (s *challengeService) guards(endpoint string) bool {
+	if s.verifier == nil {
+		return false
+	}
+	switch endpoint {
+	case challenge.EndpointSignUp:
+		return s.rules.SignUp
+	case challenge.EndpointPasswordReset:
+		return s.rules.PasswordReset
+	case challenge.EndpointMagicLink:
+		return s.rules.MagicLink
+	case challenge.EndpointSignIn:
+		return s.rules.SignInAfter > 0
+	}
+	return false
+}
+
+func (s *challengeService) verify(ctx context.Context, endpoint, answer, ip string) error {
+	if answer == "" {
+		return ErrChallengeRequired
+	}
+	if err := s.verifier.Verify(ctx, endpoint, answer, ip); err != nil {
+		if errors.Is(err, challenge.ErrFailed) {
+			return ErrChallengeFailed
+		}
+		return err
+	}
+	return nil
+}
@@ -1,11 +1,16 @@
 package service
 
-import "github.com/AurChatOrg/aurchat-server/internal/code"
+import (
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+)
 
 type AuthError struct {
 	Code    int
 	Message string
 	Err     error
+	Reasons []string // Details for the client, such as password policy violations
 }
 
 func (e *AuthError) Error() string {
@@ -25,3 +30,12 @@ func NewAuthError(code int, message string, err error) *AuthError {
 		Err:     err,
 	}
 }
+
+// TooManyAttemptsError Attempt refused until RetryAfter has passed
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return code.GetMessage(code.TooManyRequests)
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/directory"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/validation"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/bwmarrin/snowflake"
+	"go.uber.org/zap"
+)
+
+// DirectoryClient Checks a password against a directory and returns the matching entry
+type DirectoryClient interface {
+	Authenticate(username, password string) (*directory.Entry, error)
+}
+
+// ldapAuthenticator Checks passwords by binding to an LDAP directory. Accounts it signs in
+// are tagged with model.AuthSourceLDAP and keep their email and role in sync with the directory.
+type ldapAuthenticator struct {
+	directory   DirectoryClient
+	userRepo    repository.UserRepository
+	idGenerator *snowflake.Node
+	groupRoles  map[string]string // Lowercased group DN to role
+	provision   bool              // Create accounts for directory users signing in the first time
+}
+
+func NewLDAPAuthenticator(
+	directory DirectoryClient,
+	userRepo repository.UserRepository,
+	node *snowflake.Node,
+	cfg config.LDAP,
+) Authenticator {
+	return &ldapAuthenticator{
+		directory:   directory,
+		userRepo:    userRepo,
+		idGenerator: node,
+		groupRoles:  lowerKeys(cfg.GroupRoles),
+		provision:   cfg.Provision,
+	}
+}
+
+func (a *ldapAuthenticator) Authenticate(_ context.Context, identifier, password string, known *model.User) (*model.User, error) {
+	// A directory user must not take over a local account with the same name
+	if known != nil && known.AuthSource != model.AuthSourceLDAP {
+		return nil, ErrCredentialsRejected
+	}
+	if known == nil && !a.provision {
+		return nil, ErrCredentialsRejected
+	}
+
+	username := identifier
+	if known != nil {
+		username = known.Username
+	}
+
+	entry, err := a.directory.Authenticate(username, password)
+	if err != nil {
+		if errors.Is(err, directory.ErrInvalidCredentials) {
+			return nil, ErrCredentialsRejected
+		}
+		logger.Logger.Error("Error authenticating against LDAP", zap.Error(err))
+		return nil, err
+	}
+
+	if known != nil {
+		return a.sync(known, entry)
+	}
+	return a.create(username, entry)
+}
+
+// sync Copy the directory email and role onto the account when they changed. Only those
+// columns are written, so a concurrent disable or password change is not overwritten.
+func (a *ldapAuthenticator) sync(user *model.User, entry *directory.Entry) (*model.User, error) {
+	if role := groupRole(a.groupRoles, entry.Groups, user.Role); role != user.Role {
+		if err := a.userRepo.UpdateRole(user.UserID, role); err != nil {
+			return nil, err
+		}
+		user.Role = role
+	}
+
+	if entry.Email == "" {
+		return user, nil
+	}
+	email, err := validation.Email(entry.Email)
+	if err != nil {
+		logger.Logger.Warn("Directory email not synced", zap.String("dn", entry.DN), zap.Error(err))
+		return user, nil
+	}
+	if email == user.Email {
+		return user, nil
+	}
+	if err = a.userRepo.UpdateEmail(user.UserID, email); err != nil {
+		if err.Error() != strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed) {
+			return nil, err
+		}
+		// The account still signs in, it keeps the email it has
+		logger.Logger.Warn("Directory email is used by another account", zap.String("dn", entry.DN))
+		return user, nil
+	}
+	user.Email = email
+	return user, nil
+}
+
+// create Provision an account for a directory user signing in the first time.
+// The directory vouches for the email, and the account has no local password.
+func (a *ldapAuthenticator) create(username string, entry *directory.Entry) (*model.User, error) {
+	if entry.Username != "" {
+		username = entry.Username
+	}
+	username, email, err := validateAccount(username, entry.Email)
+	if err != nil {
+		logger.Logger.Warn("Directory account cannot be provisioned", zap.String("dn", entry.DN), zap.Error(err))
+		return nil, ErrCredentialsRejected
+	}
+
+	now := time.Now()
+	user := &model.User{
+		UserID:          a.idGenerator.Generate().Int64(),
+		Username:        username,
+		Email:           email,
+		Role:            groupRole(a.groupRoles, entry.Groups, model.RoleUser),
+		AuthSource:      model.AuthSourceLDAP,
+		EmailVerifiedAt: &now,
+	}
+	if err = a.userRepo.Create(user); err != nil {
+		if err.Error() == strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed) {
+			logger.Logger.Warn("Directory account conflicts with an existing account", zap.String("dn", entry.DN))
+			return nil, ErrCredentialsRejected
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// groupRole Map groups to a role through lowercased groupRoles, admin winning over any other.
+// Without a mapping the provider does not manage roles and current is kept.
+func groupRole(groupRoles map[string]string, groups []string, current string) string {
+	if len(groupRoles) == 0 {
+		return current
+	}
+
+	role := model.RoleUser
+	for _, group := range groups {
+		mapped, ok := groupRoles[strings.ToLower(group)]
+		if !ok {
+			continue
+		}
+		if mapped == model.RoleAdmin {
+			return model.RoleAdmin
+		}
+		role = mapped
+	}
+	return role
+}
+
+// lowerKeys Copy of a group mapping with lowercased group names
+func lowerKeys(groupRoles map[string]string) map[string]string {
+	lowered := make(map[string]string, len(groupRoles))
+	for group, role := range groupRoles {
+		lowered[strings.ToLower(group)] = role
+	}
+	return lowered
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/directory"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/bwmarrin/snowflake"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger.Logger = zap.NewNop()
+}
+
+const (
+	adminsGroup = "cn=Admins,ou=groups,dc=example,dc=com"
+	staffGroup  = "cn=staff,ou=groups,dc=example,dc=com"
+)
+
+// fakeDirectory Directory accepting one password per username
+type fakeDirectory struct {
+	entries   map[string]*directory.Entry
+	passwords map[string]string
+	err       error // Returned instead of checking the password, such as an unreachable server
+	calls     int
+}
+
+func (d *fakeDirectory) Authenticate(username, password string) (*directory.Entry, error) {
+	d.calls++
+	if d.err != nil {
+		return nil, d.err
+	}
+	entry, ok := d.entries[username]
+	if !ok || d.passwords[username] != password {
+		return nil, directory.ErrInvalidCredentials
+	}
+	return entry, nil
+}
+
+// fakeUserRepo Records account writes, other repository methods are not used by the authenticators
+type fakeUserRepo struct {
+	repository.UserRepository
+	created   []*model.User
+	updated   []*model.User
+	roles     []string // Roles written by UpdateRole
+	emails    []string // Emails written by UpdateEmail
+	passwords []string // Hashes written by UpdatePassword
+	createErr error
+	emailErr  error
+}
+
+func (r *fakeUserRepo) Create(user *model.User) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	r.created = append(r.created, user)
+	return nil
+}
+
+func (r *fakeUserRepo) Update(user *model.User) error {
+	r.updated = append(r.updated, user)
+	return nil
+}
+
+func (r *fakeUserRepo) FindByEmail(string) (*model.User, error) {
+	return nil, errors.New(strconv.Itoa(code.UserNotFound))
+}
+
+func (r *fakeUserRepo) UpdatePassword(_ int64, hash string) error {
+	r.passwords = append(r.passwords, hash)
+	return nil
+}
+
+func (r *fakeUserRepo) UpdateRole(_ int64, role string) error {
+	r.roles = append(r.roles, role)
+	return nil
+}
+
+func (r *fakeUserRepo) UpdateEmail(_ int64, email string) error {
+	if r.emailErr != nil {
+		return r.emailErr
+	}
+	r.emails = append(r.emails, email)
+	return nil
+}
+
+func newTestDirectory() *fakeDirectory {
+	return &fakeDirectory{
+		entries: map[string]*directory.Entry{
+			"alice": {
+				DN:       "uid=alice,ou=people,dc=example,dc=com",
+				Username: "alice",
+				Email:    "alice@example.com",
+				Groups:   []string{staffGroup, adminsGroup},
+			},
+			"bob": {
+				DN:       "uid=bob,ou=people,dc=example,dc=com",
+				Username: "bob",
+				Email:    "bob@example.com",
+				Groups:   []string{staffGroup},
+			},
+		},
+		passwords: map[string]string{"alice": "alice-secret", "bob": "bob-secret"},
+	}
+}
+
+func newTestLDAPAuthenticator(t *testing.T, dir DirectoryClient, userRepo repository.UserRepository, provision bool) Authenticator {
+	t.Helper()
+	node, err := snowflake.NewNode(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewLDAPAuthenticator(dir, userRepo, node, config.LDAP{
+		Provision:  provision,
+		GroupRoles: map[string]string{"CN=admins,ou=groups,dc=example,dc=com": model.RoleAdmin, staffGroup: model.RoleUser},
+	})
+}
+
+func newTestHasher() *hasher.Hasher {
+	return hasher.NewHasher(1024, 1, 1, 16, 32, nil)
+}
+
+func TestLDAPProvisionsNewUser(t *testing.T) {
+	userRepo := &fakeUserRepo{}
+	auth := newTestLDAPAuthenticator(t, newTestDirectory(), userRepo, true)
+
+	user, err := auth.Authenticate(context.Background(), "alice", "alice-secret", nil)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if len(userRepo.created) != 1 || userRepo.created[0] != user {
+		t.Fatalf("created = %v, want the returned user", userRepo.created)
+	}
+	if user.Username != "alice" || user.Email != "alice@example.com" || user.AuthSource != model.AuthSourceLDAP {
+		t.Errorf("user = %+v", user)
+	}
+	if user.Password != "" || user.EmailVerifiedAt == nil || user.UserID == 0 {
+		t.Errorf("want no local password, a verified email and an ID, got %+v", user)
+	}
+	// Group DNs match regardless of case, admin wins over any other mapping
+	if user.Role != model.RoleAdmin {
+		t.Errorf("role = %q, want %q", user.Role, model.RoleAdmin)
+	}
+}
+
+func TestLDAPProvisionDisabled(t *testing.T) {
+	dir := newTestDirectory()
+	auth := newTestLDAPAuthenticator(t, dir, &fakeUserRepo{}, false)
+
+	if _, err := auth.Authenticate(context.Background(), "alice", "alice-secret", nil); !errors.Is(err, ErrCredentialsRejected) {
+		t.Errorf("err = %v, want ErrCredentialsRejected", err)
+	}
+	if dir.calls != 0 {
+		t.Errorf("directory called %d times for an unknown account without provisioning", dir.calls)
+	}
+}
+
+func TestLDAPProvisionConflict(t *testing.T) {
+	userRepo := &fakeUserRepo{createErr: errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed))}
+	auth := newTestLDAPAuthenticator(t, newTestDirectory(), userRepo, true)
+
+	if _, err := auth.Authenticate(context.Background(), "alice", "alice-secret", nil); !errors.Is(err, ErrCredentialsRejected) {
+		t.Errorf("err = %v, want ErrCredentialsRejected", err)
+	}
+}
+
+func TestLDAPSyncsExistingUser(t *testing.T) {
+	userRepo := &fakeUserRepo{}
+	auth := newTestLDAPAuthenticator(t, newTestDirectory(), userRepo, false)
+	known := &model.User{UserID: 7, Username: "bob", Email: "old@example.com", Role: model.RoleAdmin, AuthSource: model.AuthSourceLDAP}
+
+	user, err := auth.Authenticate(context.Background(), "bob", "bob-secret", known)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if user.Email != "bob@example.com" || user.Role != model.RoleUser {
+		t.Errorf("user = %+v, want the directory email and a role demoted with the group", user)
+	}
+	// Only the changed columns are written, never the whole stale row
+	if len(userRepo.roles) != 1 || len(userRepo.emails) != 1 || len(userRepo.updated) != 0 {
+		t.Errorf("wrote roles %v, emails %v and %d rows, want one role and one email", userRepo.roles, userRepo.emails, len(userRepo.updated))
+	}
+
+	// Nothing changed on the next sign in, nothing is written
+	if _, err = auth.Authenticate(context.Background(), "bob", "bob-secret", known); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if len(userRepo.roles) != 1 || len(userRepo.emails) != 1 {
+		t.Errorf("wrote roles %v and emails %v, want no write when in sync", userRepo.roles, userRepo.emails)
+	}
+}
+
+func TestLDAPSyncEmail(t *testing.T) {
+	tests := []struct {
+		name      string
+		directory string
+		emailErr  error
+		want      string
+	}{
+		{"normalized", "  Bob.New@Example.COM ", nil, "Bob.New@example.com"},
+		{"invalid kept out", "not an email", nil, "bob@example.com"},
+		{"empty kept out", "", nil, "bob@example.com"},
+		{"taken by another account", "carol@example.com", errors.New(strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed)), "bob@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestDirectory()
+			dir.entries["bob"].Email = tt.directory
+			userRepo := &fakeUserRepo{emailErr: tt.emailErr}
+			auth := newTestLDAPAuthenticator(t, dir, userRepo, false)
+			known := &model.User{UserID: 7, Username: "bob", Email: "bob@example.com", Role: model.RoleUser, AuthSource: model.AuthSourceLDAP}
+
+			user, err := auth.Authenticate(context.Background(), "bob", "bob-secret", known)
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if user.Email != tt.want {
+				t.Errorf("email = %q, want %q", user.Email, tt.want)
+			}
+			if tt.want == "bob@example.com" && len(userRepo.emails) != 0 {
+				t.Errorf("wrote emails %v, want none", userRepo.emails)
+			}
+		})
+	}
+}
+
+func TestLDAPDirectoryError(t *testing.T) {
+	dir := newTestDirectory()
+	dir.err = errors.New("connection refused")
+	auth := newTestLDAPAuthenticator(t, dir, &fakeUserRepo{}, true)
+
+	// An unreachable directory is an error, not a wrong password to count against the user
+	_, err := auth.Authenticate(context.Background(), "alice", "alice-secret", nil)
+	if err == nil || errors.Is(err, ErrCredentialsRejected) {
+		t.Errorf("err = %v, want the directory error", err)
+	}
+}
+
+func TestAuthenticatorChain(t *testing.T) {
+	ctx := context.Background()
+	hasher := newTestHasher()
+	localHash, err := hasher.Hash(ctx, "local-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := &fakeUserRepo{}
+	dir := newTestDirectory()
+	// The directory also has a "carol", who must not take over the local account of that name
+	dir.entries["carol"] = &directory.Entry{DN: "uid=carol,dc=example,dc=com", Username: "carol", Email: "carol@example.com"}
+	dir.passwords["carol"] = "directory-secret"
+
+	chain := []Authenticator{
+		newTestLDAPAuthenticator(t, dir, userRepo, true),
+		NewLocalAuthenticator(userRepo, hasher),
+	}
+	local := &model.User{UserID: 1, Username: "carol", Email: "carol@example.com", Password: localHash}
+	directoryUser := &model.User{UserID: 2, Username: "bob", Email: "bob@example.com", AuthSource: model.AuthSourceLDAP}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		known    *model.User
+		want     *model.User
+	}{
+		{"local account falls through to the local password", "carol", "local-secret", local, local},
+		{"local account ignores the directory password", "carol", "directory-secret", local, nil},
+		{"directory account signs in through the directory", "bob", "bob-secret", directoryUser, directoryUser},
+		{"directory account has no local password", "bob", "local-secret", directoryUser, nil},
+		{"unknown user rejected by both", "nobody", "whatever", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := authenticate(ctx, chain, tt.username, tt.password, tt.known)
+			if tt.want == nil {
+				if !errors.Is(err, ErrCredentialsRejected) {
+					t.Errorf("err = %v, want ErrCredentialsRejected", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("authenticate: %v", err)
+			}
+			if user != tt.want {
+				t.Errorf("signed in as %+v, want %+v", user, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/challenge"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/mailer"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"go.uber.org/zap"
+)
+
+const magicLinkPath = "/magic-link"
+
+var ErrMagicLinkOtherBrowser = NewAuthError(
+	code.MagicLinkOtherBrowser,
+	code.GetMessage(code.MagicLinkOtherBrowser),
+	nil,
+)
+
+type MagicLinkService interface {
+	// Send Email a sign in link and return the nonce the requesting browser must present with it
+	Send(ctx context.Context, email string, client ClientInfo) (string, error)
+	Consume(token, nonce string, client ClientInfo) (*SignInResult, error)
+	TTL() time.Duration
+}
+
+type magicLinkService struct {
+	userRepo     repository.UserRepository
+	tokenRepo    repository.OneTimeTokenRepository
+	mailer       mailer.Mailer
+	authService  AuthService
+	challenges   ChallengeService
+	mailThrottle *MailThrottle
+	ttl          time.Duration
+	baseURL      string
+}
+
+func NewMagicLinkService(
+	userRepo repository.UserRepository,
+	tokenRepo repository.OneTimeTokenRepository,
+	mailer mailer.Mailer,
+	authService AuthService,
+	challenges ChallengeService,
+	mailThrottle *MailThrottle,
+	ttl time.Duration,
+	baseURL string,
+) MagicLinkService {
+	return &magicLinkService{
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		mailer:       mailer,
+		authService:  authService,
+		challenges:   challenges,
+		mailThrottle: mailThrottle,
+		ttl:          ttl,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// TTL Link lifetime
+func (s *magicLinkService) TTL() time.Duration {
+	return s.ttl
+}
+
+// Send A nonce is returned whether or not the address belongs to an account, and the link
+// is issued in the background, so the response does not reveal which addresses are registered.
+func (s *magicLinkService) Send(ctx context.Context, email string, client ClientInfo) (string, error) {
+	if err := s.challenges.Check(ctx, challenge.EndpointMagicLink, client.Challenge, client.IP); err != nil {
+		return "", err
+	}
+	if err := s.mailThrottle.Allow(email, client.IP); err != nil {
+		return "", err
+	}
+
+	nonce, err := token.NewOpaque()
+	if err != nil {
+		return "", err
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nonce, nil
+		}
+		return "", err
+	}
+	// Directory accounts sign in through the directory, so disabling them there takes effect
+	if user.AuthSource != "" {
+		return nonce, nil
+	}
+
+	go func() {
+		if err := s.sendLink(user, token.HashOpaque(nonce)); err != nil {
+			logger.Logger.Warn("Error sending sign in link email", zap.Int64("userID", user.UserID), zap.Error(err))
+		}
+	}()
+	return nonce, nil
+}
+
+// Consume Sign in with an emailed link opened in the browser that requested it
+func (s *magicLinkService) Consume(raw, nonce string, client ClientInfo) (*SignInResult, error) {
+	stored, err := peekOneTimeToken(s.tokenRepo, model.TokenPurposeMagicLink, raw)
+	if err != nil {
+		return nil, err
+	}
+	// A link forwarded to, or intercepted by, someone else is useless without the nonce cookie
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(stored.NonceHash), []byte(token.HashOpaque(nonce))) != 1 {
+		return nil, ErrMagicLinkOtherBrowser
+	}
+	if _, err = consumeOneTimeToken(s.tokenRepo, model.TokenPurposeMagicLink, raw); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+
+	// Following the emailed link proves the address belongs to the user
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err = s.userRepo.MarkEmailVerified(user.UserID, now); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	return s.authService.FinishSignIn(user, client)
+}
+
+func (s *magicLinkService) sendLink(user *model.User, nonceHash string) error {
+	raw, err := issueBoundOneTimeToken(s.tokenRepo, user.UserID, model.TokenPurposeMagicLink, s.ttl, nonceHash)
+	if err != nil {
+		return err
+	}
+
+	link := s.baseURL + magicLinkPath + "?token=" + url.QueryEscape(raw)
+	return sendMail(s.mailer, mailer.Message{
+		To:      user.Email,
+		Subject: "Your AurChat sign in link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSign in to AurChat by opening the link below in the browser you requested it from:\n\n%s\n\nThe link expires in %d minutes and can only be used once. If you did not ask to sign in, ignore this email.\n",
+			user.Username, link, int(s.ttl.Minutes()),
+		),
+	})
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/normalize"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/throttle"
+)
+
+// Mail limits, counted per address and per client IP whether or not the address has an account
+const (
+	MailEmailFree  = 3
+	MailIPFree     = 10
+	MailBaseDelay  = time.Minute
+	MailMaxDelay   = time.Hour
+	MailLimitReset = 24 * time.Hour
+)
+
+// MailThrottle Limits the mail an address or a client IP can trigger. Every endpoint that mails
+// an address given in the request shares it, so one address has one budget.
+type MailThrottle struct {
+	emailLimiter *throttle.Limiter
+	ipLimiter    *throttle.Limiter
+}
+
+func NewMailThrottle(emailLimiter, ipLimiter *throttle.Limiter) *MailThrottle {
+	return &MailThrottle{emailLimiter: emailLimiter, ipLimiter: ipLimiter}
+}
+
+// Allow Refuse the request while the address or the client IP asked too often, else count it
+func (t *MailThrottle) Allow(email, ip string) error {
+	ctx := context.Background()
+	emailKey := normalize.Fold(email)
+
+	emailWait, err := t.emailLimiter.Check(ctx, emailKey)
+	if err != nil {
+		return err
+	}
+	ipWait, err := t.ipLimiter.Check(ctx, ip)
+	if err != nil {
+		return err
+	}
+	if wait := max(emailWait, ipWait); wait > 0 {
+		return &TooManyAttemptsError{RetryAfter: wait}
+	}
+
+	if err = t.emailLimiter.Fail(ctx, emailKey); err != nil {
+		return err
+	}
+	return t.ipLimiter.Fail(ctx, ip)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/throttle"
+)
+
+func newTestMailThrottle() *MailThrottle {
+	store := throttle.NewMemoryStore()
+	return NewMailThrottle(
+		throttle.NewLimiter(store, "mail-email:", MailEmailFree, MailBaseDelay, MailMaxDelay, MailLimitReset),
+		throttle.NewLimiter(store, "mail-ip:", MailIPFree, MailBaseDelay, MailMaxDelay, MailLimitReset),
+	)
+}
+
+func TestMailThrottlePerAddress(t *testing.T) {
+	mail := newTestMailThrottle()
+
+	// The free mails leave the next one undelayed, the one after that waits
+	for i := range MailEmailFree + 1 {
+		if err := mail.Allow("alice@example.com", "203.0.113.1"); err != nil {
+			t.Fatalf("mail %d: %v", i+1, err)
+		}
+	}
+	// The same address in another case, from another IP, shares the budget
+	var tooMany *TooManyAttemptsError
+	if err := mail.Allow("Alice@Example.com", "203.0.113.2"); !errors.As(err, &tooMany) || tooMany.RetryAfter <= 0 {
+		t.Errorf("err = %v, want TooManyAttemptsError", err)
+	}
+	if err := mail.Allow("bob@example.com", "203.0.113.2"); err != nil {
+		t.Errorf("other address: %v", err)
+	}
+}
+
+func TestMailThrottlePerIP(t *testing.T) {
+	mail := newTestMailThrottle()
+
+	for i := range MailIPFree + 1 {
+		if err := mail.Allow(string(rune('a'+i))+"@example.com", "203.0.113.1"); err != nil {
+			t.Fatalf("mail %d: %v", i+1, err)
+		}
+	}
+	var tooMany *TooManyAttemptsError
+	if err := mail.Allow("new@example.com", "203.0.113.1"); !errors.As(err, &tooMany) {
+		t.Errorf("err = %v, want TooManyAttemptsError", err)
+	}
+}
+
+func TestForgotThrottled(t *testing.T) {
+	mail := newTestMailThrottle()
+	passwords := NewPasswordService(&fakeUserRepo{}, nil, nil, nil, nil, nil, nil,
+		NewChallengeService(nil, ChallengeRules{}, nil, nil), mail, nil, "https://chat.example.com")
+	client := ClientInfo{IP: "203.0.113.1"}
+
+	// Unknown addresses count too, so a refusal does not tell whether the address has an account
+	for i := range MailEmailFree + 1 {
+		if err := passwords.Forgot(context.Background(), "nobody@example.com", client); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	var tooMany *TooManyAttemptsError
+	if err := passwords.Forgot(context.Background(), "nobody@example.com", client); !errors.As(err, &tooMany) {
+		t.Errorf("err = %v, want TooManyAttemptsError", err)
+	}
+}
+
+func TestMagicLinkThrottled(t *testing.T) {
+	mail := newTestMailThrottle()
+	links := NewMagicLinkService(&fakeUserRepo{}, nil, nil, nil,
+		NewChallengeService(nil, ChallengeRules{}, nil, nil), mail, time.Minute, "https://chat.example.com")
+	client := ClientInfo{IP: "203.0.113.1"}
+
+	for i := range MailEmailFree + 1 {
+		if _, err := links.Send(context.Background(), "nobody@example.com", client); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	var tooMany *TooManyAttemptsError
+	if _, err := links.Send(context.Background(), "nobody@example.com", client); !errors.As(err, &tooMany) {
+		t.Errorf("err = %v, want TooManyAttemptsError", err)
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/otp"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/throttle"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+)
+
+const (
+	mfaIssuer         = "AurChat"
+	MFATicketPurpose  = "mfa"
+	MFATicketTTL      = 300 // Seconds to complete the second step
+	recoveryCodeCount = 10
+	recoveryCodeHalf  = 5
+	recoveryAlphabet  = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+var (
+	ErrMFACodeInvalid = NewAuthError(
+		code.MFACodeInvalid,
+		code.GetMessage(code.MFACodeInvalid),
+		nil,
+	)
+
+	ErrMFAAlreadyEnabled = NewAuthError(
+		code.MFAAlreadyEnabled,
+		code.GetMessage(code.MFAAlreadyEnabled),
+		nil,
+	)
+
+	ErrMFANotEnabled = NewAuthError(
+		code.MFANotEnabled,
+		code.GetMessage(code.MFANotEnabled),
+		nil,
+	)
+
+	ErrReauthenticationUnavailable = NewAuthError(
+		code.ReauthenticationUnavailable,
+		code.GetMessage(code.ReauthenticationUnavailable),
+		nil,
+	)
+)
+
+// TOTPSetup Pending enrollment returned to the authenticator app
+type TOTPSetup struct {
+	Secret string
+	URI    string
+}
+
+type MFAService interface {
+	SetupTOTP(user *model.User) (*TOTPSetup, error)
+	ConfirmTOTP(user *model.User, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, user *model.User, password, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, user *model.User, password, code string) ([]string, error)
+	Reauthenticate(ctx context.Context, user *model.User, password, code string) error
+}
+
+type mfaService struct {
+	userRepo     repository.UserRepository
+	recoveryRepo repository.RecoveryCodeRepository
+	hasher       *hasher.Hasher
+	userLimiter  *throttle.Limiter
+}
+
+func NewMFAService(
+	userRepo repository.UserRepository,
+	recoveryRepo repository.RecoveryCodeRepository,
+	hasher *hasher.Hasher,
+	userLimiter *throttle.Limiter,
+) MFAService {
+	return &mfaService{
+		userRepo:     userRepo,
+		recoveryRepo: recoveryRepo,
+		hasher:       hasher,
+		userLimiter:  userLimiter,
+	}
+}
+
+// SetupTOTP Generate a secret pending confirmation, replacing any earlier pending one
+func (s *mfaService) SetupTOTP(user *model.User) (*TOTPSetup, error) {
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, uri, err := otp.Generate(mfaIssuer, user.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.userRepo.UpdateTOTP(user.UserID, secret, nil); err != nil {
+		return nil, err
+	}
+
+	return &TOTPSetup{Secret: secret, URI: uri}, nil
+}
+
+// ConfirmTOTP Activate the pending secret once the app proves it generates valid codes
+func (s *mfaService) ConfirmTOTP(user *model.User, passcode string) ([]string, error) {
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnabled
+	}
+
+	step, ok := otp.Validate(passcode, user.TOTPSecret, time.Now(), 0)
+	if !ok {
+		return nil, ErrMFACodeInvalid
+	}
+
+	now := time.Now()
+	if err := s.userRepo.UpdateTOTP(user.UserID, user.TOTPSecret, &now); err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.AdvanceTOTPStep(user.UserID, step); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(user.UserID)
+}
+
+// DisableTOTP Turn two-factor off after re-authenticating with password, if the account has one, and a second factor
+func (s *mfaService) DisableTOTP(ctx context.Context, user *model.User, password, passcode string) error {
+	if user.TOTPEnabledAt == nil {
+		return ErrMFANotEnabled
+	}
+	if err := s.Reauthenticate(ctx, user, password, passcode); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdateTOTP(user.UserID, "", nil); err != nil {
+		return err
+	}
+	return s.recoveryRepo.DeleteByUserID(user.UserID)
+}
+
+// RegenerateRecoveryCodes Replace every recovery code after re-authenticating
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, user *model.User, password, passcode string) ([]string, error) {
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.Reauthenticate(ctx, user, password, passcode); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(user.UserID)
+}
+
+// Reauthenticate Confirm a sensitive change with the password, if the account has one, and the
+// second factor, if one is enrolled. A session token alone never passes, and guesses are throttled.
+func (s *mfaService) Reauthenticate(ctx context.Context, user *model.User, password, passcode string) error {
+	if user.Password == "" && user.TOTPEnabledAt == nil {
+		return ErrReauthenticationUnavailable
+	}
+
+	return throttleAccount(ctx, s.userLimiter, user.UserID, func() error {
+		// Passwordless accounts re-authenticate with the second factor alone
+		if user.Password != "" {
+			if err := matchPassword(ctx, s.hasher, user, password); err != nil {
+				return err
+			}
+		}
+
+		if user.TOTPEnabledAt == nil {
+			return nil
+		}
+		return verifySecondFactor(s.userRepo, s.recoveryRepo, user, passcode)
+	})
+}
+
+func (s *mfaService) issueRecoveryCodes(userID int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		recoveryCode, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, recoveryCode)
+		hashes = append(hashes, token.HashOpaque(normalizeRecoveryCode(recoveryCode)))
+	}
+
+	if err := s.recoveryRepo.Replace(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor Accept either a current TOTP code or an unused recovery code
+func verifySecondFactor(
+	userRepo repository.UserRepository,
+	recoveryRepo repository.RecoveryCodeRepository,
+	user *model.User,
+	passcode string,
+) error {
+	passcode = strings.TrimSpace(passcode)
+
+	if _, err := strconv.Atoi(passcode); err == nil && len(passcode) == 6 {
+		step, ok := otp.Validate(passcode, user.TOTPSecret, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return ErrMFACodeInvalid
+		}
+		// A concurrent request may have used the same step in the meantime
+		advanced, err := userRepo.AdvanceTOTPStep(user.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return ErrMFACodeInvalid
+		}
+		return nil
+	}
+
+	used, err := recoveryRepo.Consume(user.UserID, token.HashOpaque(normalizeRecoveryCode(passcode)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrMFACodeInvalid
+	}
+	return nil
+}
+
+// newRecoveryCode Generate a code such as "k3m9q-x2w7a" with 50 bits of entropy
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, recoveryCodeHalf*2)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, v := range buf {
+		if i == recoveryCodeHalf {
+			b.WriteByte('-')
+		}
+		b.WriteByte(recoveryAlphabet[v&31])
+	}
+	return b.String(), nil
+}
+
+func normalizeRecoveryCode(value string) string {
+	value = strings.ToLower(value)
+	return strings.NewReplacer("-", "", " ", "").Replace(value)
+}
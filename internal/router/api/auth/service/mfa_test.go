@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+)
+
+// fakeRecoveryRepo Holds unused recovery code hashes
+type fakeRecoveryRepo struct {
+	repository.RecoveryCodeRepository
+	hashes map[string]bool
+}
+
+func (r *fakeRecoveryRepo) Consume(_ int64, hash string) (bool, error) {
+	if !r.hashes[hash] {
+		return false, nil
+	}
+	delete(r.hashes, hash)
+	return true, nil
+}
+
+func TestReauthenticate(t *testing.T) {
+	ctx := context.Background()
+	hasher := newTestHasher()
+	hash, err := hasher.Hash(ctx, "correct-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled := time.Now()
+	const recoveryCode = "abcde-fghij"
+
+	tests := []struct {
+		name     string
+		user     *model.User
+		password string
+		passcode string
+		want     error
+	}{
+		{"password", &model.User{Password: hash}, "correct-password", "", nil},
+		{"wrong password", &model.User{Password: hash}, "wrong", "", ErrAccountnameOrPassword},
+		{"password and second factor", &model.User{Password: hash, TOTPEnabledAt: &enabled}, "correct-password", recoveryCode, nil},
+		{"missing second factor", &model.User{Password: hash, TOTPEnabledAt: &enabled}, "correct-password", "", ErrMFACodeInvalid},
+		{"second factor without password", &model.User{Password: hash, TOTPEnabledAt: &enabled}, "", recoveryCode, ErrAccountnameOrPassword},
+		{"passwordless with second factor", &model.User{TOTPEnabledAt: &enabled}, "", recoveryCode, nil},
+		{"passwordless without second factor", &model.User{}, "anything", "anything", ErrReauthenticationUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recoveryRepo := &fakeRecoveryRepo{hashes: map[string]bool{
+				token.HashOpaque(normalizeRecoveryCode(recoveryCode)): true,
+			}}
+			mfa := NewMFAService(&fakeUserRepo{}, recoveryRepo, hasher, newTestLimiter())
+
+			err := mfa.Reauthenticate(ctx, tt.user, tt.password, tt.passcode)
+			if tt.want == nil && err != nil {
+				t.Errorf("err = %v, want nil", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReauthenticateThrottled(t *testing.T) {
+	ctx := context.Background()
+	hasher := newTestHasher()
+	hash, err := hasher.Hash(ctx, "correct-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled := time.Now()
+	mfa := NewMFAService(&fakeUserRepo{}, &fakeRecoveryRepo{}, hasher, newTestLimiter())
+	user := &model.User{UserID: 7, Password: hash, TOTPEnabledAt: &enabled}
+
+	// Wrong passwords and wrong codes both count against the account
+	for i := range 4 {
+		password := "wrong"
+		if i%2 == 1 {
+			password = "correct-password"
+		}
+		if err = mfa.Reauthenticate(ctx, user, password, "000000"); err == nil {
+			t.Fatalf("guess %d accepted", i+1)
+		}
+	}
+	var tooMany *TooManyAttemptsError
+	if err = mfa.Reauthenticate(ctx, user, "correct-password", "000000"); !errors.As(err, &tooMany) {
+		t.Errorf("err = %v, want TooManyAttemptsError", err)
+	}
+}
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/oidc"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/validation"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/bwmarrin/snowflake"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	OIDCStatePurpose = "oidc"
+	OIDCStateTTL     = 600 // Seconds to complete the provider login
+
+	usernameAttempts = 5
+)
+
+var (
+	ErrIdentityProviderNotFound = NewAuthError(
+		code.IdentityProviderNotFound,
+		code.GetMessage(code.IdentityProviderNotFound),
+		nil,
+	)
+
+	ErrIdentityProviderFailed = NewAuthError(
+		code.IdentityProviderFailed,
+		code.GetMessage(code.IdentityProviderFailed),
+		nil,
+	)
+
+	ErrIdentityAlreadyLinked = NewAuthError(
+		code.IdentityAlreadyLinked,
+		code.GetMessage(code.IdentityAlreadyLinked),
+		nil,
+	)
+
+	ErrIdentityNotFound = NewAuthError(
+		code.IdentityNotFound,
+		code.GetMessage(code.IdentityNotFound),
+		nil,
+	)
+
+	ErrIdentityNotLinked = NewAuthError(
+		code.IdentityNotLinked,
+		code.GetMessage(code.IdentityNotLinked),
+		nil,
+	)
+)
+
+// oidcState Authorization request state sealed into a cookie until the callback
+type oidcState struct {
+	Provider   string `json:"provider"`
+	State      string `json:"state"`
+	Nonce      string `json:"nonce"`
+	Verifier   string `json:"verifier"`
+	LinkUserID int64  `json:"link,omitempty"` // Set when a signed in user links the identity
+}
+
+// OIDCResult Either a token pair from a sign in, or the identity just linked
+type OIDCResult struct {
+	Tokens *TokenPair
+	Linked *model.LinkedIdentity
+}
+
+type OIDCService interface {
+	Providers() []*oidc.Provider
+	Begin(ctx context.Context, provider string) (string, string, error)
+	Link(ctx context.Context, provider string, user *model.User, password, passcode string) (string, string, error)
+	Callback(ctx context.Context, provider, authCode, state, sealed string, client ClientInfo) (*OIDCResult, error)
+	ListIdentities(userID int64) ([]*model.LinkedIdentity, error)
+	Unlink(userID int64, id uint) error
+}
+
+type oidcService struct {
+	registry     *oidc.Registry
+	userRepo     repository.UserRepository
+	identityRepo repository.LinkedIdentityRepository
+	sealer       *token.Sealer
+	hasher       *hasher.Hasher
+	idGenerator  *snowflake.Node
+	authService  AuthService
+	verification VerificationService
+	mfaService   MFAService
+}
+
+func NewOIDCService(
+	registry *oidc.Registry,
+	userRepo repository.UserRepository,
+	identityRepo repository.LinkedIdentityRepository,
+	sealer *token.Sealer,
+	hasher *hasher.Hasher,
+	node *snowflake.Node,
+	authService AuthService,
+	verification VerificationService,
+	mfaService MFAService,
+) OIDCService {
+	return &oidcService{
+		registry:     registry,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		sealer:       sealer,
+		hasher:       hasher,
+		idGenerator:  node,
+		authService:  authService,
+		verification: verification,
+		mfaService:   mfaService,
+	}
+}
+
+func (s *oidcService) Providers() []*oidc.Provider {
+	return s.registry.List()
+}
+
+// Link Begin linking a provider account to the user. Linked identities sign in without the
+// second factor, so the user re-authenticates first.
+func (s *oidcService) Link(ctx context.Context, providerName string, user *model.User, password, passcode string) (string, string, error) {
+	if _, err := s.registry.Get(providerName); err != nil {
+		return "", "", ErrIdentityProviderNotFound
+	}
+	if err := s.mfaService.Reauthenticate(ctx, user, password, passcode); err != nil {
+		return "", "", err
+	}
+	return s.begin(ctx, providerName, user.UserID)
+}
+
+// Begin Start signing in with a provider
+func (s *oidcService) Begin(ctx context.Context, providerName string) (string, string, error) {
+	return s.begin(ctx, providerName, 0)
+}
+
+// begin Build the provider authorization URL and the sealed state to keep until the callback.
+// A non-zero linkUserID links the provider account to that user instead of signing in.
+func (s *oidcService) begin(ctx context.Context, providerName string, linkUserID int64) (string, string, error) {
+	provider, err := s.registry.Get(providerName)
+	if err != nil {
+		return "", "", ErrIdentityProviderNotFound
+	}
+
+	state, err := token.NewOpaque()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := token.NewOpaque()
+	if err != nil {
+		return "", "", err
+	}
+
+	// The verifier only travels sealed, the provider sees its S256 challenge
+	verifier := oauth2.GenerateVerifier()
+	sealed, err := s.sealer.Seal(oidcState{
+		Provider:   providerName,
+		State:      state,
+		Nonce:      nonce,
+		Verifier:   verifier,
+		LinkUserID: linkUserID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		logger.Logger.Error("Error starting OIDC sign in", zap.String("provider", providerName), zap.Error(err))
+		return "", "", ErrIdentityProviderFailed
+	}
+	return authURL, sealed, nil
+}
+
+// Callback Redeem the authorization code, then sign in, provision or link depending on the state.
+// Second factors are left to the identity provider.
+func (s *oidcService) Callback(
+	ctx context.Context,
+	providerName, authCode, state, sealed string,
+	client ClientInfo,
+) (*OIDCResult, error) {
+	var pending oidcState
+	if err := s.sealer.Open(sealed, &pending); err != nil {
+		if errors.Is(err, token.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		if errors.Is(err, token.ErrRevocation) {
+			return nil, err
+		}
+		return nil, ErrTokenInvalid
+	}
+	if pending.Provider != providerName || pending.State == "" || pending.State != state {
+		return nil, ErrTokenInvalid
+	}
+
+	provider, err := s.registry.Get(providerName)
+	if err != nil {
+		return nil, ErrIdentityProviderNotFound
+	}
+
+	identity, err := provider.Exchange(ctx, authCode, pending.Nonce, pending.Verifier)
+	if err != nil {
+		logger.Logger.Warn("OIDC callback rejected", zap.String("provider", providerName), zap.Error(err))
+		return nil, ErrIdentityProviderFailed
+	}
+
+	if pending.LinkUserID != 0 {
+		linked, err := s.link(pending.LinkUserID, providerName, identity)
+		if err != nil {
+			return nil, err
+		}
+		return &OIDCResult{Linked: linked}, nil
+	}
+
+	user, err := s.resolveUser(ctx, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.authService.IssueSession(user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCResult{Tokens: tokens}, nil
+}
+
+func (s *oidcService) ListIdentities(userID int64) ([]*model.LinkedIdentity, error) {
+	return s.identityRepo.ListByUserID(userID)
+}
+
+func (s *oidcService) Unlink(userID int64, id uint) error {
+	if err := s.identityRepo.Delete(userID, id); err != nil {
+		if err.Error() == strconv.Itoa(code.IdentityNotFound) {
+			return ErrIdentityNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *oidcService) link(userID int64, providerName string, identity *oidc.Identity) (*model.LinkedIdentity, error) {
+	existing, err := s.identityRepo.FindByIssuerSubject(identity.Issuer, identity.Subject)
+	if err == nil {
+		if existing.UserID == userID {
+			return existing, nil
+		}
+		return nil, ErrIdentityAlreadyLinked
+	}
+	if err.Error() != strconv.Itoa(code.IdentityNotFound) {
+		return nil, err
+	}
+
+	linked := &model.LinkedIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Issuer:   identity.Issuer,
+		Subject:  identity.Subject,
+		Email:    truncate(identity.Email, 64),
+	}
+	if err = s.identityRepo.Create(linked); err != nil {
+		if err.Error() == strconv.Itoa(code.IdentityAlreadyLinked) {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return nil, err
+	}
+	return linked, nil
+}
+
+// resolveUser Find the account linked to the identity, or create one when the provider allows sign up.
+// An identity is never attached to an existing account by email, the owner has to link it while signed in.
+func (s *oidcService) resolveUser(ctx context.Context, provider *oidc.Provider, identity *oidc.Identity) (*model.User, error) {
+	linked, err := s.identityRepo.FindByIssuerSubject(identity.Issuer, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.FindByID(linked.UserID)
+		if err != nil {
+			if err.Error() == strconv.Itoa(code.UserNotFound) {
+				return nil, ErrIdentityNotLinked
+			}
+			return nil, err
+		}
+		return user, nil
+	}
+	if err.Error() != strconv.Itoa(code.IdentityNotFound) {
+		return nil, err
+	}
+
+	if !provider.AllowSignUp() || !s.authService.RegistrationOpen() || identity.Email == "" {
+		return nil, ErrIdentityNotLinked
+	}
+	// Claims are checked like sign up input, an email the account table cannot hold is not linked
+	username, email, err := validateAccount(usernameFromIdentity(identity.PreferredUsername, identity.Email), identity.Email)
+	if err != nil {
+		return nil, ErrIdentityNotLinked
+	}
+	if _, err = s.userRepo.FindByEmail(email); err == nil {
+		return nil, ErrIdentityNotLinked
+	} else if err.Error() != strconv.Itoa(code.UserNotFound) {
+		return nil, err
+	}
+
+	return s.provision(ctx, provider.Name(), identity, username, email)
+}
+
+// provision Create an account for a new identity and link it
+func (s *oidcService) provision(ctx context.Context, providerName string, identity *oidc.Identity, username, email string) (*model.User, error) {
+	user, err := provisionUser(ctx, s.userRepo, s.hasher, s.idGenerator, username, email, identity.EmailVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.link(user.UserID, providerName, identity); err != nil {
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err = s.verification.SendVerification(user); err != nil {
+			logger.Logger.Warn("Error sending verification email", zap.Int64("userID", user.UserID), zap.Error(err))
+		}
+	}
+	return user, nil
+}
+
+// provisionUser Create an account for an external identity, suffixing the username while it is taken.
+// Its password is random, the owner can set one through the password reset flow.
+func provisionUser(
+	ctx context.Context,
+	userRepo repository.UserRepository,
+	hasher *hasher.Hasher,
+	node *snowflake.Node,
+	username, email string,
+	emailVerified bool,
+) (*model.User, error) {
+	secret, err := token.NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hasher.Hash(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		UserID:   node.Generate().Int64(),
+		Email:    email,
+		Password: hash,
+	}
+	if emailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	for attempt := range usernameAttempts {
+		user.Username = username
+		if attempt > 0 {
+			suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+			if err != nil {
+				return nil, err
+			}
+			user.Username = username + suffix.String()
+		}
+
+		err = userRepo.Create(user)
+		if err == nil {
+			return user, nil
+		}
+		if err.Error() != strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed) {
+			return nil, err
+		}
+	}
+	return nil, ErrIdentityNotLinked
+}
+
+// usernameFromIdentity Derive a username from the provider's preferred username or the email local part
+func usernameFromIdentity(preferred, email string) string {
+	candidate := preferred
+	if candidate == "" || strings.Contains(candidate, "@") {
+		candidate, _, _ = strings.Cut(email, "@")
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(candidate) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+
+	username := truncate(b.String(), 16) // Leaves room for a numeric suffix
+	if _, err := validation.Username(username); err != nil {
+		username = "user"
+	}
+	return username
+}
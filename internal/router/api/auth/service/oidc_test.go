@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/oidc"
+)
+
+func TestOIDCLinkReauthenticates(t *testing.T) {
+	ctx := context.Background()
+	hasher := newTestHasher()
+	hash, err := hasher.Hash(ctx, "correct-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userRepo := &fakeUserRepo{}
+	// Reauthentication fails before discovery, so the issuer is never contacted
+	registry := oidc.NewRegistry([]config.OIDC{{Name: "corp", Issuer: "https://idp.invalid", ClientID: "aurchat"}}, "https://chat.example.com")
+	mfa := NewMFAService(userRepo, &fakeRecoveryRepo{}, hasher, newTestLimiter())
+	service := NewOIDCService(registry, userRepo, nil, nil, hasher, nil, nil, nil, mfa)
+	user := &model.User{UserID: 1, Username: "alice", Password: hash}
+
+	if _, _, err = service.Link(ctx, "corp", user, "", ""); !errors.Is(err, ErrAccountnameOrPassword) {
+		t.Errorf("session only: err = %v, want ErrAccountnameOrPassword", err)
+	}
+	if _, _, err = service.Link(ctx, "corp", user, "wrong", ""); !errors.Is(err, ErrAccountnameOrPassword) {
+		t.Errorf("wrong password: err = %v, want ErrAccountnameOrPassword", err)
+	}
+	if _, _, err = service.Link(ctx, "other", user, "correct-password", ""); !errors.Is(err, ErrIdentityProviderNotFound) {
+		t.Errorf("unknown provider: err = %v, want ErrIdentityProviderNotFound", err)
+	}
+}
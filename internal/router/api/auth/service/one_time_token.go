@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/mailer"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+)
+
+const mailSendTimeout = 10 * time.Second
+
+// issueOneTimeToken Burn the user's outstanding tokens of the purpose and store a new one
+func issueOneTimeToken(
+	tokenRepo repository.OneTimeTokenRepository,
+	userID int64,
+	purpose string,
+	ttl time.Duration,
+) (string, error) {
+	return issueBoundOneTimeToken(tokenRepo, userID, purpose, ttl, "")
+}
+
+// issueBoundOneTimeToken Issue a token that only works alongside the nonce with the given hash
+func issueBoundOneTimeToken(
+	tokenRepo repository.OneTimeTokenRepository,
+	userID int64,
+	purpose string,
+	ttl time.Duration,
+	nonceHash string,
+) (string, error) {
+	if err := tokenRepo.InvalidateByUserID(userID, purpose); err != nil {
+		return "", err
+	}
+
+	raw, err := token.NewOpaque()
+	if err != nil {
+		return "", err
+	}
+	if err = tokenRepo.Create(&model.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: token.HashOpaque(raw),
+		ExpiresAt: time.Now().Add(ttl),
+		NonceHash: nonceHash,
+	}); err != nil {
+		return "", err
+	}
+
+	return raw, nil
+}
+
+// consumeOneTimeToken Use up a token of the purpose, mapping lookup failures to auth errors
+func consumeOneTimeToken(
+	tokenRepo repository.OneTimeTokenRepository,
+	purpose string,
+	raw string,
+) (*model.OneTimeToken, error) {
+	stored, err := tokenRepo.Consume(purpose, token.HashOpaque(raw))
+	if err != nil {
+		return nil, mapOneTimeTokenError(err)
+	}
+	return stored, nil
+}
+
+// peekOneTimeToken Look up a usable token of the purpose without consuming it
+func peekOneTimeToken(
+	tokenRepo repository.OneTimeTokenRepository,
+	purpose string,
+	raw string,
+) (*model.OneTimeToken, error) {
+	stored, err := tokenRepo.FindActive(purpose, token.HashOpaque(raw))
+	if err != nil {
+		return nil, mapOneTimeTokenError(err)
+	}
+	return stored, nil
+}
+
+func mapOneTimeTokenError(err error) error {
+	switch err.Error() {
+	case strconv.Itoa(code.TokenInvalid):
+		return ErrTokenInvalid
+	case strconv.Itoa(code.TokenExpired):
+		return ErrTokenExpired
+	}
+	return err
+}
+
+// sendMail Deliver a message with the standard send timeout
+func sendMail(m mailer.Mailer, msg mailer.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+	defer cancel()
+	return m.Send(ctx, msg)
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/challenge"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/mailer"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/policy"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/throttle"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	passwordResetTTL  = time.Hour
+	passwordResetPath = "/reset-password"
+)
+
+type PasswordService interface {
+	Forgot(ctx context.Context, email string, client ClientInfo) error
+	Reset(ctx context.Context, token, password string) error
+	Change(ctx context.Context, user *model.User, sessionID int64, currentPassword, newPassword string) error
+}
+
+type passwordService struct {
+	userRepo       repository.UserRepository
+	tokenRepo      repository.OneTimeTokenRepository
+	hasher         *hasher.Hasher
+	policy         *policy.PasswordPolicy
+	mailer         mailer.Mailer
+	authService    AuthService
+	sessionService SessionService
+	challenges     ChallengeService
+	mailThrottle   *MailThrottle
+	userLimiter    *throttle.Limiter
+	baseURL        string
+}
+
+func NewPasswordService(
+	userRepo repository.UserRepository,
+	tokenRepo repository.OneTimeTokenRepository,
+	hasher *hasher.Hasher,
+	policy *policy.PasswordPolicy,
+	mailer mailer.Mailer,
+	authService AuthService,
+	sessionService SessionService,
+	challenges ChallengeService,
+	mailThrottle *MailThrottle,
+	userLimiter *throttle.Limiter,
+	baseURL string,
+) PasswordService {
+	return &passwordService{
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		hasher:         hasher,
+		policy:         policy,
+		mailer:         mailer,
+		authService:    authService,
+		sessionService: sessionService,
+		challenges:     challenges,
+		mailThrottle:   mailThrottle,
+		userLimiter:    userLimiter,
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Forgot Email a reset link when the address belongs to an account.
+// The link is issued in the background so the response does not reveal whether it does.
+func (s *passwordService) Forgot(ctx context.Context, email string, client ClientInfo) error {
+	if err := s.challenges.Check(ctx, challenge.EndpointPasswordReset, client.Challenge, client.IP); err != nil {
+		return err
+	}
+	if err := s.mailThrottle.Allow(email, client.IP); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nil
+		}
+		return err
+	}
+	// Directory accounts change their password in the directory
+	if user.AuthSource != "" {
+		return nil
+	}
+
+	go func() {
+		if err := s.sendResetLink(user); err != nil {
+			logger.Logger.Warn("Error sending password reset email", zap.Int64("userID", user.UserID), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (s *passwordService) sendResetLink(user *model.User) error {
+	raw, err := issueOneTimeToken(s.tokenRepo, user.UserID, model.TokenPurposeResetPassword, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.baseURL + passwordResetPath + "?token=" + url.QueryEscape(raw)
+	return sendMail(s.mailer, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your AurChat password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your AurChat account. Choose a new password by opening the link below:\n\n%s\n\nThe link expires in 1 hour and can only be used once. If you did not ask for a reset, ignore this email.\n",
+			user.Username, link,
+		),
+	})
+}
+
+// Reset Consume a reset token, store the new password and sign the user out everywhere
+func (s *passwordService) Reset(ctx context.Context, raw, password string) error {
+	// Check the policy before consuming, so a rejected password leaves the link usable
+	pending, err := peekOneTimeToken(s.tokenRepo, model.TokenPurposeResetPassword, raw)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByID(pending.UserID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return ErrTokenInvalid
+		}
+		return err
+	}
+	if err = checkPasswordPolicy(s.policy, password, user.Username, user.Email); err != nil {
+		return err
+	}
+	hash, err := s.hasher.Hash(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	if _, err = consumeOneTimeToken(s.tokenRepo, model.TokenPurposeResetPassword, raw); err != nil {
+		return err
+	}
+
+	if err = s.userRepo.UpdatePassword(user.UserID, hash); err != nil {
+		return err
+	}
+
+	// Following the emailed link proves the address belongs to the user
+	if user.EmailVerifiedAt == nil {
+		if err = s.userRepo.MarkEmailVerified(user.UserID, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return s.authService.SignOutAll(user.UserID)
+}
+
+// Change Replace the password after checking the current one, then sign out every other session.
+// Passwordless accounts have no current password to check and set their first one here.
+func (s *passwordService) Change(ctx context.Context, user *model.User, sessionID int64, currentPassword, newPassword string) error {
+	if user.AuthSource != "" {
+		return ErrPasswordManagedByDirectory
+	}
+	if user.Password != "" {
+		err := throttleAccount(ctx, s.userLimiter, user.UserID, func() error {
+			return matchPassword(ctx, s.hasher, user, currentPassword)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := checkPasswordPolicy(s.policy, newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	hash, err := s.hasher.Hash(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+	if err = s.userRepo.UpdatePassword(user.UserID, hash); err != nil {
+		return err
+	}
+	return s.sessionService.RevokeOthers(user.UserID, sessionID)
+}
+
+// throttleAccount Run a credential check for a signed-in account. Wrong passwords and codes count against
+// the account like failed sign ins, keyed by user ID, so a stolen session cannot guess them online.
+func throttleAccount(ctx context.Context, limiter *throttle.Limiter, userID int64, check func() error) error {
+	key := "id:" + strconv.FormatInt(userID, 10)
+	wait, err := limiter.Check(ctx, key)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &TooManyAttemptsError{RetryAfter: wait}
+	}
+
+	if err = check(); err != nil {
+		if errors.Is(err, ErrAccountnameOrPassword) || errors.Is(err, ErrMFACodeInvalid) {
+			if failErr := limiter.Fail(ctx, key); failErr != nil {
+				return failErr
+			}
+		}
+		return err
+	}
+
+	if err = limiter.Reset(ctx, key); err != nil {
+		logger.Logger.Warn("Error resetting account attempts", zap.Int64("userID", userID), zap.Error(err))
+	}
+	return nil
+}
+
+// matchPassword Compare a password with the account's hash, a hash that cannot be read never matches
+func matchPassword(ctx context.Context, h *hasher.Hasher, user *model.User, password string) error {
+	match, err := h.VerifyHash(ctx, password, user.Password)
+	if err != nil && !errors.Is(err, hasher.ErrUnsupportedHash) {
+		return err
+	}
+	if !match {
+		return ErrAccountnameOrPassword
+	}
+	return nil
+}
+
+// checkPasswordPolicy Reject a new password with code.PasswordTooWeak and the violated rules
+func checkPasswordPolicy(p *policy.PasswordPolicy, password, username, email string) error {
+	reasons, err := p.Check(password, username, email)
+	if err != nil {
+		return err
+	}
+	if len(reasons) > 0 {
+		authErr := NewAuthError(code.PasswordTooWeak, code.GetMessage(code.PasswordTooWeak), nil)
+		authErr.Reasons = reasons
+		return authErr
+	}
+	return nil
+}
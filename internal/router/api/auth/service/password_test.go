@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/policy"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/throttle"
+)
+
+// fakeSessionService Records the sessions kept when the others are signed out
+type fakeSessionService struct {
+	SessionService
+	kept []int64
+}
+
+func (s *fakeSessionService) RevokeOthers(_, currentSessionID int64) error {
+	s.kept = append(s.kept, currentSessionID)
+	return nil
+}
+
+// newTestLimiter Account limiter allowing three free failures
+func newTestLimiter() *throttle.Limiter {
+	return throttle.NewLimiter(throttle.NewMemoryStore(), "user:", 3, time.Second, time.Minute, time.Hour)
+}
+
+func newTestPasswordService(t *testing.T, userRepo *fakeUserRepo, sessions SessionService) PasswordService {
+	t.Helper()
+	passwordPolicy, err := policy.NewPasswordPolicy(config.PasswordPolicy{MinLength: 8, MaxLength: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewPasswordService(userRepo, nil, newTestHasher(), passwordPolicy, nil, nil, sessions,
+		NewChallengeService(nil, ChallengeRules{}, nil, nil), newTestMailThrottle(), newTestLimiter(), "https://chat.example.com")
+}
+
+func TestChangePasswordThrottled(t *testing.T) {
+	ctx := context.Background()
+	hash, err := newTestHasher().Hash(ctx, "current-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := &fakeSessionService{}
+	passwords := newTestPasswordService(t, &fakeUserRepo{}, sessions)
+	user := &model.User{UserID: 7, Username: "alice", Password: hash}
+
+	// The free guesses leave the next one undelayed, the one after that waits
+	for i := range 4 {
+		if err = passwords.Change(ctx, user, 1, "guess", "new-password-123"); !errors.Is(err, ErrAccountnameOrPassword) {
+			t.Fatalf("guess %d: err = %v, want ErrAccountnameOrPassword", i+1, err)
+		}
+	}
+	var tooMany *TooManyAttemptsError
+	if err = passwords.Change(ctx, user, 1, "current-password", "new-password-123"); !errors.As(err, &tooMany) {
+		t.Fatalf("err = %v, want TooManyAttemptsError even for the right password", err)
+	}
+	if len(sessions.kept) != 0 {
+		t.Errorf("password changed while throttled")
+	}
+
+	// Another account is not affected
+	other := &model.User{UserID: 8, Username: "bob", Password: hash}
+	if err = passwords.Change(ctx, other, 2, "current-password", "new-password-123"); err != nil {
+		t.Errorf("other account: %v", err)
+	}
+}
+
+func TestChangePasswordWithoutCurrent(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		user *model.User
+		want error
+	}{
+		{"passwordless", &model.User{UserID: 7, Username: "alice"}, nil},
+		{"directory", &model.User{UserID: 8, Username: "bob", AuthSource: model.AuthSourceLDAP}, ErrPasswordManagedByDirectory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := &fakeUserRepo{}
+			passwords := newTestPasswordService(t, userRepo, &fakeSessionService{})
+
+			err := passwords.Change(ctx, tt.user, 1, "", "new-password-123")
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				if len(userRepo.passwords) != 1 {
+					t.Fatal("password not stored")
+				}
+				if ok, _ := newTestHasher().VerifyHash(ctx, "new-password-123", userRepo.passwords[0]); !ok {
+					t.Error("stored hash does not match the new password")
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if len(userRepo.passwords) != 0 {
+				t.Error("password stored for a directory account")
+			}
+		})
+	}
+}
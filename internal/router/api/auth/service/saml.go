@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"strconv"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/saml"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/bwmarrin/snowflake"
+	"go.uber.org/zap"
+)
+
+const (
+	SAMLStatePurpose = "saml"
+	SAMLStateTTL     = 600 // Seconds to complete the provider login
+)
+
+// samlState AuthnRequest state sealed into a cookie until the response is posted back
+type samlState struct {
+	Provider  string `json:"provider"`
+	State     string `json:"state"` // Sent as RelayState
+	RequestID string `json:"request"`
+}
+
+type SAMLService interface {
+	Providers() []*saml.Provider
+	Metadata(provider string) ([]byte, error)
+	Begin(ctx context.Context, provider string) (string, string, error)
+	Assert(ctx context.Context, provider, samlResponse, relayState, sealed string, client ClientInfo) (*TokenPair, error)
+}
+
+type samlService struct {
+	registry     *saml.Registry
+	userRepo     repository.UserRepository
+	identityRepo repository.LinkedIdentityRepository
+	sealer       *token.Sealer
+	revocations  token.RevocationStore
+	hasher       *hasher.Hasher
+	idGenerator  *snowflake.Node
+	authService  AuthService
+}
+
+func NewSAMLService(
+	registry *saml.Registry,
+	userRepo repository.UserRepository,
+	identityRepo repository.LinkedIdentityRepository,
+	sealer *token.Sealer,
+	revocations token.RevocationStore,
+	hasher *hasher.Hasher,
+	node *snowflake.Node,
+	authService AuthService,
+) SAMLService {
+	return &samlService{
+		registry:     registry,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		sealer:       sealer,
+		revocations:  revocations,
+		hasher:       hasher,
+		idGenerator:  node,
+		authService:  authService,
+	}
+}
+
+func (s *samlService) Providers() []*saml.Provider {
+	return s.registry.List()
+}
+
+// Metadata Service provider metadata of a provider
+func (s *samlService) Metadata(providerName string) ([]byte, error) {
+	provider, err := s.registry.Get(providerName)
+	if err != nil {
+		return nil, ErrIdentityProviderNotFound
+	}
+	return provider.Metadata()
+}
+
+// Begin Build the signed AuthnRequest URL and the sealed state to keep until the response
+func (s *samlService) Begin(ctx context.Context, providerName string) (string, string, error) {
+	provider, err := s.registry.Get(providerName)
+	if err != nil {
+		return "", "", ErrIdentityProviderNotFound
+	}
+
+	state, err := token.NewOpaque()
+	if err != nil {
+		return "", "", err
+	}
+	redirectURL, requestID, err := provider.AuthnRequest(ctx, state)
+	if err != nil {
+		logger.Logger.Error("Error starting SAML sign in", zap.String("provider", providerName), zap.Error(err))
+		return "", "", ErrIdentityProviderFailed
+	}
+
+	sealed, err := s.sealer.Seal(samlState{
+		Provider:  providerName,
+		State:     state,
+		RequestID: requestID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return redirectURL, sealed, nil
+}
+
+// Assert Verify a response posted to the assertion consumer service, then sign in or provision.
+// Responses without a matching pending request are only accepted from providers allowing IdP-initiated sign in.
+// Second factors are left to the identity provider.
+func (s *samlService) Assert(
+	ctx context.Context,
+	providerName, samlResponse, relayState, sealed string,
+	client ClientInfo,
+) (*TokenPair, error) {
+	provider, err := s.registry.Get(providerName)
+	if err != nil {
+		return nil, ErrIdentityProviderNotFound
+	}
+
+	identity, err := provider.ParseResponse(ctx, samlResponse, s.pendingRequest(providerName, relayState, sealed))
+	if err != nil {
+		logger.Logger.Warn("SAML response rejected", zap.String("provider", providerName), zap.Error(err))
+		return nil, ErrIdentityProviderFailed
+	}
+
+	// Assertions are bearer credentials until they expire, each one signs in once
+	first, err := s.revocations.RevokeOnce(ctx, "saml:"+token.HashOpaque(identity.Issuer+" "+identity.AssertionID), identity.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		return nil, ErrTokenInvalid
+	}
+
+	user, err := s.resolveUser(ctx, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// Roles follow the provider's groups on every sign in when it maps them
+	if role := groupRole(lowerKeys(provider.GroupRoles()), identity.Groups, user.Role); role != user.Role {
+		if err = s.userRepo.UpdateRole(user.UserID, role); err != nil {
+			return nil, err
+		}
+		user.Role = role
+	}
+	return s.authService.IssueSession(user, client)
+}
+
+// pendingRequest ID of the AuthnRequest this browser started, empty when there is none
+func (s *samlService) pendingRequest(providerName, relayState, sealed string) string {
+	if sealed == "" {
+		return ""
+	}
+
+	var pending samlState
+	if err := s.sealer.Open(sealed, &pending); err != nil {
+		return ""
+	}
+	if pending.Provider != providerName || pending.State == "" ||
+		subtle.ConstantTimeCompare([]byte(pending.State), []byte(relayState)) != 1 {
+		return ""
+	}
+	return pending.RequestID
+}
+
+// resolveUser Find the account linked to the subject, or create one when the provider allows sign up.
+// Subjects are never attached to an existing account by email.
+func (s *samlService) resolveUser(ctx context.Context, provider *saml.Provider, identity *saml.Identity) (*model.User, error) {
+	linked, err := s.identityRepo.FindByIssuerSubject(identity.Issuer, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.FindByID(linked.UserID)
+		if err != nil {
+			if err.Error() == strconv.Itoa(code.UserNotFound) {
+				return nil, ErrIdentityNotLinked
+			}
+			return nil, err
+		}
+		return user, nil
+	}
+	if err.Error() != strconv.Itoa(code.IdentityNotFound) {
+		return nil, err
+	}
+
+	if !provider.AllowSignUp() || !s.authService.RegistrationOpen() || identity.Email == "" {
+		return nil, ErrIdentityNotLinked
+	}
+	if _, err = s.userRepo.FindByEmail(identity.Email); err == nil {
+		return nil, ErrIdentityNotLinked
+	} else if err.Error() != strconv.Itoa(code.UserNotFound) {
+		return nil, err
+	}
+
+	username, email, err := validateAccount(usernameFromIdentity(identity.Username, identity.Email), identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	// The provider signs the assertion and vouches for the email, as the LDAP and SCIM paths trust theirs
+	user, err := provisionUser(ctx, s.userRepo, s.hasher, s.idGenerator, username, email, true)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.identityRepo.Create(&model.LinkedIdentity{
+		UserID:   user.UserID,
+		Provider: provider.Name(),
+		Issuer:   identity.Issuer,
+		Subject:  identity.Subject,
+		Email:    truncate(identity.Email, 64),
+	}); err != nil {
+		if err.Error() == strconv.Itoa(code.IdentityAlreadyLinked) {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return nil, err
+	}
+	return user, nil
+}
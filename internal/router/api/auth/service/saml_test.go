@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/saml"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/bwmarrin/snowflake"
+)
+
+// fakeIdentityRepo Records linked identities, none exist beforehand
+type fakeIdentityRepo struct {
+	repository.LinkedIdentityRepository
+	created []*model.LinkedIdentity
+}
+
+func (r *fakeIdentityRepo) FindByIssuerSubject(string, string) (*model.LinkedIdentity, error) {
+	return nil, errors.New(strconv.Itoa(code.IdentityNotFound))
+}
+
+func (r *fakeIdentityRepo) Create(identity *model.LinkedIdentity) error {
+	r.created = append(r.created, identity)
+	return nil
+}
+
+// fakeAuthService Open registration, other methods are not used by provisioning
+type fakeAuthService struct {
+	AuthService
+}
+
+func (fakeAuthService) RegistrationOpen() bool {
+	return true
+}
+
+// newTestSAMLProvider Provider signing with a throwaway key, its metadata is never fetched
+func newTestSAMLProvider(t *testing.T) *saml.Provider {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "aurchat"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "sp.crt")
+	keyPath := filepath.Join(dir, "sp.key")
+	if err = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := saml.NewRegistry([]config.SAML{{
+		Name:        "corp",
+		MetadataURL: "https://idp.invalid/metadata",
+		Certificate: certPath,
+		PrivateKey:  keyPath,
+		AllowSignUp: true,
+	}}, "https://chat.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := registry.Get("corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestSAMLProvisionsVerifiedUser(t *testing.T) {
+	node, err := snowflake.NewNode(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userRepo := &fakeUserRepo{}
+	identityRepo := &fakeIdentityRepo{}
+	service := &samlService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		hasher:       newTestHasher(),
+		idGenerator:  node,
+		authService:  fakeAuthService{},
+	}
+
+	user, err := service.resolveUser(context.Background(), newTestSAMLProvider(t), &saml.Identity{
+		Issuer:  "https://idp.example.com",
+		Subject: "alice",
+		Email:   "alice@example.com",
+	})
+	if err != nil {
+		t.Fatalf("resolveUser: %v", err)
+	}
+	// The first sign in must not stop at email verification
+	if user.EmailVerifiedAt == nil {
+		t.Error("provisioned account has an unverified email")
+	}
+	if len(userRepo.created) != 1 || len(identityRepo.created) != 1 {
+		t.Errorf("created %d accounts and %d identities, want 1 each", len(userRepo.created), len(identityRepo.created))
+	}
+}
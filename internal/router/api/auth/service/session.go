@@ -0,0 +1,99 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+)
+
+// lastSeenInterval Minimum interval between two last seen updates of a session
+const lastSeenInterval = time.Minute
+
+var ErrSessionNotFound = NewAuthError(
+	code.SessionNotFound,
+	code.GetMessage(code.SessionNotFound),
+	nil,
+)
+
+type SessionService interface {
+	List(userID int64) ([]*model.Session, error)
+	Rename(userID, sessionID int64, deviceName string) error
+	Revoke(userID, sessionID int64) error
+	RevokeOthers(userID, currentSessionID int64) error
+}
+
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+	refreshRepo repository.RefreshTokenRepository
+}
+
+func NewSessionService(
+	sessionRepo repository.SessionRepository,
+	refreshRepo repository.RefreshTokenRepository,
+) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+		refreshRepo: refreshRepo,
+	}
+}
+
+func (s *sessionService) List(userID int64) ([]*model.Session, error) {
+	return s.sessionRepo.ListActiveByUserID(userID)
+}
+
+func (s *sessionService) Rename(userID, sessionID int64, deviceName string) error {
+	if err := s.sessionRepo.Rename(userID, sessionID, truncate(deviceName, 64)); err != nil {
+		if err.Error() == strconv.Itoa(code.SessionNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *sessionService) Revoke(userID, sessionID int64) error {
+	session, err := s.sessionRepo.FindBySessionID(sessionID)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.SessionNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	if session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+
+	return revokeSession(s.sessionRepo, s.refreshRepo, sessionID)
+}
+
+func (s *sessionService) RevokeOthers(userID, currentSessionID int64) error {
+	sessionIDs, err := s.sessionRepo.RevokeByUserID(userID, currentSessionID)
+	if err != nil {
+		return err
+	}
+	return s.refreshRepo.RevokeFamilies(sessionIDs)
+}
+
+// revokeSession Revoke a session and the refresh tokens issued within it.
+// Access tokens of the session are rejected by ValidateToken from then on.
+func revokeSession(
+	sessionRepo repository.SessionRepository,
+	refreshRepo repository.RefreshTokenRepository,
+	sessionID int64,
+) error {
+	if err := sessionRepo.Revoke(sessionID); err != nil {
+		return err
+	}
+	return refreshRepo.RevokeFamily(sessionID)
+}
+
+func truncate(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max])
+}
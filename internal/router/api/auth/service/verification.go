@@ -0,0 +1,117 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/mailer"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	verificationTTL  = 24 * time.Hour
+	verificationPath = "/verify-email"
+)
+
+var ErrEmailNotVerified = NewAuthError(
+	code.EmailNotVerified,
+	code.GetMessage(code.EmailNotVerified),
+	nil,
+)
+
+type VerificationService interface {
+	SendVerification(user *model.User) error
+	Verify(token string) error
+	Resend(email, ip string) error
+}
+
+type verificationService struct {
+	userRepo     repository.UserRepository
+	tokenRepo    repository.OneTimeTokenRepository
+	mailer       mailer.Mailer
+	baseURL      string
+	mailThrottle *MailThrottle
+}
+
+func NewVerificationService(
+	userRepo repository.UserRepository,
+	tokenRepo repository.OneTimeTokenRepository,
+	mailer mailer.Mailer,
+	baseURL string,
+	mailThrottle *MailThrottle,
+) VerificationService {
+	return &verificationService{
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		mailer:       mailer,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		mailThrottle: mailThrottle,
+	}
+}
+
+// SendVerification Email a fresh verification link, burning any earlier one
+func (s *verificationService) SendVerification(user *model.User) error {
+	raw, err := issueOneTimeToken(s.tokenRepo, user.UserID, model.TokenPurposeVerifyEmail, verificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.baseURL + verificationPath + "?token=" + url.QueryEscape(raw)
+	return sendMail(s.mailer, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your AurChat email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s\n\nThe link expires in 24 hours. If you did not create an AurChat account, ignore this email.\n",
+			user.Username, link,
+		),
+	})
+}
+
+// Verify Consume a verification token and mark its user's email as verified
+func (s *verificationService) Verify(raw string) error {
+	stored, err := consumeOneTimeToken(s.tokenRepo, model.TokenPurposeVerifyEmail, raw)
+	if err != nil {
+		return err
+	}
+
+	if err = s.userRepo.MarkEmailVerified(stored.UserID, time.Now()); err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return ErrTokenInvalid
+		}
+		return err
+	}
+	return nil
+}
+
+// Resend Send a new verification link. Unknown or verified addresses are ignored silently, and the
+// mail is sent in the background so the response time does not tell whether the address has an account.
+func (s *verificationService) Resend(email, ip string) error {
+	if err := s.mailThrottle.Allow(email, ip); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	go func() {
+		if err := s.SendVerification(user); err != nil {
+			logger.Logger.Warn("Error sending verification email", zap.Int64("userID", user.UserID), zap.Error(err))
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.uber.org/zap"
+)
+
+const (
+	WebAuthnCeremonyPurpose = "webauthn"
+	WebAuthnCeremonyTTL     = 300 // Seconds to complete a ceremony
+
+	ceremonyRegister = "register"
+	ceremonyLogin    = "login"
+)
+
+var (
+	ErrPasskeyVerification = NewAuthError(
+		code.PasskeyVerificationFailed,
+		code.GetMessage(code.PasskeyVerificationFailed),
+		nil,
+	)
+
+	ErrPasskeyNotFound = NewAuthError(
+		code.PasskeyNotFound,
+		code.GetMessage(code.PasskeyNotFound),
+		nil,
+	)
+)
+
+// ceremonyState Ceremony data sealed into a cookie between the begin and finish calls
+type ceremonyState struct {
+	Kind    string               `json:"kind"`
+	UserID  int64                `json:"uid,omitempty"` // Registering user, or the user named at sign in
+	Session webauthn.SessionData `json:"session"`
+}
+
+type WebAuthnService interface {
+	BeginRegistration(ctx context.Context, user *model.User, password, passcode string) (*protocol.CredentialCreation, string, error)
+	FinishRegistration(user *model.User, ceremony, name string, response *protocol.ParsedCredentialCreationData) (*model.WebAuthnCredential, error)
+	BeginLogin(username string) (*protocol.CredentialAssertion, string, error)
+	FinishLogin(ceremony string, response *protocol.ParsedCredentialAssertionData, client ClientInfo) (*TokenPair, error)
+	List(userID int64) ([]*model.WebAuthnCredential, error)
+	Rename(userID int64, id uint, name string) error
+	Delete(userID int64, id uint) error
+}
+
+type webAuthnService struct {
+	webAuthn       *webauthn.WebAuthn
+	userRepo       repository.UserRepository
+	credentialRepo repository.WebAuthnCredentialRepository
+	sealer         *token.Sealer
+	authService    AuthService
+	mfaService     MFAService
+}
+
+func NewWebAuthnService(
+	webAuthn *webauthn.WebAuthn,
+	userRepo repository.UserRepository,
+	credentialRepo repository.WebAuthnCredentialRepository,
+	sealer *token.Sealer,
+	authService AuthService,
+	mfaService MFAService,
+) WebAuthnService {
+	return &webAuthnService{
+		webAuthn:       webAuthn,
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+		sealer:         sealer,
+		authService:    authService,
+		mfaService:     mfaService,
+	}
+}
+
+// BeginRegistration Create the options for a new passkey, excluding authenticators the user already registered.
+// Passkeys sign in without the second factor, so adding one needs the user to re-authenticate.
+func (s *webAuthnService) BeginRegistration(
+	ctx context.Context,
+	user *model.User,
+	password, passcode string,
+) (*protocol.CredentialCreation, string, error) {
+	if err := s.mfaService.Reauthenticate(ctx, user, password, passcode); err != nil {
+		return nil, "", err
+	}
+
+	account, err := s.loadAccount(user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	creation, session, err := s.webAuthn.BeginRegistration(account,
+		webauthn.WithExclusions(webauthn.Credentials(account.credentials).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ceremony, err := s.sealer.Seal(ceremonyState{Kind: ceremonyRegister, UserID: user.UserID, Session: *session})
+	if err != nil {
+		return nil, "", err
+	}
+	return creation, ceremony, nil
+}
+
+// FinishRegistration Verify the attestation and store the new credential
+func (s *webAuthnService) FinishRegistration(
+	user *model.User,
+	ceremony, name string,
+	response *protocol.ParsedCredentialCreationData,
+) (*model.WebAuthnCredential, error) {
+	state, err := s.openCeremony(ceremony, ceremonyRegister)
+	if err != nil {
+		return nil, err
+	}
+	if state.UserID != user.UserID {
+		return nil, ErrTokenInvalid
+	}
+
+	account, err := s.loadAccount(user)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webAuthn.CreateCredential(account, state.Session, response)
+	if err != nil {
+		logger.Logger.Info("Passkey registration rejected", zap.Int64("userID", user.UserID), zap.Error(err))
+		return nil, ErrPasskeyVerification
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	stored := &model.WebAuthnCredential{
+		UserID:          user.UserID,
+		CredentialID:    credential.ID,
+		Name:            truncate(name, 64),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err = s.credentialRepo.Create(stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// BeginLogin Create the options for a passkey sign in. Without a username the
+// authenticator offers its discoverable credentials for this site. An unknown username, or an
+// account without passkeys, gets the same discoverable options so the answer does not reveal which.
+func (s *webAuthnService) BeginLogin(username string) (*protocol.CredentialAssertion, string, error) {
+	state := ceremonyState{Kind: ceremonyLogin}
+
+	var account *passkeyAccount
+	if username != "" {
+		user, err := s.userRepo.FindByUsername(username)
+		if err != nil && err.Error() != strconv.Itoa(code.UserNotFound) {
+			return nil, "", err
+		}
+		if user != nil {
+			if account, err = s.loadAccount(user); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	var (
+		assertion *protocol.CredentialAssertion
+		session   *webauthn.SessionData
+		err       error
+	)
+	if account == nil || len(account.credentials) == 0 {
+		assertion, session, err = s.webAuthn.BeginDiscoverableLogin(
+			webauthn.WithUserVerification(protocol.VerificationRequired),
+		)
+	} else {
+		state.UserID = account.user.UserID
+		assertion, session, err = s.webAuthn.BeginLogin(account,
+			webauthn.WithUserVerification(protocol.VerificationRequired),
+		)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	state.Session = *session
+	ceremony, err := s.sealer.Seal(state)
+	if err != nil {
+		return nil, "", err
+	}
+	return assertion, ceremony, nil
+}
+
+// FinishLogin Verify the assertion and start a session, as a password sign in would
+func (s *webAuthnService) FinishLogin(
+	ceremony string,
+	response *protocol.ParsedCredentialAssertionData,
+	client ClientInfo,
+) (*TokenPair, error) {
+	state, err := s.openCeremony(ceremony, ceremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		account    *passkeyAccount
+		credential *webauthn.Credential
+	)
+	if state.UserID == 0 {
+		var user webauthn.User
+		user, credential, err = s.webAuthn.ValidatePasskeyLogin(s.discoverAccount, state.Session, response)
+		if err == nil {
+			account = user.(*passkeyAccount)
+		}
+	} else {
+		user, findErr := s.userRepo.FindByID(state.UserID)
+		if findErr != nil {
+			return nil, ErrPasskeyVerification
+		}
+		if account, err = s.loadAccount(user); err != nil {
+			return nil, err
+		}
+		credential, err = s.webAuthn.ValidateLogin(account, state.Session, response)
+	}
+	if err != nil {
+		logger.Logger.Info("Passkey sign in rejected", zap.Error(err))
+		return nil, ErrPasskeyVerification
+	}
+
+	stored := account.find(credential.ID)
+	if stored == nil {
+		return nil, ErrPasskeyVerification
+	}
+	if credential.Authenticator.CloneWarning {
+		logger.Logger.Warn("Passkey signature counter went backwards, possible cloned authenticator",
+			zap.Int64("userID", account.user.UserID),
+			zap.Uint("credentialID", stored.ID),
+		)
+		return nil, ErrPasskeyVerification
+	}
+
+	if err = s.credentialRepo.RecordUse(
+		stored.ID,
+		credential.Authenticator.SignCount,
+		credential.Flags.BackupState,
+		time.Now(),
+	); err != nil {
+		return nil, err
+	}
+
+	return s.authService.IssueSession(account.user, client)
+}
+
+func (s *webAuthnService) List(userID int64) ([]*model.WebAuthnCredential, error) {
+	return s.credentialRepo.ListByUserID(userID)
+}
+
+func (s *webAuthnService) Rename(userID int64, id uint, name string) error {
+	return mapPasskeyError(s.credentialRepo.Rename(userID, id, truncate(name, 64)))
+}
+
+func (s *webAuthnService) Delete(userID int64, id uint) error {
+	return mapPasskeyError(s.credentialRepo.Delete(userID, id))
+}
+
+func (s *webAuthnService) openCeremony(ceremony, kind string) (*ceremonyState, error) {
+	var state ceremonyState
+	if err := s.sealer.Open(ceremony, &state); err != nil {
+		if errors.Is(err, token.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		if errors.Is(err, token.ErrRevocation) {
+			return nil, err
+		}
+		return nil, ErrTokenInvalid
+	}
+	if state.Kind != kind {
+		return nil, ErrTokenInvalid
+	}
+	return &state, nil
+}
+
+// discoverAccount Resolve the account named by the user handle of a discoverable credential
+func (s *webAuthnService) discoverAccount(_, userHandle []byte) (webauthn.User, error) {
+	if len(userHandle) != 8 {
+		return nil, ErrPasskeyVerification
+	}
+
+	user, err := s.userRepo.FindByID(int64(binary.BigEndian.Uint64(userHandle)))
+	if err != nil {
+		return nil, err
+	}
+	return s.loadAccount(user)
+}
+
+func (s *webAuthnService) loadAccount(user *model.User) (*passkeyAccount, error) {
+	stored, err := s.credentialRepo.ListByUserID(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &passkeyAccount{user: user, stored: stored}
+	for _, credential := range stored {
+		account.credentials = append(account.credentials, toWebAuthnCredential(credential))
+	}
+	return account, nil
+}
+
+func mapPasskeyError(err error) error {
+	if err != nil && err.Error() == strconv.Itoa(code.PasskeyNotFound) {
+		return ErrPasskeyNotFound
+	}
+	return err
+}
+
+func toWebAuthnCredential(stored *model.WebAuthnCredential) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	if stored.Transports != "" {
+		for _, transport := range strings.Split(stored.Transports, ",") {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+
+	return webauthn.Credential{
+		ID:              stored.CredentialID,
+		PublicKey:       stored.PublicKey,
+		AttestationType: stored.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: stored.BackupEligible,
+			BackupState:    stored.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    stored.AAGUID,
+			SignCount: stored.SignCount,
+		},
+	}
+}
+
+// passkeyAccount Adapts a user and their stored credentials to the webauthn library
+type passkeyAccount struct {
+	user        *model.User
+	stored      []*model.WebAuthnCredential
+	credentials []webauthn.Credential
+}
+
+// WebAuthnID User handle, the snowflake user ID as 8 big-endian bytes
+func (a *passkeyAccount) WebAuthnID() []byte {
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, uint64(a.user.UserID))
+	return id
+}
+
+func (a *passkeyAccount) WebAuthnName() string {
+	return a.user.Username
+}
+
+func (a *passkeyAccount) WebAuthnDisplayName() string {
+	return a.user.Username
+}
+
+func (a *passkeyAccount) WebAuthnCredentials() []webauthn.Credential {
+	return a.credentials
+}
+
+func (a *passkeyAccount) find(credentialID []byte) *model.WebAuthnCredential {
+	for _, stored := range a.stored {
+		if string(stored.CredentialID) == string(credentialID) {
+			return stored
+		}
+	}
+	return nil
+}
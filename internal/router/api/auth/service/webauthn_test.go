@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+)
+
+func TestBeginRegistrationReauthenticates(t *testing.T) {
+	ctx := context.Background()
+	hasher := newTestHasher()
+	hash, err := hasher.Hash(ctx, "correct-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &fakeUserRepo{}
+	mfa := NewMFAService(user, &fakeRecoveryRepo{}, hasher, newTestLimiter())
+	// Reauthentication fails before the ceremony, so no relying party is needed
+	passkeys := NewWebAuthnService(nil, user, nil, nil, nil, mfa)
+
+	account := &model.User{UserID: 1, Username: "alice", Password: hash}
+	if _, _, err = passkeys.BeginRegistration(ctx, account, "", ""); !errors.Is(err, ErrAccountnameOrPassword) {
+		t.Errorf("session only: err = %v, want ErrAccountnameOrPassword", err)
+	}
+	if _, _, err = passkeys.BeginRegistration(ctx, account, "wrong", ""); !errors.Is(err, ErrAccountnameOrPassword) {
+		t.Errorf("wrong password: err = %v, want ErrAccountnameOrPassword", err)
+	}
+}
@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/scim/service"
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandler struct {
+	groupService   service.GroupService
+	location       string
+	memberLocation string
+}
+
+func NewGroupHandler(groupService service.GroupService, baseURL string) *GroupHandler {
+	return &GroupHandler{
+		groupService:   groupService,
+		location:       baseURL + "/api/v1/scim/v2/Groups/",
+		memberLocation: baseURL + "/api/v1/scim/v2/Users/",
+	}
+}
+
+// List godoc
+// @Summary      List groups
+// @Description  Page through provisioned groups, optionally narrowed by a SCIM filter such as displayName eq "Engineering"
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Param        filter              query  string  false  "SCIM filter"
+// @Param        startIndex          query  int     false  "1-based index of the first result"
+// @Param        count               query  int     false  "Page size, at most 200"
+// @Param        excludedAttributes  query  string  false  "members to leave the member lists out"
+// @Success      200  {object}  dto.SCIMListResp
+// @Failure      400  {object}  dto.SCIMErrorResp
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Groups [get]
+func (h *GroupHandler) List(c *gin.Context) {
+	startIndex, count := pagination(c)
+	groups, total, err := h.groupService.List(c.Query("filter"), startIndex-1, count)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	withMembers := !excludesAttribute(c, "members")
+	resources := make([]any, 0, len(groups))
+	for _, group := range groups {
+		resource, err := h.toResource(group, withMembers)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+	writeResource(c, http.StatusOK, dto.SCIMListResp{
+		Schemas:      []string{dto.SCIMListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// Get godoc
+// @Summary      Get a group
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id                  path   string  true   "Group ID"
+// @Param        excludedAttributes  query  string  false  "members to leave the member list out"
+// @Success      200  {object}  dto.SCIMGroup
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      404  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Groups/{id} [get]
+func (h *GroupHandler) Get(c *gin.Context) {
+	id, ok := groupID(c)
+	if !ok {
+		return
+	}
+
+	group, err := h.groupService.Get(id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	h.writeGroup(c, http.StatusOK, group, !excludesAttribute(c, "members"))
+}
+
+// Create godoc
+// @Summary      Provision a group
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body  body  dto.SCIMGroup  true  "Group"
+// @Success      201  {object}  dto.SCIMGroup
+// @Failure      400  {object}  dto.SCIMErrorResp
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      409  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Groups [post]
+func (h *GroupHandler) Create(c *gin.Context) {
+	var req dto.SCIMGroup
+	if !bindResource(c, &req) {
+		return
+	}
+	attrs, err := toGroupAttributes(req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	group, err := h.groupService.Create(attrs)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Header("Location", h.location+strconv.FormatUint(uint64(group.ID), 10))
+	h.writeGroup(c, http.StatusCreated, group, true)
+}
+
+// Replace godoc
+// @Summary      Replace a group
+// @Description  Overwrite the group's name and its full member list
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path  string         true  "Group ID"
+// @Param        body  body  dto.SCIMGroup  true  "Group"
+// @Success      200  {object}  dto.SCIMGroup
+// @Failure      400  {object}  dto.SCIMErrorResp
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      404  {object}  dto.SCIMErrorResp
+// @Failure      409  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Groups/{id} [put]
+func (h *GroupHandler) Replace(c *gin.Context) {
+	id, ok := groupID(c)
+	if !ok {
+		return
+	}
+	var req dto.SCIMGroup
+	if !bindResource(c, &req) {
+		return
+	}
+	attrs, err := toGroupAttributes(req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	group, err := h.groupService.Replace(id, attrs)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	h.writeGroup(c, http.StatusOK, group, true)
+}
+
+// Patch godoc
+// @Summary      Update a group
+// @Description  Rename the group or add, remove and replace members
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path  string            true  "Group ID"
+// @Param        body  body  dto.SCIMPatchReq  true  "Operations"
+// @Success      200  {object}  dto.SCIMGroup
+// @Failure      400  {object}  dto.SCIMErrorResp
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      404  {object}  dto.SCIMErrorResp
+// @Failure      409  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Groups/{id} [patch]
+func (h *GroupHandler) Patch(c *gin.Context) {
+	id, ok := groupID(c)
+	if !ok {
+		return
+	}
+	operations, ok := patchOperations(c)
+	if !ok {
+		return
+	}
+
+	group, err := h.groupService.Patch(id, operations)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	h.writeGroup(c, http.StatusOK, group, !excludesAttribute(c, "members"))
+}
+
+// Delete godoc
+// @Summary      Delete a group
+// @Description  Delete the group and its memberships, the member accounts are untouched
+// @Tags         SCIM
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Group ID"
+// @Success      204
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      404  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Groups/{id} [delete]
+func (h *GroupHandler) Delete(c *gin.Context) {
+	id, ok := groupID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.groupService.Delete(id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// writeGroup Write a single group resource
+func (h *GroupHandler) writeGroup(c *gin.Context, status int, group *model.Group, withMembers bool) {
+	resource, err := h.toResource(group, withMembers)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	writeResource(c, status, resource)
+}
+
+// toResource Convert a group to its SCIM representation, loading the members when asked
+func (h *GroupHandler) toResource(group *model.Group, withMembers bool) (dto.SCIMGroup, error) {
+	id := strconv.FormatUint(uint64(group.ID), 10)
+	resource := dto.SCIMGroup{
+		Schemas:     []string{dto.SCIMGroupSchema},
+		ID:          id,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Meta: &dto.SCIMMeta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt,
+			LastModified: group.UpdatedAt,
+			Location:     h.location + id,
+		},
+	}
+	if !withMembers {
+		return resource, nil
+	}
+
+	members, err := h.groupService.Members(group.ID)
+	if err != nil {
+		return dto.SCIMGroup{}, err
+	}
+	resource.Members = make([]dto.SCIMMember, 0, len(members))
+	for _, member := range members {
+		memberID := strconv.FormatInt(member.UserID, 10)
+		resource.Members = append(resource.Members, dto.SCIMMember{
+			Value:   memberID,
+			Ref:     h.memberLocation + memberID,
+			Display: member.Username,
+		})
+	}
+	return resource, nil
+}
+
+// groupID Parse the group ID from the path, unknown formats are reported like unknown groups
+func groupID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, strconv.IntSize)
+	if err != nil || id == 0 {
+		writeError(c, service.ErrGroupNotFound)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// toGroupAttributes Read the group attributes, members are referenced by user ID
+func toGroupAttributes(req dto.SCIMGroup) (service.GroupAttributes, error) {
+	attrs := service.GroupAttributes{
+		DisplayName: req.DisplayName,
+		ExternalID:  req.ExternalID,
+		Members:     make([]int64, 0, len(req.Members)),
+	}
+	for _, member := range req.Members {
+		id, err := strconv.ParseInt(member.Value, 10, 64)
+		if err != nil {
+			return service.GroupAttributes{}, service.ErrMemberUnknown
+		}
+		attrs.Members = append(attrs.Members, id)
+	}
+	return attrs, nil
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/scim/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	scimContentType = "application/scim+json"
+	defaultCount    = 100
+	maxCount        = 200
+)
+
+// RequireToken Only let through requests carrying the provisioning client's bearer token.
+// Session cookies are never accepted here, the API is for machines only.
+func RequireToken(token string) gin.HandlerFunc {
+	expected := []byte(token)
+	return func(c *gin.Context) {
+		scheme, value, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimSpace(value)), expected) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="aurchat-scim"`)
+			writeError(c, service.NewSCIMError(http.StatusUnauthorized, "", "Invalid provisioning token"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ServiceProviderConfig godoc
+// @Summary      SCIM service provider configuration
+// @Description  Features of the SCIM API, read by identity providers when the connection is set up
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  dto.SCIMServiceConfigResp
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/ServiceProviderConfig [get]
+func ServiceProviderConfig(c *gin.Context) {
+	writeResource(c, http.StatusOK, dto.SCIMServiceConfigResp{
+		Schemas: []string{dto.SCIMServiceConfigSchema},
+		Patch:   dto.SCIMSupported{Supported: true},
+		Filter:  dto.SCIMFilterConfig{Supported: true, MaxResults: maxCount},
+		AuthenticationSchemes: []dto.SCIMAuthScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer token",
+			Description: "Provisioning token from the server configuration",
+		}},
+	})
+}
+
+// writeResource Write a SCIM body with the SCIM media type
+func writeResource(c *gin.Context, status int, body any) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, body)
+}
+
+// writeError Write SCIM errors as they are, and anything else as an opaque server error
+func writeError(c *gin.Context, err error) {
+	var scimErr *service.SCIMError
+	if errors.Is(err, hasher.ErrBusy) {
+		c.Header("Retry-After", "1")
+		scimErr = service.NewSCIMError(http.StatusServiceUnavailable, "", "Password hashing is at capacity, retry later")
+	} else if !errors.As(err, &scimErr) {
+		logger.Logger.Error("SCIM request failed", zap.Error(err))
+		scimErr = service.NewSCIMError(http.StatusInternalServerError, "", "Internal server error")
+	}
+
+	writeResource(c, scimErr.Status, dto.SCIMErrorResp{
+		Schemas:  []string{dto.SCIMErrorSchema},
+		Status:   strconv.Itoa(scimErr.Status),
+		ScimType: scimErr.ScimType,
+		Detail:   scimErr.Detail,
+	})
+}
+
+// bindResource Decode a request body, reporting malformed JSON as invalidSyntax
+func bindResource(c *gin.Context, body any) bool {
+	if err := c.ShouldBindJSON(body); err != nil {
+		writeError(c, service.NewSCIMError(http.StatusBadRequest, service.ScimTypeInvalidSyntax, "Malformed request body"))
+		return false
+	}
+	return true
+}
+
+// patchOperations Convert a PATCH request, keeping each value raw for the service to decode per path
+func patchOperations(c *gin.Context) ([]service.PatchOperation, bool) {
+	var req struct {
+		Operations []struct {
+			Op    string   `json:"op"`
+			Path  string   `json:"path"`
+			Value rawValue `json:"value"`
+		} `json:"Operations"`
+	}
+	if !bindResource(c, &req) {
+		return nil, false
+	}
+	if len(req.Operations) == 0 {
+		writeError(c, service.NewSCIMError(http.StatusBadRequest, service.ScimTypeInvalidSyntax, "No operations"))
+		return nil, false
+	}
+
+	operations := make([]service.PatchOperation, 0, len(req.Operations))
+	for _, op := range req.Operations {
+		operations = append(operations, service.PatchOperation{Op: op.Op, Path: op.Path, Value: []byte(op.Value)})
+	}
+	return operations, true
+}
+
+// rawValue A JSON value kept undecoded, a JSON null is kept as no value
+type rawValue []byte
+
+func (v *rawValue) UnmarshalJSON(data []byte) error {
+	if string(data) != "null" {
+		*v = append((*v)[:0], data...)
+	}
+	return nil
+}
+
+// pagination Read startIndex and count, which are 1-based and clamped as RFC 7644 allows
+func pagination(c *gin.Context) (startIndex, count int) {
+	startIndex, err := strconv.Atoi(c.Query("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err = strconv.Atoi(c.Query("count"))
+	if err != nil {
+		count = defaultCount
+	}
+	return startIndex, min(max(count, 0), maxCount)
+}
+
+// excludesAttribute Whether the excludedAttributes parameter names the attribute
+func excludesAttribute(c *gin.Context, attribute string) bool {
+	for _, excluded := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(excluded), attribute) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/scim/service"
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	userService service.UserService
+	location    string
+}
+
+func NewUserHandler(userService service.UserService, baseURL string) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+		location:    baseURL + "/api/v1/scim/v2/Users/",
+	}
+}
+
+// List godoc
+// @Summary      List users
+// @Description  Page through provisioned users, optionally narrowed by a SCIM filter such as userName eq "alice"
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Param        filter      query  string  false  "SCIM filter"
+// @Param        startIndex  query  int     false  "1-based index of the first result"
+// @Param        count       query  int     false  "Page size, at most 200"
+// @Success      200  {object}  dto.SCIMListResp
+// @Failure      400  {object}  dto.SCIMErrorResp
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Users [get]
+func (h *UserHandler) List(c *gin.Context) {
+	startIndex, count := pagination(c)
+	users, total, err := h.userService.List(c.Query("filter"), startIndex-1, count)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	resources := make([]any, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, h.toResource(user))
+	}
+	writeResource(c, http.StatusOK, dto.SCIMListResp{
+		Schemas:      []string{dto.SCIMListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// Get godoc
+// @Summary      Get a user
+// @Tags         SCIM
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path  string  true  "User ID"
+// @Success      200  {object}  dto.SCIMUser
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      404  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Users/{id} [get]
+func (h *UserHandler) Get(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.Get(id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	writeResource(c, http.StatusOK, h.toResource(user))
+}
+
+// Create godoc
+// @Summary      Provision a user
+// @Description  Create an account with a verified email. Without a password the account signs in through SSO or a magic link.
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body  body  dto.SCIMUser  true  "User"
+// @Success      201  {object}  dto.SCIMUser
+// @Failure      400  {object}  dto.SCIMErrorResp
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      409  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Users [post]
+func (h *UserHandler) Create(c *gin.Context) {
+	var req dto.SCIMUser
+	if !bindResource(c, &req) {
+		return
+	}
+
+	user, err := h.userService.Create(c.Request.Context(), toUserAttributes(req))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Header("Location", h.location+strconv.FormatInt(user.UserID, 10))
+	writeResource(c, http.StatusCreated, h.toResource(user))
+}
+
+// Replace godoc
+// @Summary      Replace a user
+// @Description  Overwrite every attribute of the user. active=false disables the account and ends its sessions.
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path  string        true  "User ID"
+// @Param        body  body  dto.SCIMUser  true  "User"
+// @Success      200  {object}  dto.SCIMUser
+// @Failure      400  {object}  dto.SCIMErrorResp
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      404  {object}  dto.SCIMErrorResp
+// @Failure      409  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Users/{id} [put]
+func (h *UserHandler) Replace(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	var req dto.SCIMUser
+	if !bindResource(c, &req) {
+		return
+	}
+
+	user, err := h.userService.Replace(c.Request.Context(), id, toUserAttributes(req))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	writeResource(c, http.StatusOK, h.toResource(user))
+}
+
+// Patch godoc
+// @Summary      Update a user
+// @Description  Apply add, replace and remove operations, such as replacing active with false to disable the account
+// @Tags         SCIM
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path  string            true  "User ID"
+// @Param        body  body  dto.SCIMPatchReq  true  "Operations"
+// @Success      200  {object}  dto.SCIMUser
+// @Failure      400  {object}  dto.SCIMErrorResp
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      404  {object}  dto.SCIMErrorResp
+// @Failure      409  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Users/{id} [patch]
+func (h *UserHandler) Patch(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	operations, ok := patchOperations(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.Patch(c.Request.Context(), id, operations)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	writeResource(c, http.StatusOK, h.toResource(user))
+}
+
+// Delete godoc
+// @Summary      Deprovision a user
+// @Description  Disable the account and end its sessions. The account is kept so its messages and memberships survive.
+// @Tags         SCIM
+// @Security     BearerAuth
+// @Param        id   path  string  true  "User ID"
+// @Success      204
+// @Failure      401  {object}  dto.SCIMErrorResp
+// @Failure      404  {object}  dto.SCIMErrorResp
+// @Failure      500  {object}  dto.SCIMErrorResp
+// @Router       /scim/v2/Users/{id} [delete]
+func (h *UserHandler) Delete(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.Deactivate(id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// toResource Convert an account to its SCIM representation
+func (h *UserHandler) toResource(user *model.User) dto.SCIMUser {
+	id := strconv.FormatInt(user.UserID, 10)
+	active := user.DisabledAt == nil
+	resource := dto.SCIMUser{
+		Schemas:    []string{dto.SCIMUserSchema},
+		ID:         id,
+		ExternalID: user.ExternalID,
+		UserName:   user.Username,
+		Active:     &active,
+		Meta: &dto.SCIMMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     h.location + id,
+		},
+	}
+	if user.Email != "" {
+		resource.Emails = []dto.SCIMEmail{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	return resource
+}
+
+// userID Parse the user ID from the path, unknown formats are reported like unknown users
+func userID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(c, service.ErrUserNotFound)
+		return 0, false
+	}
+	return id, true
+}
+
+// toUserAttributes Read the attributes AurChat stores from a SCIM user
+func toUserAttributes(req dto.SCIMUser) service.UserAttributes {
+	attrs := service.UserAttributes{
+		UserName:   req.UserName,
+		ExternalID: req.ExternalID,
+		Active:     req.Active == nil || *req.Active,
+		Password:   req.Password,
+	}
+	for i, email := range req.Emails {
+		if i == 0 || email.Primary {
+			attrs.Email = email.Value
+		}
+		if email.Primary {
+			break
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GroupRepository interface {
+	Create(group *model.Group, memberIDs []int64) error
+	FindByID(id uint) (*model.Group, error)
+	Search(condition string, args []any, offset, limit int) ([]*model.Group, int64, error)
+	Update(group *model.Group) error
+	Delete(id uint) error
+	ListMembers(groupID uint) ([]*model.User, error)
+	AddMembers(groupID uint, userIDs []int64) error
+	RemoveMembers(groupID uint, userIDs []int64) error
+	ReplaceMembers(groupID uint, userIDs []int64) error
+	// Transaction Run fn with a repository whose writes commit together, or not at all when fn fails
+	Transaction(fn func(tx GroupRepository) error) error
+}
+
+type groupRepository struct {
+	db *gorm.DB
+}
+
+func NewGroupRepository(db *gorm.DB) GroupRepository {
+	return &groupRepository{db: db}
+}
+
+// Create Store a new group with its initial members
+func (r *groupRepository) Create(group *model.Group, memberIDs []int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(group).Error; err != nil {
+			return groupError(err)
+		}
+		return addMembers(tx, group.ID, memberIDs)
+	})
+}
+
+// Transaction Run fn inside one database transaction, the transactions of the methods
+// below become savepoints within it
+func (r *groupRepository) Transaction(fn func(tx GroupRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&groupRepository{db: tx})
+	})
+}
+
+func (r *groupRepository) FindByID(id uint) (*model.Group, error) {
+	var group model.Group
+	if err := r.db.Where("id = ?", id).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(strconv.Itoa(code.GroupNotFound))
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// Search Groups matching a SQL condition, oldest first, with the total number of matches.
+// An empty condition matches every group.
+func (r *groupRepository) Search(condition string, args []any, offset, limit int) ([]*model.Group, int64, error) {
+	query := r.db.Model(&model.Group{})
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+	// Shared by the count and the page
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var groups []*model.Group
+	if err := query.Order("id").Offset(offset).Limit(limit).Find(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+// Update Save the group name and external ID
+func (r *groupRepository) Update(group *model.Group) error {
+	if err := r.db.Save(group).Error; err != nil {
+		return groupError(err)
+	}
+	return nil
+}
+
+// Delete Remove the group and its memberships, the members' accounts are untouched
+func (r *groupRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&model.Group{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(strconv.Itoa(code.GroupNotFound))
+		}
+		return tx.Where("group_id = ?", id).Delete(&model.GroupMember{}).Error
+	})
+}
+
+// ListMembers Accounts in the group, in the order they joined
+func (r *groupRepository) ListMembers(groupID uint) ([]*model.User, error) {
+	var users []*model.User
+	if err := r.db.Joins("JOIN group_members ON group_members.user_id = users.user_id").
+		Where("group_members.group_id = ?", groupID).
+		Order("group_members.created_at, users.id").
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// AddMembers Add accounts to the group, ignoring those already in it
+func (r *groupRepository) AddMembers(groupID uint, userIDs []int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := touchGroup(tx, groupID); err != nil {
+			return err
+		}
+		return addMembers(tx, groupID, userIDs)
+	})
+}
+
+// RemoveMembers Remove accounts from the group, ignoring those not in it
+func (r *groupRepository) RemoveMembers(groupID uint, userIDs []int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := touchGroup(tx, groupID); err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+		return tx.Where("group_id = ? AND user_id IN ?", groupID, userIDs).Delete(&model.GroupMember{}).Error
+	})
+}
+
+// ReplaceMembers Make the group hold exactly the given accounts
+func (r *groupRepository) ReplaceMembers(groupID uint, userIDs []int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := touchGroup(tx, groupID); err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&model.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return addMembers(tx, groupID, userIDs)
+	})
+}
+
+// addMembers Insert memberships after checking every account exists and is not a bot
+func addMembers(tx *gorm.DB, groupID uint, userIDs []int64) error {
+	userIDs = slices.Compact(slices.Sorted(slices.Values(userIDs)))
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var found int64
+	if err := tx.Model(&model.User{}).Where("user_id IN ? AND NOT bot", userIDs).Count(&found).Error; err != nil {
+		return err
+	}
+	if found != int64(len(userIDs)) {
+		return errors.New(strconv.Itoa(code.UserNotFound))
+	}
+
+	members := make([]model.GroupMember, 0, len(userIDs))
+	for _, userID := range userIDs {
+		members = append(members, model.GroupMember{GroupID: groupID, UserID: userID})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error
+}
+
+// touchGroup Bump the group's modification time, failing when it does not exist
+func touchGroup(tx *gorm.DB, groupID uint) error {
+	result := tx.Model(&model.Group{}).Where("id = ?", groupID).Update("updated_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(strconv.Itoa(code.GroupNotFound))
+	}
+	return nil
+}
+
+// groupError Report a name taken by a concurrent write to the unique index
+func groupError(err error) error {
+	if strings.Contains(err.Error(), "23505") {
+		return errors.New(strconv.Itoa(code.GroupAlreadyExists))
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package scim
+
+import (
+	"github.com/AurChatOrg/aurchat-server/internal/config"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/policy"
+	"github.com/AurChatOrg/aurchat-server/internal/repo"
+	authRepository "github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/scim/handler"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/scim/repository"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/scim/service"
+	"github.com/bwmarrin/snowflake"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// minTokenLength Shortest provisioning token accepted, the token is the only credential of the API
+const minTokenLength = 32
+
+// RegisterSCIMAPI Register the SCIM 2.0 provisioning API when it is enabled
+func RegisterSCIMAPI(route *gin.RouterGroup, node *snowflake.Node, hasher *hasher.Hasher) {
+	cfg := config.Cfg.SCIM
+	if !cfg.Enabled {
+		return
+	}
+	if len(cfg.Token) < minTokenLength {
+		logger.Logger.Fatal("SCIM needs a provisioning token of at least 32 characters")
+	}
+
+	passwordPolicy, err := policy.NewPasswordPolicy(config.Cfg.Auth.Password)
+	if err != nil {
+		logger.Logger.Fatal("Error loading password policy", zap.Error(err))
+	}
+
+	userRepo := authRepository.NewUserRepository(repo.Postgres)
+	sessionRepo := authRepository.NewSessionRepository(repo.Postgres)
+	refreshRepo := authRepository.NewRefreshTokenRepository(repo.Postgres)
+	groupRepo := repository.NewGroupRepository(repo.Postgres)
+
+	userService := service.NewUserService(userRepo, sessionRepo, refreshRepo, hasher, passwordPolicy, node)
+	userHandler := handler.NewUserHandler(userService, config.Cfg.App.BaseURL)
+	groupService := service.NewGroupService(groupRepo)
+	groupHandler := handler.NewGroupHandler(groupService, config.Cfg.App.BaseURL)
+
+	scim := route.Group("/scim/v2", handler.RequireToken(cfg.Token))
+	{
+		scim.GET("/ServiceProviderConfig", handler.ServiceProviderConfig)
+
+		scim.GET("/Users", userHandler.List)
+		scim.POST("/Users", userHandler.Create)
+		scim.GET("/Users/:id", userHandler.Get)
+		scim.PUT("/Users/:id", userHandler.Replace)
+		scim.PATCH("/Users/:id", userHandler.Patch)
+		scim.DELETE("/Users/:id", userHandler.Delete)
+
+		scim.GET("/Groups", groupHandler.List)
+		scim.POST("/Groups", groupHandler.Create)
+		scim.GET("/Groups/:id", groupHandler.Get)
+		scim.PUT("/Groups/:id", groupHandler.Replace)
+		scim.PATCH("/Groups/:id", groupHandler.Patch)
+		scim.DELETE("/Groups/:id", groupHandler.Delete)
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// SCIM error types (RFC 7644 section 3.12)
+const (
+	ScimTypeInvalidFilter = "invalidFilter"
+	ScimTypeUniqueness    = "uniqueness"
+	ScimTypeInvalidSyntax = "invalidSyntax"
+	ScimTypeInvalidPath   = "invalidPath"
+	ScimTypeInvalidValue  = "invalidValue"
+	ScimTypeNoTarget      = "noTarget"
+)
+
+// SCIMError Error reported to the provisioning client with its HTTP status and SCIM type
+type SCIMError struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (e *SCIMError) Error() string {
+	return e.Detail
+}
+
+func NewSCIMError(status int, scimType, detail string) *SCIMError {
+	return &SCIMError{
+		Status:   status,
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+var (
+	ErrUserNotFound  = NewSCIMError(http.StatusNotFound, "", "User not found")
+	ErrGroupNotFound = NewSCIMError(http.StatusNotFound, "", "Group not found")
+	ErrUserExists    = NewSCIMError(http.StatusConflict, ScimTypeUniqueness, "Username or email already exists")
+	ErrGroupExists   = NewSCIMError(http.StatusConflict, ScimTypeUniqueness, "Group name already exists")
+	ErrMemberUnknown = NewSCIMError(http.StatusBadRequest, ScimTypeInvalidValue, "Group member is not a provisionable user")
+)
+
+// invalidValue Report an attribute value the server cannot store
+func invalidValue(format string, args ...any) *SCIMError {
+	return NewSCIMError(http.StatusBadRequest, ScimTypeInvalidValue, fmt.Sprintf(format, args...))
+}
+
+// invalidPath Report a PATCH path the server does not understand
+func invalidPath(path string) *SCIMError {
+	return NewSCIMError(http.StatusBadRequest, ScimTypeInvalidPath, fmt.Sprintf("Unsupported path %q", path))
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/scim"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/scim/repository"
+)
+
+// groupColumns Filterable group attributes
+var groupColumns = map[string]scim.Column{
+	"id":          {Expr: "CAST(id AS TEXT)"},
+	"externalId":  {Expr: "external_id"},
+	"displayName": {Expr: "display_name"},
+}
+
+// memberPath A single member selected by value, as sent by Azure AD and Okta to remove one member
+var memberPath = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]+)"\s*]$`)
+
+// GroupAttributes Writable attributes of a SCIM group
+type GroupAttributes struct {
+	DisplayName string
+	ExternalID  string
+	Members     []int64
+}
+
+type GroupService interface {
+	List(filter string, offset, limit int) ([]*model.Group, int64, error)
+	Get(id uint) (*model.Group, error)
+	Members(id uint) ([]*model.User, error)
+	Create(attrs GroupAttributes) (*model.Group, error)
+	Replace(id uint, attrs GroupAttributes) (*model.Group, error)
+	Patch(id uint, operations []PatchOperation) (*model.Group, error)
+	Delete(id uint) error
+}
+
+type groupService struct {
+	groupRepo repository.GroupRepository
+}
+
+func NewGroupService(groupRepo repository.GroupRepository) GroupService {
+	return &groupService{groupRepo: groupRepo}
+}
+
+// List Groups matching the filter
+func (s *groupService) List(filter string, offset, limit int) ([]*model.Group, int64, error) {
+	var condition string
+	var args []any
+	if filter != "" {
+		var err error
+		if condition, args, err = scim.Compile(filter, groupColumns); err != nil {
+			return nil, 0, NewSCIMError(400, ScimTypeInvalidFilter, err.Error())
+		}
+	}
+	return s.groupRepo.Search(condition, args, offset, limit)
+}
+
+func (s *groupService) Get(id uint) (*model.Group, error) {
+	group, err := s.groupRepo.FindByID(id)
+	if err != nil {
+		return nil, groupError(err)
+	}
+	return group, nil
+}
+
+func (s *groupService) Members(id uint) ([]*model.User, error) {
+	return s.groupRepo.ListMembers(id)
+}
+
+func (s *groupService) Create(attrs GroupAttributes) (*model.Group, error) {
+	group := &model.Group{}
+	if err := applyGroup(group, attrs); err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.Create(group, attrs.Members); err != nil {
+		return nil, groupError(err)
+	}
+	return group, nil
+}
+
+// Replace Overwrite the group's name, external ID and full member list
+func (s *groupService) Replace(id uint, attrs GroupAttributes) (*model.Group, error) {
+	var group *model.Group
+	err := s.groupRepo.Transaction(func(tx repository.GroupRepository) error {
+		var err error
+		if group, err = tx.FindByID(id); err != nil {
+			return groupError(err)
+		}
+		if err = applyGroup(group, attrs); err != nil {
+			return err
+		}
+
+		if err = tx.Update(group); err != nil {
+			return groupError(err)
+		}
+		return groupError(tx.ReplaceMembers(group.ID, attrs.Members))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// Patch Apply operations in order. RFC 7644 section 3.5.2 makes a PATCH atomic, so every
+// operation runs in one transaction and a failing one rolls back those before it.
+func (s *groupService) Patch(id uint, operations []PatchOperation) (*model.Group, error) {
+	var group *model.Group
+	err := s.groupRepo.Transaction(func(tx repository.GroupRepository) error {
+		var err error
+		if group, err = tx.FindByID(id); err != nil {
+			return groupError(err)
+		}
+
+		attrs := GroupAttributes{DisplayName: group.DisplayName, ExternalID: group.ExternalID}
+		for _, operation := range operations {
+			if err = patchOperation(tx, group.ID, &attrs, operation); err != nil {
+				return err
+			}
+		}
+
+		if err = applyGroup(group, attrs); err != nil {
+			return err
+		}
+		return groupError(tx.Update(group))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// Delete Remove the group and its memberships, the member accounts are untouched
+func (s *groupService) Delete(id uint) error {
+	if err := s.groupRepo.Delete(id); err != nil {
+		return groupError(err)
+	}
+	return nil
+}
+
+// patchOperation Apply one operation, with or without a path
+func patchOperation(tx repository.GroupRepository, groupID uint, attrs *GroupAttributes, operation PatchOperation) error {
+	op, err := operation.normalizedOp()
+	if err != nil {
+		return err
+	}
+
+	if operation.Path != "" {
+		return patchAttribute(tx, groupID, attrs, op, operation.Path, operation.Value)
+	}
+	if op == PatchRemove {
+		return NewSCIMError(400, ScimTypeNoTarget, "remove needs a path")
+	}
+	values, err := operation.attributes()
+	if err != nil {
+		return err
+	}
+	for path, value := range values {
+		if err = patchAttribute(tx, groupID, attrs, op, path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchAttribute Apply one operation to one attribute
+func patchAttribute(tx repository.GroupRepository, groupID uint, attrs *GroupAttributes, op, path string, value json.RawMessage) error {
+	if match := memberPath.FindStringSubmatch(path); match != nil {
+		if op != PatchRemove {
+			return invalidPath(path)
+		}
+		id, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			// Never a member, so there is nothing to remove
+			return nil
+		}
+		return groupError(tx.RemoveMembers(groupID, []int64{id}))
+	}
+
+	var err error
+	switch strings.ToLower(path) {
+	case "displayname":
+		if op == PatchRemove {
+			return invalidValue("displayName cannot be removed")
+		}
+		attrs.DisplayName, err = decodeString(value, "displayName")
+	case "externalid":
+		if op == PatchRemove {
+			attrs.ExternalID = ""
+			return nil
+		}
+		attrs.ExternalID, err = decodeString(value, "externalId")
+	case "members":
+		err = patchMembers(tx, groupID, op, value)
+	default:
+		return invalidPath(path)
+	}
+	return err
+}
+
+// patchMembers Add, remove or replace members. A remove without a value empties the group.
+func patchMembers(tx repository.GroupRepository, groupID uint, op string, value json.RawMessage) error {
+	if op == PatchRemove && len(value) == 0 {
+		return groupError(tx.ReplaceMembers(groupID, nil))
+	}
+
+	ids, err := decodeMemberIDs(value)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case PatchAdd:
+		err = tx.AddMembers(groupID, ids)
+	case PatchRemove:
+		err = tx.RemoveMembers(groupID, ids)
+	default:
+		err = tx.ReplaceMembers(groupID, ids)
+	}
+	return groupError(err)
+}
+
+// applyGroup Validate the attributes and copy them onto the group
+func applyGroup(group *model.Group, attrs GroupAttributes) error {
+	displayName := strings.TrimSpace(attrs.DisplayName)
+	if displayName == "" || len(displayName) > 255 {
+		return invalidValue("displayName must be between 1 and 255 characters")
+	}
+	if len(attrs.ExternalID) > 255 {
+		return invalidValue("externalId is too long")
+	}
+	group.DisplayName, group.ExternalID = displayName, attrs.ExternalID
+	return nil
+}
+
+// groupError Map repository error codes to SCIM errors
+func groupError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch err.Error() {
+	case strconv.Itoa(code.GroupNotFound):
+		return ErrGroupNotFound
+	case strconv.Itoa(code.GroupAlreadyExists):
+		return ErrGroupExists
+	case strconv.Itoa(code.UserNotFound):
+		return ErrMemberUnknown
+	}
+	return err
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/scim/repository"
+)
+
+// fakeGroupStore State shared by a fake repository and the transactions opened on it
+type fakeGroupStore struct {
+	group   model.Group
+	members map[int64]bool
+	users   map[int64]bool // Accounts that can join
+}
+
+// fakeGroupRepo Holds one group, a transaction works on a copy kept only when it succeeds
+type fakeGroupRepo struct {
+	repository.GroupRepository
+	store  *fakeGroupStore
+	inTx   bool
+	direct int // Writes made outside a transaction
+}
+
+func (r *fakeGroupRepo) Transaction(fn func(tx repository.GroupRepository) error) error {
+	copied := *r.store
+	copied.members = maps.Clone(r.store.members)
+	tx := &fakeGroupRepo{store: &copied, inTx: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	*r.store = copied
+	return nil
+}
+
+func (r *fakeGroupRepo) FindByID(id uint) (*model.Group, error) {
+	if id != r.store.group.ID {
+		return nil, errors.New(strconv.Itoa(code.GroupNotFound))
+	}
+	group := r.store.group
+	return &group, nil
+}
+
+func (r *fakeGroupRepo) Update(group *model.Group) error {
+	r.write()
+	r.store.group = *group
+	return nil
+}
+
+func (r *fakeGroupRepo) AddMembers(_ uint, userIDs []int64) error {
+	r.write()
+	for _, id := range userIDs {
+		if !r.store.users[id] {
+			return errors.New(strconv.Itoa(code.UserNotFound))
+		}
+	}
+	for _, id := range userIDs {
+		r.store.members[id] = true
+	}
+	return nil
+}
+
+func (r *fakeGroupRepo) RemoveMembers(_ uint, userIDs []int64) error {
+	r.write()
+	for _, id := range userIDs {
+		delete(r.store.members, id)
+	}
+	return nil
+}
+
+func (r *fakeGroupRepo) ReplaceMembers(groupID uint, userIDs []int64) error {
+	r.write()
+	clear(r.store.members)
+	return r.AddMembers(groupID, userIDs)
+}
+
+func (r *fakeGroupRepo) write() {
+	if !r.inTx {
+		r.direct++
+	}
+}
+
+func (r *fakeGroupRepo) memberIDs() []int64 {
+	return slices.Sorted(maps.Keys(r.store.members))
+}
+
+func newFakeGroupRepo() *fakeGroupRepo {
+	group := model.Group{DisplayName: "Engineering"}
+	group.ID = 1
+	return &fakeGroupRepo{store: &fakeGroupStore{
+		group:   group,
+		members: map[int64]bool{10: true},
+		users:   map[int64]bool{10: true, 11: true, 12: true},
+	}}
+}
+
+func memberOp(op string, ids ...int64) PatchOperation {
+	var members []map[string]string
+	for _, id := range ids {
+		members = append(members, map[string]string{"value": strconv.FormatInt(id, 10)})
+	}
+	value, _ := json.Marshal(members)
+	return PatchOperation{Op: op, Path: "members", Value: value}
+}
+
+func TestGroupPatch(t *testing.T) {
+	repo := newFakeGroupRepo()
+	service := NewGroupService(repo)
+
+	group, err := service.Patch(1, []PatchOperation{
+		memberOp(PatchAdd, 11, 12),
+		{Op: "Remove", Path: `members[value eq "10"]`},
+		{Op: PatchReplace, Value: json.RawMessage(`{"displayName":"Platform"}`)},
+	})
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if group.DisplayName != "Platform" || repo.store.group.DisplayName != "Platform" {
+		t.Errorf("displayName = %q, stored %q, want Platform", group.DisplayName, repo.store.group.DisplayName)
+	}
+	if got := repo.memberIDs(); !slices.Equal(got, []int64{11, 12}) {
+		t.Errorf("members = %v, want [11 12]", got)
+	}
+	if repo.direct != 0 {
+		t.Errorf("%d writes outside the transaction", repo.direct)
+	}
+}
+
+func TestGroupPatchAtomic(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations []PatchOperation
+		want       error
+	}{
+		{"unknown member", []PatchOperation{
+			memberOp(PatchRemove, 10),
+			memberOp(PatchAdd, 11),
+			memberOp(PatchAdd, 99),
+		}, ErrMemberUnknown},
+		{"invalid name after member changes", []PatchOperation{
+			memberOp(PatchReplace, 11, 12),
+			{Op: PatchReplace, Path: "displayName", Value: json.RawMessage(`""`)},
+		}, nil},
+		{"unsupported path", []PatchOperation{
+			memberOp(PatchAdd, 11),
+			{Op: PatchReplace, Path: "owner", Value: json.RawMessage(`"x"`)},
+		}, nil},
+		{"missing group", nil, ErrGroupNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeGroupRepo()
+			id := uint(1)
+			if tt.operations == nil {
+				id = 2
+			}
+
+			_, err := NewGroupService(repo).Patch(id, tt.operations)
+			if err == nil {
+				t.Fatal("Patch succeeded, want an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			// Nothing from the earlier operations is kept
+			if got := repo.memberIDs(); !slices.Equal(got, []int64{10}) {
+				t.Errorf("members = %v, want the original [10]", got)
+			}
+			if repo.store.group.DisplayName != "Engineering" {
+				t.Errorf("displayName = %q, want the original", repo.store.group.DisplayName)
+			}
+		})
+	}
+}
+
+func TestGroupReplaceAtomic(t *testing.T) {
+	repo := newFakeGroupRepo()
+
+	_, err := NewGroupService(repo).Replace(1, GroupAttributes{DisplayName: "Platform", Members: []int64{11, 99}})
+	if !errors.Is(err, ErrMemberUnknown) {
+		t.Fatalf("err = %v, want ErrMemberUnknown", err)
+	}
+	if repo.store.group.DisplayName != "Engineering" || !slices.Equal(repo.memberIDs(), []int64{10}) {
+		t.Errorf("group = %q with %v, want it unchanged", repo.store.group.DisplayName, repo.memberIDs())
+	}
+}
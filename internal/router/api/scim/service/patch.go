@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// PATCH operations (RFC 7644 section 3.5.2)
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+)
+
+// PatchOperation One operation of a PATCH request, Value is left raw as its shape depends on the path
+type PatchOperation struct {
+	Op    string
+	Path  string
+	Value json.RawMessage
+}
+
+// normalizedOp Operation names are matched case insensitively, as some clients send "Replace"
+func (o PatchOperation) normalizedOp() (string, error) {
+	switch op := strings.ToLower(o.Op); op {
+	case PatchAdd, PatchRemove, PatchReplace:
+		return op, nil
+	default:
+		return "", invalidValue("Unsupported operation %q", o.Op)
+	}
+}
+
+// attributes Split a path-less add or replace into one value per attribute
+func (o PatchOperation) attributes() (map[string]json.RawMessage, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(o.Value, &values); err != nil {
+		return nil, invalidValue("Operation without a path needs an object value")
+	}
+	return values, nil
+}
+
+// decodeString Read a string value
+func decodeString(raw json.RawMessage, attribute string) (string, error) {
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", invalidValue("%s must be a string", attribute)
+	}
+	return value, nil
+}
+
+// decodeBool Read a boolean value, also accepted as the strings "true" and "false" some clients send
+func decodeBool(raw json.RawMessage, attribute string) (bool, error) {
+	var value bool
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if value, err := strconv.ParseBool(text); err == nil {
+			return value, nil
+		}
+	}
+	return false, invalidValue("%s must be a boolean", attribute)
+}
+
+// decodeMemberIDs Read a list of members, or a single member, into user IDs
+func decodeMemberIDs(raw json.RawMessage) ([]int64, error) {
+	type member struct {
+		Value string `json:"value"`
+	}
+
+	var members []member
+	if err := json.Unmarshal(raw, &members); err != nil {
+		var single member
+		if err := json.Unmarshal(raw, &single); err != nil {
+			return nil, invalidValue("members must be a list of objects with a value")
+		}
+		members = []member{single}
+	}
+
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m.Value, 10, 64)
+		if err != nil {
+			return nil, ErrMemberUnknown
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/hasher"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/logger"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/policy"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/scim"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/validation"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/repository"
+	"github.com/bwmarrin/snowflake"
+	"go.uber.org/zap"
+)
+
+// userColumns Filterable user attributes
+var userColumns = map[string]scim.Column{
+	"id":           {Expr: "CAST(user_id AS TEXT)"},
+	"externalId":   {Expr: "external_id"},
+	"userName":     {Expr: "username"},
+	"emails":       {Expr: "email"},
+	"emails.value": {Expr: "email"},
+	"active":       {Expr: "disabled_at IS NULL", Boolean: true},
+}
+
+// UserAttributes Writable attributes of a SCIM user. Attributes AurChat does not store, such as name, are dropped.
+type UserAttributes struct {
+	UserName   string
+	Email      string
+	ExternalID string
+	Active     bool
+	Password   string // Empty keeps the current password, accounts created without one are passwordless
+}
+
+type UserService interface {
+	List(filter string, offset, limit int) ([]*model.User, int64, error)
+	Get(id int64) (*model.User, error)
+	Create(ctx context.Context, attrs UserAttributes) (*model.User, error)
+	Replace(ctx context.Context, id int64, attrs UserAttributes) (*model.User, error)
+	Patch(ctx context.Context, id int64, operations []PatchOperation) (*model.User, error)
+	Deactivate(id int64) error
+}
+
+type userService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	refreshRepo repository.RefreshTokenRepository
+	hasher      *hasher.Hasher
+	policy      *policy.PasswordPolicy
+	idGenerator *snowflake.Node
+}
+
+func NewUserService(
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	refreshRepo repository.RefreshTokenRepository,
+	hasher *hasher.Hasher,
+	policy *policy.PasswordPolicy,
+	node *snowflake.Node,
+) UserService {
+	return &userService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		refreshRepo: refreshRepo,
+		hasher:      hasher,
+		policy:      policy,
+		idGenerator: node,
+	}
+}
+
+// List Users matching the filter, bots are never exposed
+func (s *userService) List(filter string, offset, limit int) ([]*model.User, int64, error) {
+	var condition string
+	var args []any
+	if filter != "" {
+		var err error
+		if condition, args, err = scim.Compile(filter, userColumns); err != nil {
+			return nil, 0, NewSCIMError(400, ScimTypeInvalidFilter, err.Error())
+		}
+	}
+	return s.userRepo.Search(condition, args, offset, limit)
+}
+
+func (s *userService) Get(id int64) (*model.User, error) {
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == strconv.Itoa(code.UserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.Bot {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// Create Provision an account. The identity provider vouches for the email, so it starts verified.
+func (s *userService) Create(ctx context.Context, attrs UserAttributes) (*model.User, error) {
+	now := time.Now()
+	user := &model.User{
+		UserID:          s.idGenerator.Generate().Int64(),
+		EmailVerifiedAt: &now,
+	}
+	if err := s.apply(ctx, user, attrs); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		if err.Error() == strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed) {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// Replace Overwrite every writable attribute of the user
+func (s *userService) Replace(ctx context.Context, id int64, attrs UserAttributes) (*model.User, error) {
+	user, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.save(ctx, user, attrs)
+}
+
+// Patch Apply add, replace and remove operations to the user's attributes
+func (s *userService) Patch(ctx context.Context, id int64, operations []PatchOperation) (*model.User, error) {
+	user, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := UserAttributes{
+		UserName:   user.Username,
+		Email:      user.Email,
+		ExternalID: user.ExternalID,
+		Active:     user.DisabledAt == nil,
+	}
+	for _, operation := range operations {
+		op, err := operation.normalizedOp()
+		if err != nil {
+			return nil, err
+		}
+
+		if op == PatchRemove {
+			if err = removeUserAttribute(&attrs, operation.Path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if operation.Path != "" {
+			if err = setUserAttribute(&attrs, operation.Path, operation.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		values, err := operation.attributes()
+		if err != nil {
+			return nil, err
+		}
+		for path, value := range values {
+			if err = setUserAttribute(&attrs, path, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.save(ctx, user, attrs)
+}
+
+// Deactivate Disable the account in place of deleting it, so its messages and memberships survive
+func (s *userService) Deactivate(id int64) error {
+	user, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if user.DisabledAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	if err = s.userRepo.SetDisabled(user.UserID, &now); err != nil {
+		return err
+	}
+	return s.signOut(user.UserID)
+}
+
+// save Store the attributes, signing the user out everywhere when they were just disabled
+func (s *userService) save(ctx context.Context, user *model.User, attrs UserAttributes) (*model.User, error) {
+	wasActive := user.DisabledAt == nil
+	if err := s.apply(ctx, user, attrs); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		if err.Error() == strconv.Itoa(code.UserAlreadyExistsOrEmailAlreadyUsed) {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+
+	if wasActive && user.DisabledAt != nil {
+		if err := s.signOut(user.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// apply Validate the attributes and copy them onto the user
+func (s *userService) apply(ctx context.Context, user *model.User, attrs UserAttributes) error {
+	username, err := validation.Username(attrs.UserName)
+	if err != nil {
+		return invalidValue("userName %q is not a valid username", attrs.UserName)
+	}
+	email, err := validation.Email(attrs.Email)
+	if err != nil {
+		return invalidValue("A valid email is required")
+	}
+	if len(attrs.ExternalID) > 255 {
+		return invalidValue("externalId is too long")
+	}
+
+	if attrs.Password != "" {
+		reasons, err := s.policy.Check(attrs.Password, username, email)
+		if err != nil {
+			return err
+		}
+		if len(reasons) > 0 {
+			return invalidValue("password is too weak: %s", strings.Join(reasons, ", "))
+		}
+		if user.Password, err = s.hasher.Hash(ctx, attrs.Password); err != nil {
+			return err
+		}
+	}
+
+	user.Username, user.Email, user.ExternalID = username, email, attrs.ExternalID
+	if !attrs.Active && user.DisabledAt == nil {
+		now := time.Now()
+		user.DisabledAt = &now
+	} else if attrs.Active {
+		user.DisabledAt = nil
+	}
+	return nil
+}
+
+// signOut End every session of a disabled user, their access tokens are already refused
+func (s *userService) signOut(userID int64) error {
+	if _, err := s.sessionRepo.RevokeByUserID(userID, 0); err != nil {
+		return err
+	}
+	if err := s.refreshRepo.RevokeByUserID(userID); err != nil {
+		return err
+	}
+	logger.Logger.Info("Provisioning disabled account", zap.Int64("userID", userID))
+	return nil
+}
+
+// setUserAttribute Set one attribute from an add or replace operation
+func setUserAttribute(attrs *UserAttributes, path string, value json.RawMessage) error {
+	var err error
+	switch attribute := strings.ToLower(path); {
+	case attribute == "username":
+		attrs.UserName, err = decodeString(value, "userName")
+	case attribute == "externalid":
+		attrs.ExternalID, err = decodeString(value, "externalId")
+	case attribute == "active":
+		attrs.Active, err = decodeBool(value, "active")
+	case attribute == "password":
+		attrs.Password, err = decodeString(value, "password")
+	case attribute == "emails":
+		attrs.Email, err = primaryEmail(value)
+	case strings.HasPrefix(attribute, "emails[") || attribute == "emails.value":
+		// A single address such as emails[type eq "work"].value, AurChat keeps one email per account
+		attrs.Email, err = decodeString(value, "emails")
+	}
+	// Attributes AurChat does not store are ignored, like they are on create
+	return err
+}
+
+// removeUserAttribute Clear one attribute, the required ones cannot be removed
+func removeUserAttribute(attrs *UserAttributes, path string) error {
+	switch attribute := strings.ToLower(path); {
+	case attribute == "":
+		return NewSCIMError(400, ScimTypeNoTarget, "remove needs a path")
+	case attribute == "externalid":
+		attrs.ExternalID = ""
+	case attribute == "username", attribute == "active", attribute == "password", strings.HasPrefix(attribute, "emails"):
+		return invalidValue("%s cannot be removed", path)
+	}
+	return nil
+}
+
+// primaryEmail Pick the address marked primary from an emails list, else the first one
+func primaryEmail(value json.RawMessage) (string, error) {
+	var emails []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	}
+	if err := json.Unmarshal(value, &emails); err != nil || len(emails) == 0 {
+		return "", invalidValue("emails must be a non-empty list")
+	}
+	for _, email := range emails {
+		if email.Primary {
+			return email.Value, nil
+		}
+	}
+	return emails[0].Value, nil
+}
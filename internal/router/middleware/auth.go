@@ -0,0 +1,209 @@
+// Package middleware provides reusable gin middleware for route groups.
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TokenCookie = "token" // Cookie written by AuthHandler.SignIn
+
+	contextUserKey   = "auth.user"
+	contextClaimsKey = "auth.claims"
+)
+
+// TokenValidator Resolves a raw token into its user and claims
+type TokenValidator interface {
+	ValidateToken(token string) (*model.User, *token.UserClaims, error)
+}
+
+// RequireAuth Reject requests without a valid token
+func RequireAuth(validator TokenValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := authenticate(c, validator); !ok {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSessionAuth Reject requests without a valid token from a signed in session.
+// API tokens are refused, so routes managing the account and its credentials stay out of their reach.
+func RequireSessionAuth(validator TokenValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := authenticate(c, validator)
+		if !ok {
+			return
+		}
+
+		if claims.APITokenID != 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResp{
+				Code: code.Forbidden,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// OptionalAuth Resolve the user when a valid token is present, continue anonymously otherwise
+func OptionalAuth(validator TokenValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if raw := ExtractToken(c); raw != "" {
+			if user, claims, err := validator.ValidateToken(raw); err == nil {
+				setPrincipal(c, user, claims)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireScopes Reject principals whose token does not grant every listed scope.
+// Must be mounted after RequireAuth. Tokens without scopes, such as session access tokens, are unrestricted.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := CurrentClaims(c)
+		if !ok {
+			abortUnauthorized(c, code.Unauthorized)
+			return
+		}
+
+		if !HasScopes(claims, scopes...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResp{
+				Code: code.Forbidden,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole Reject principals without one of the listed roles. Must be mounted after RequireAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
+		if !ok {
+			abortUnauthorized(c, code.Unauthorized)
+			return
+		}
+
+		if !slices.Contains(roles, user.Role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResp{
+				Code: code.Forbidden,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasScopes Report whether the claims grant every listed scope
+func HasScopes(claims *token.UserClaims, scopes ...string) bool {
+	if len(claims.Scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(claims.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// ExtractToken Read the token from the Authorization header, falling back to the cookie
+func ExtractToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		scheme, value, found := strings.Cut(header, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(value)
+		}
+		return ""
+	}
+
+	if cookie, err := c.Cookie(TokenCookie); err == nil {
+		return cookie
+	}
+	return ""
+}
+
+// CurrentUser Return the authenticated user stored by the auth middleware
+func CurrentUser(c *gin.Context) (*model.User, bool) {
+	value, ok := c.Get(contextUserKey)
+	if !ok {
+		return nil, false
+	}
+	user, ok := value.(*model.User)
+	return user, ok
+}
+
+// MustCurrentUser Return the authenticated user, panicking on routes without RequireAuth
+func MustCurrentUser(c *gin.Context) *model.User {
+	user, ok := CurrentUser(c)
+	if !ok {
+		panic("middleware: no authenticated user in context")
+	}
+	return user
+}
+
+// CurrentClaims Return the token claims stored by the auth middleware
+func CurrentClaims(c *gin.Context) (*token.UserClaims, bool) {
+	value, ok := c.Get(contextClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*token.UserClaims)
+	return claims, ok
+}
+
+// authenticate Validate the request token and store its principal, aborting the request on failure
+func authenticate(c *gin.Context, validator TokenValidator) (*token.UserClaims, bool) {
+	raw := ExtractToken(c)
+	if raw == "" {
+		abortUnauthorized(c, code.Unauthorized)
+		return nil, false
+	}
+
+	user, claims, err := validator.ValidateToken(raw)
+	if err != nil {
+		var authErr *service.AuthError
+		switch {
+		case errors.Is(err, service.ErrAccountDisabled):
+			// The token is valid, the account behind it may not act
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResp{
+				Code: code.AccountDisabled,
+			})
+		case errors.As(err, &authErr):
+			abortUnauthorized(c, authErr.Code)
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResp{
+				Code: code.ServerUnknownError,
+			})
+		}
+		return nil, false
+	}
+
+	setPrincipal(c, user, claims)
+	return claims, true
+}
+
+func setPrincipal(c *gin.Context, user *model.User, claims *token.UserClaims) {
+	c.Set(contextUserKey, user)
+	c.Set(contextClaimsKey, claims)
+}
+
+func abortUnauthorized(c *gin.Context, errCode int) {
+	c.Header("WWW-Authenticate", `Bearer realm="aurchat"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResp{
+		Code: errCode,
+	})
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AurChatOrg/aurchat-server/internal/code"
+	"github.com/AurChatOrg/aurchat-server/internal/dto"
+	"github.com/AurChatOrg/aurchat-server/internal/model"
+	"github.com/AurChatOrg/aurchat-server/internal/pkg/token"
+	"github.com/AurChatOrg/aurchat-server/internal/router/api/auth/service"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeValidator Fails every token with the same error, or accepts it when there is none
+type fakeValidator struct {
+	err error
+}
+
+func (v fakeValidator) ValidateToken(string) (*model.User, *token.UserClaims, error) {
+	if v.err != nil {
+		return nil, nil, v.err
+	}
+	return &model.User{UserID: 1}, &token.UserClaims{}, nil
+}
+
+func TestRequireAuthStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		cookie     string
+		err        error
+		wantStatus int
+		wantCode   int
+	}{
+		{"valid", "raw", nil, http.StatusNoContent, 0},
+		{"missing token", "", nil, http.StatusUnauthorized, code.Unauthorized},
+		{"expired", "raw", service.ErrTokenExpired, http.StatusUnauthorized, code.TokenExpired},
+		{"disabled account", "raw", service.ErrAccountDisabled, http.StatusForbidden, code.AccountDisabled},
+		{"store unavailable", "raw", errors.New("connection refused"), http.StatusInternalServerError, code.ServerUnknownError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", RequireAuth(fakeValidator{err: tt.err}), func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: TokenCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantCode == 0 {
+				return
+			}
+			var resp dto.ErrorResp
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", resp.Code, tt.wantCode)
+			}
+		})
+	}
+}
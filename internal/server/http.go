@@ -9,6 +9,7 @@ import (
 	"github.com/AurChatOrg/aurchat-server/internal/router"
 	"github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
 	"github.com/AurChatOrg/aurchat-server/internal/config"
@@ -28,6 +29,7 @@ func NewHTTPServer(cfg *config.Config, log *zap.Logger) *http.Server {
 
 	// Register Route
 	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler)) // Swagger UI
+	engine.GET("/metrics", gin.WrapH(promhttp.Handler()))                     // Prometheus
 	router.RegisterAPI(engine, cfg)                                           // /api/*
 	//router.RegisterWS(engine, cfg)  // /ws
 	//router.RegisterRTC(engine, cfg) // /rtc/*